@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rubiojr/hashup/internal/agent"
+	"github.com/rubiojr/hashup/internal/crypto"
+	"github.com/rubiojr/hashup/internal/log"
+	"github.com/rubiojr/hashup/internal/util"
+	"github.com/urfave/cli/v2"
+)
+
+func commandAgent() *cli.Command {
+	return &cli.Command{
+		Name:  "agent",
+		Usage: "Serve indexed files to `hs get` over NATS",
+		Description: "Answers requests for the files below the given directories, or agent.roots,\n" +
+			"on <agent.subject>.<hostname>. Requests and files are encrypted with the\n" +
+			"encryption key end to end and `hs get` verifies the content hash on arrival.",
+		ArgsUsage: "[DIR...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "config",
+				Usage: "Path to the configuration file",
+			},
+			&cli.StringFlag{
+				Name:    "nats-url",
+				Usage:   "NATS URL",
+				EnvVars: []string{"HASHUP_NATS_URL"},
+			},
+			&cli.StringFlag{
+				Name:    "encryption-key",
+				Usage:   "Encryption key shared with hs get",
+				EnvVars: []string{"HASHUP_ENCRYPTION_KEY"},
+			},
+			&cli.StringFlag{
+				Name:  "client-cert",
+				Usage: "TLS client cert",
+			},
+			&cli.StringFlag{
+				Name:  "client-key",
+				Usage: "TLS client key",
+			},
+			&cli.StringFlag{
+				Name:  "ca-cert",
+				Usage: "TLS CA cert",
+			},
+			&cli.BoolFlag{
+				Name:    "debug",
+				Usage:   "Debug mode",
+				EnvVars: []string{"HASHUP_DEBUG"},
+			},
+		},
+		Action: runAgent,
+	}
+}
+
+func runAgent(c *cli.Context) error {
+	if c.Bool("debug") {
+		os.Setenv("HASHUP_DEBUG", "1")
+	}
+
+	cfg, err := util.LoadConfigFromCLI(c)
+	if err != nil {
+		return err
+	}
+
+	roots := c.Args().Slice()
+	if len(roots) == 0 {
+		roots = cfg.Agent.Roots
+	}
+	if len(roots) == 0 {
+		return fmt.Errorf("no directories to serve, pass them as arguments or set agent.roots")
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+
+	machine, err := crypto.NewAge(cfg.Main.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("failed to create crypto instance: %v", err)
+	}
+
+	nc, err := agent.Connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	subject := agent.Subject(cfg.Agent.Subject, hostname)
+	a, err := agent.New(nc, machine, subject, roots)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Serving files below %v on %s\n", roots, subject)
+	return a.Serve(ctx)
+}
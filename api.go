@@ -0,0 +1,157 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rubiojr/hashup/internal/api"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/util"
+	"github.com/rubiojr/hashup/pkg/config"
+	"github.com/urfave/cli/v2"
+)
+
+func commandAPI() *cli.Command {
+	return &cli.Command{
+		Name:  "api",
+		Usage: "Serve index API",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "address",
+				Usage: "Address to listen on (defaults to api.listen_addr, localhost:8448)",
+			},
+			&cli.IntFlag{
+				Name:  "limit",
+				Usage: "Maximum number of results to return by default (defaults to api.default_limit)",
+			},
+			&cli.BoolFlag{
+				Name:  "ui",
+				Usage: "Serve the web search page at / (defaults to api.ui)",
+			},
+			&cli.StringFlag{
+				Name:  "config",
+				Usage: "Path to the configuration file",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cfgPath := c.String("config")
+			if cfgPath == "" {
+				cfgDir, err := config.DefaultConfigDir()
+				if err != nil {
+					return err
+				}
+				cfgPath = filepath.Join(cfgDir, "config.toml")
+			}
+			return api.Serve(cfgPath, api.ServeOptions{
+				Addr:  c.String("address"),
+				Limit: c.Int("limit"),
+				UI:    c.Bool("ui"),
+			})
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:  "token",
+				Usage: "Manage API tokens",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "create",
+						Usage:     "Create an API token",
+						ArgsUsage: "NAME",
+						Flags:     tokenFlags(),
+						Action: func(c *cli.Context) error {
+							if c.NArg() != 1 {
+								return fmt.Errorf("a token name is required")
+							}
+							return withTokenDB(c, func(cfg *config.Config, db *sql.DB) error {
+								token, err := hsdb.CreateToken(db, c.Args().First())
+								if err != nil {
+									return err
+								}
+								fmt.Println(token)
+								if !cfg.API.RequireAuth {
+									fmt.Fprintln(os.Stderr, "Warning: api.require_auth is not enabled, the API accepts read requests without a token")
+								}
+								return nil
+							})
+						},
+					},
+					{
+						Name:      "revoke",
+						Usage:     "Revoke an API token",
+						ArgsUsage: "NAME",
+						Flags:     tokenFlags(),
+						Action: func(c *cli.Context) error {
+							if c.NArg() != 1 {
+								return fmt.Errorf("a token name is required")
+							}
+							return withTokenDB(c, func(cfg *config.Config, db *sql.DB) error {
+								if err := hsdb.RevokeToken(db, c.Args().First()); err != nil {
+									return err
+								}
+								fmt.Printf("Revoked token %s\n", c.Args().First())
+								return nil
+							})
+						},
+					},
+					{
+						Name:  "list",
+						Usage: "List API tokens",
+						Flags: tokenFlags(),
+						Action: func(c *cli.Context) error {
+							return withTokenDB(c, func(cfg *config.Config, db *sql.DB) error {
+								tokens, err := hsdb.Tokens(db)
+								if err != nil {
+									return err
+								}
+
+								w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+								fmt.Fprintln(w, "NAME\tCREATED\tLAST USED")
+								for _, t := range tokens {
+									lastUsed := "never"
+									if t.LastUsed != nil {
+										lastUsed = t.LastUsed.Format(time.DateTime)
+									}
+									fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, t.Created.Format(time.DateTime), lastUsed)
+								}
+								return w.Flush()
+							})
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func tokenFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "config",
+			Usage: "Path to the configuration file",
+		},
+		&cli.StringFlag{
+			Name:  "db-path",
+			Usage: "Override the database path",
+		},
+	}
+}
+
+// withTokenDB runs fn with the database the API serves.
+func withTokenDB(c *cli.Context, fn func(*config.Config, *sql.DB) error) error {
+	cfg, err := util.LoadConfigFromCLI(c)
+	if err != nil {
+		return err
+	}
+
+	db, err := hsdb.OpenDatabase(cfg.Store.DBPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	return fn(cfg, db)
+}
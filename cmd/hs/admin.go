@@ -33,7 +33,14 @@ func commandAdmin() *cli.Command {
 					},
 				},
 				Action: func(c *cli.Context) error {
-					return recreateDatabase(c.Bool("force"), c.String("db-path"))
+					dbPath := c.String("db-path")
+					if dbPath == "" {
+						var err error
+						if dbPath, err = getDBPath(c); err != nil {
+							return err
+						}
+					}
+					return recreateDatabase(c.Bool("force"), dbPath)
 				},
 			},
 			{
@@ -59,7 +66,7 @@ func commandAdmin() *cli.Command {
 					},
 				},
 				Action: func(c *cli.Context) error {
-					dbPath, err := getDBPath()
+					dbPath, err := getDBPath(c)
 					if err != nil {
 						return err
 					}
@@ -76,19 +83,9 @@ func commandAdmin() *cli.Command {
 	}
 }
 
-func recreateDatabase(force bool, dbPathOverride string) error {
-	// Get the database path
-	dbPath := dbPathOverride
-	var err error
-	if dbPath == "" {
-		dbPath, err = getDBPath()
-		if err != nil {
-			return err
-		}
-	}
-
+func recreateDatabase(force bool, dbPath string) error {
 	// Check if the database file exists
-	_, err = os.Stat(dbPath)
+	_, err := os.Stat(dbPath)
 	if err == nil {
 		// File exists, ask for confirmation unless forced
 		if !force {
@@ -126,18 +123,25 @@ func recreateDatabase(force bool, dbPathOverride string) error {
 		return fmt.Errorf("failed to open database: %v", err)
 	}
 	defer db.Close()
+	if _, err := hsdb.EnsureFTS(db); err != nil {
+		return err
+	}
 
 	fmt.Printf("Successfully created new database at %s\n", dbPath)
 	return nil
 }
 
 func deleteFilesByHost(dbPath, host string, force, dryRun bool) error {
-	// Connect to the database
-	db, err := sql.Open("sqlite3", dbPath)
+	// Connect to the database, making sure the full-text index triggers
+	// match what this binary supports before deleting.
+	db, err := hsdb.OpenDatabase(dbPath)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %v", err)
 	}
 	defer db.Close()
+	if _, err := hsdb.EnsureFTS(db); err != nil {
+		return err
+	}
 
 	// Count how many files will be deleted
 	var fileCount int
@@ -246,6 +250,14 @@ func deleteFilesByHost(dbPath, host string, force, dryRun bool) error {
 				return fmt.Errorf("failed to delete from file_notes: %v", err)
 			}
 		}
+
+		// Delete from file_removals
+		for _, id := range fileIDs {
+			_, err = tx.Exec("DELETE FROM file_removals WHERE file_id = ?", id)
+			if err != nil {
+				return fmt.Errorf("failed to delete from file_removals: %v", err)
+			}
+		}
 	}
 
 	// Delete the file records
@@ -260,6 +272,18 @@ func deleteFilesByHost(dbPath, host string, force, dryRun bool) error {
 		return fmt.Errorf("failed to get rows affected: %v", err)
 	}
 
+	// Delete the host directory tree
+	_, err = tx.Exec("DELETE FROM directories WHERE host = ?", host)
+	if err != nil {
+		return fmt.Errorf("failed to delete directories: %v", err)
+	}
+
+	// Forget the usual activity of the host
+	_, err = tx.Exec("DELETE FROM anomaly_baselines WHERE host = ?", host)
+	if err != nil {
+		return fmt.Errorf("failed to delete anomaly baseline: %v", err)
+	}
+
 	// Cleanup orphaned hashes (optional, but keeps the database clean)
 	_, err = tx.Exec(`
 		DELETE FROM file_hashes
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/output"
+	"github.com/urfave/cli/v2"
+)
+
+func commandAlerts() *cli.Command {
+	return &cli.Command{
+		Name:  "alerts",
+		Usage: "List hosts with unusual file activity",
+		Description: "The store alerts when a host changes many more indexed files than usual in a\n" +
+			"window, or creates many files with extensions it never had, like ransomware\n" +
+			"encrypting files. Thresholds are set in the [anomaly] config section and alerts\n" +
+			"are also logged by the store and posted to the anomaly webhook.\n" +
+			"Silence hosts during known bulk operations with `hs alerts quiet`.",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "Show alerts since an age (30m, 12h, 7d, 2w) or date (YYYY-MM-DD)",
+				Value: "7d",
+			},
+			&cli.StringSliceFlag{
+				Name:    "host",
+				Aliases: []string{"H"},
+				Usage:   "Only list alerts of these hosts",
+			},
+			&cli.IntFlag{
+				Name:    "limit",
+				Aliases: []string{"l"},
+				Usage:   "Maximum number of alerts",
+				Value:   100,
+			},
+		}, append(backendFlags(), outputFlags()...)...),
+		Subcommands: []*cli.Command{
+			commandAlertsQuiet(),
+			commandAlertsResume(),
+		},
+		Action: func(c *cli.Context) error {
+			p, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+
+			since, err := hsdb.ParseSince(c.String("since"), time.Now())
+			if err != nil {
+				return err
+			}
+
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			alerts, err := b.Alerts(hsdb.AnomalyOptions{
+				Hosts: c.StringSlice("host"),
+				Since: since,
+				Limit: c.Int("limit"),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to query alerts: %v", err)
+			}
+
+			return output.Write(p, alerts, output.Spec[*hsdb.AnomalyAlert]{
+				Columns: []output.Column[*hsdb.AnomalyAlert]{
+					{Header: "CREATED", Value: func(a *hsdb.AnomalyAlert) any { return a.Created.Format("2006-01-02 15:04") }},
+					{Header: "HOST", Value: func(a *hsdb.AnomalyAlert) any { return a.Host }},
+					{Header: "KIND", Value: func(a *hsdb.AnomalyAlert) any { return a.Kind }},
+					{Header: "FILES", Value: func(a *hsdb.AnomalyAlert) any { return a.Files }},
+					{Header: "BASELINE", Value: func(a *hsdb.AnomalyAlert) any { return fmt.Sprintf("%.1f", a.Baseline) }},
+					{Header: "EXTENSIONS", Value: func(a *hsdb.AnomalyAlert) any { return strings.Join(a.Extensions, ",") }},
+				},
+				Key: func(a *hsdb.AnomalyAlert) string { return fmt.Sprint(a.ID) },
+				Text: func(w io.Writer) error {
+					printAlerts(w, alerts)
+					return nil
+				},
+			})
+		},
+	}
+}
+
+func printAlerts(w io.Writer, alerts []*hsdb.AnomalyAlert) {
+	for _, a := range alerts {
+		created := a.Created.Local().Format("2006-01-02 15:04")
+		switch a.Kind {
+		case hsdb.AnomalyMassChange:
+			fmt.Fprintf(w, "%s %s: %d files changed, usually %.0f\n", created, a.Host, a.Files, a.Baseline)
+		case hsdb.AnomalyNewExtensions:
+			fmt.Fprintf(w, "%s %s: %d new files with unfamiliar extensions (%s)\n",
+				created, a.Host, a.Files, strings.Join(a.Extensions, ", "))
+		default:
+			fmt.Fprintf(w, "%s %s: %s, %d files\n", created, a.Host, a.Kind, a.Files)
+		}
+		for _, p := range a.Paths {
+			fmt.Fprintf(w, "  %s\n", p)
+		}
+		if n := a.Files - int64(len(a.Paths)); n > 0 {
+			fmt.Fprintf(w, "  ... and %d more\n", n)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d alerts\n", len(alerts))
+}
+
+func commandAlertsQuiet() *cli.Command {
+	return &cli.Command{
+		Name:  "quiet",
+		Usage: "Silence the alerts of a host, or list silenced hosts",
+		Description: "Silences the alerts of HOST, or every host with '*', during a known bulk\n" +
+			"operation like a restore or a photo library migration. The store picks up\n" +
+			"changes within a minute. Without a host, lists the silenced hosts.",
+		ArgsUsage: "[HOST]",
+		Flags: append([]cli.Flag{
+			&cli.DurationFlag{
+				Name:  "for",
+				Usage: "How long to silence the host",
+				Value: 2 * time.Hour,
+			},
+			&cli.StringFlag{
+				Name:  "reason",
+				Usage: "Why the host is silenced",
+			},
+		}, append(backendFlags(), outputFlags()...)...),
+		Action: func(c *cli.Context) error {
+			p, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			if c.NArg() == 0 {
+				periods, err := b.QuietPeriods()
+				if err != nil {
+					return fmt.Errorf("failed to query quiet periods: %v", err)
+				}
+				return output.Write(p, periods, output.Spec[*hsdb.QuietPeriod]{
+					Columns: []output.Column[*hsdb.QuietPeriod]{
+						{Header: "HOST", Value: func(q *hsdb.QuietPeriod) any { return q.Host }},
+						{Header: "UNTIL", Value: func(q *hsdb.QuietPeriod) any { return q.Until.Local().Format("2006-01-02 15:04") }},
+						{Header: "REASON", Value: func(q *hsdb.QuietPeriod) any { return q.Reason }},
+					},
+					Key: func(q *hsdb.QuietPeriod) string { return q.Host },
+				})
+			}
+
+			if c.Duration("for") <= 0 {
+				return fmt.Errorf("--for must be positive")
+			}
+			q, err := b.SetQuiet(c.Args().First(), time.Now().Add(c.Duration("for")), c.String("reason"))
+			if err != nil {
+				return fmt.Errorf("failed to silence %s: %v", c.Args().First(), err)
+			}
+			fmt.Printf("Alerts of %s silenced until %s\n", q.Host, q.Until.Local().Format("2006-01-02 15:04"))
+			return nil
+		},
+	}
+}
+
+func commandAlertsResume() *cli.Command {
+	return &cli.Command{
+		Name:      "resume",
+		Usage:     "End the quiet period of a host",
+		ArgsUsage: "HOST",
+		Flags:     backendFlags(),
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("a host is required")
+			}
+
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			if err := b.DeleteQuiet(c.Args().First()); err != nil {
+				return err
+			}
+			fmt.Printf("Alerts of %s resumed\n", c.Args().First())
+			return nil
+		},
+	}
+}
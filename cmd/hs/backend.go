@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/rubiojr/hashup/cmd/hs/types"
+	"github.com/rubiojr/hashup/internal/api"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/tui"
+	scantypes "github.com/rubiojr/hashup/internal/types"
+	"github.com/rubiojr/hashup/pkg/config"
+)
+
+// backend runs hs queries against a local database or an API server.
+type backend interface {
+	tui.Backend
+	// Name identifies the index in federated search results.
+	Name() string
+	Hosts() ([]*hsdb.HostStat, error)
+	AllTags() ([]string, error)
+	// File returns the copies of the content with the given hash.
+	File(hash string) (*hsdb.FileDetail, error)
+	ExtensionStats(orderBy string, descending bool, host string, limit int) (*hsdb.Stats, error)
+	LargeFiles(threshold int64, limit int) ([]*types.FileResult, error)
+	Dupes(opts hsdb.DupeOptions) ([]*hsdb.DupeGroup, error)
+	NearDupes(opts hsdb.NearDupeOptions) ([]*hsdb.NearDupe, error)
+	// Manifest returns the chunk manifest of the content with the given
+	// hash, and ChunkSources the files of host holding its chunks.
+	Manifest(hash string) (*scantypes.ChunkManifest, error)
+	ChunkSources(hash, host string) ([]*hsdb.ChunkSource, error)
+	Coverage(opts hsdb.CoverageOptions) (*hsdb.CoverageReport, error)
+	Diff(from, to hsdb.TreeRef) (*hsdb.TreeDiff, error)
+	DU(tree hsdb.TreeRef, opts hsdb.DUOptions) (*hsdb.DUNode, error)
+	List(dir hsdb.TreeRef) (*hsdb.Listing, error)
+	Recent(opts hsdb.RecentOptions) ([]*hsdb.Change, error)
+	Timeline(opts hsdb.RecentOptions) ([]*hsdb.TimelineDay, error)
+	Integrity(opts hsdb.IntegrityOptions) ([]*hsdb.IntegrityEvent, error)
+	Alerts(opts hsdb.AnomalyOptions) ([]*hsdb.AnomalyAlert, error)
+	QuietPeriods() ([]*hsdb.QuietPeriod, error)
+	SetQuiet(host string, until time.Time, reason string) (*hsdb.QuietPeriod, error)
+	DeleteQuiet(host string) error
+	Close() error
+}
+
+// backendFlags select the backend of a command, see openBackend.
+func backendFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "db",
+			Usage: "Database path (defaults to store.db_path)",
+		},
+		&cli.StringFlag{
+			Name:  "server-url",
+			Usage: "HashUp API server URL (defaults to client.server_url)",
+		},
+	}
+}
+
+// openBackend returns the backend selected by --server-url, --db or the
+// configuration, in that order. Without a client.server_url setting the
+// store.db_path database is used.
+func openBackend(c *cli.Context) (backend, error) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL := c.String("server-url")
+	if serverURL == "" && c.String("db") == "" {
+		serverURL = cfg.Client.ServerURL
+	}
+	if serverURL != "" {
+		var opts []api.ClientOption
+		if cfg.Client.Token != "" {
+			opts = append(opts, api.WithToken(cfg.Client.Token))
+		}
+		if cfg.Client.CACert != "" {
+			pool, err := api.LoadCACert(cfg.Client.CACert)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, api.WithRootCAs(pool))
+		}
+		client, err := apiClient(serverURL, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &remoteBackend{name: api.PeerName(serverURL), client: client}, nil
+	}
+
+	dbPath, err := getDBPath(c)
+	if err != nil {
+		return nil, err
+	}
+	return openLocalBackend(dbPath)
+}
+
+// openLocalBackend opens the existing database at dbPath for reading,
+// bringing its schema up to date first if it was created by an older
+// version.
+func openLocalBackend(dbPath string) (*localBackend, error) {
+	pool, err := hsdb.OpenReadOnly(dbPath, 4)
+	if err != nil {
+		return nil, err
+	}
+	b := &localBackend{pool: pool, db: pool.WithContext(context.Background()), path: dbPath}
+
+	outdated, err := hsdb.Outdated(b.db)
+	if err == nil && outdated {
+		_, err = b.writer()
+	}
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// loadConfig loads the configuration file set with --config, or the
+// default one. Without a default configuration file the default settings
+// are used.
+func loadConfig(c *cli.Context) (*config.Config, error) {
+	if path := c.String("config"); path != "" {
+		return config.LoadConfig(path)
+	}
+
+	dir, err := config.DefaultConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "config.toml")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return config.DefaultConfig(), nil
+	}
+	return config.LoadConfig(path)
+}
+
+// localBackend runs queries against a local database. Tagging, quiet
+// periods and schema updates open a read-write connection the first time
+// they are needed.
+type localBackend struct {
+	pool *hsdb.Pool
+	db   hsdb.Querier
+	path string
+
+	mu sync.Mutex
+	rw *sql.DB
+}
+
+// writer returns the read-write connection to the database, opening it and
+// bringing the schema up to date the first time.
+func (b *localBackend) writer() (*sql.DB, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rw != nil {
+		return b.rw, nil
+	}
+
+	rw, err := hsdb.OpenDatabase(b.path)
+	if err != nil {
+		if rw != nil {
+			rw.Close()
+		}
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	b.rw = rw
+
+	return rw, nil
+}
+
+func (b *localBackend) Name() string {
+	return "local"
+}
+
+func (b *localBackend) Search(query string, opts hsdb.SearchOptions) (*hsdb.SearchPage, error) {
+	return hsdb.SearchFiles(b.db, query, opts)
+}
+
+func (b *localBackend) Facets(query string) (*hsdb.Facets, error) {
+	return hsdb.SearchFacets(b.db, query, hsdb.SearchOptions{})
+}
+
+func (b *localBackend) Tags(f *types.FileResult) ([]string, error) {
+	id, err := hsdb.FileID(b.db, f.Host, f.FilePath, f.FileHash)
+	if err != nil {
+		return nil, err
+	}
+	return hsdb.FileTags(b.db, id)
+}
+
+func (b *localBackend) SetTags(f *types.FileResult, tags []string) error {
+	id, err := hsdb.FileID(b.db, f.Host, f.FilePath, f.FileHash)
+	if err != nil {
+		return err
+	}
+	rw, err := b.writer()
+	if err != nil {
+		return err
+	}
+	return hsdb.SetTags(rw, id, tags)
+}
+
+func (b *localBackend) Hosts() ([]*hsdb.HostStat, error) {
+	return hsdb.Hosts(b.db)
+}
+
+func (b *localBackend) AllTags() ([]string, error) {
+	return hsdb.AllTags(b.db)
+}
+
+func (b *localBackend) File(hash string) (*hsdb.FileDetail, error) {
+	return hsdb.File(b.db, hash)
+}
+
+func (b *localBackend) ExtensionStats(orderBy string, descending bool, host string, limit int) (*hsdb.Stats, error) {
+	stats, err := hsdb.FileStats(b.db, orderBy, descending, host)
+	if err != nil {
+		return nil, err
+	}
+	return stats.Summary(host, limit), nil
+}
+
+func (b *localBackend) LargeFiles(threshold int64, limit int) ([]*types.FileResult, error) {
+	return hsdb.LargeFiles(b.db, threshold, limit)
+}
+
+func (b *localBackend) Dupes(opts hsdb.DupeOptions) ([]*hsdb.DupeGroup, error) {
+	return hsdb.Dupes(b.db, opts)
+}
+
+func (b *localBackend) NearDupes(opts hsdb.NearDupeOptions) ([]*hsdb.NearDupe, error) {
+	return hsdb.NearDupes(b.db, opts)
+}
+
+func (b *localBackend) Manifest(hash string) (*scantypes.ChunkManifest, error) {
+	return hsdb.Manifest(b.db, hash)
+}
+
+func (b *localBackend) ChunkSources(hash, host string) ([]*hsdb.ChunkSource, error) {
+	return hsdb.ChunkSources(b.db, hash, host)
+}
+
+func (b *localBackend) Coverage(opts hsdb.CoverageOptions) (*hsdb.CoverageReport, error) {
+	return hsdb.Coverage(b.db, opts)
+}
+
+func (b *localBackend) Diff(from, to hsdb.TreeRef) (*hsdb.TreeDiff, error) {
+	return hsdb.Diff(b.db, from, to)
+}
+
+func (b *localBackend) DU(tree hsdb.TreeRef, opts hsdb.DUOptions) (*hsdb.DUNode, error) {
+	return hsdb.DU(b.db, tree, opts)
+}
+
+func (b *localBackend) List(dir hsdb.TreeRef) (*hsdb.Listing, error) {
+	return hsdb.List(b.db, dir)
+}
+
+func (b *localBackend) Recent(opts hsdb.RecentOptions) ([]*hsdb.Change, error) {
+	return hsdb.Recent(b.db, opts)
+}
+
+func (b *localBackend) Timeline(opts hsdb.RecentOptions) ([]*hsdb.TimelineDay, error) {
+	return hsdb.Timeline(b.db, opts)
+}
+
+func (b *localBackend) Integrity(opts hsdb.IntegrityOptions) ([]*hsdb.IntegrityEvent, error) {
+	return hsdb.IntegrityEvents(b.db, opts)
+}
+
+func (b *localBackend) Alerts(opts hsdb.AnomalyOptions) ([]*hsdb.AnomalyAlert, error) {
+	return hsdb.AnomalyAlerts(b.db, opts)
+}
+
+func (b *localBackend) QuietPeriods() ([]*hsdb.QuietPeriod, error) {
+	return hsdb.QuietPeriods(b.db)
+}
+
+func (b *localBackend) SetQuiet(host string, until time.Time, reason string) (*hsdb.QuietPeriod, error) {
+	rw, err := b.writer()
+	if err != nil {
+		return nil, err
+	}
+
+	q := &hsdb.QuietPeriod{Host: host, Until: until, Reason: reason}
+	if err := hsdb.SetQuiet(rw, q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (b *localBackend) DeleteQuiet(host string) error {
+	rw, err := b.writer()
+	if err != nil {
+		return err
+	}
+	return hsdb.DeleteQuiet(rw, host)
+}
+
+func (b *localBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rw != nil {
+		b.rw.Close()
+	}
+	return b.pool.Close()
+}
+
+// remoteBackend runs queries on an API server.
+type remoteBackend struct {
+	name   string
+	client *api.Client
+}
+
+func (b *remoteBackend) Name() string {
+	return b.name
+}
+
+func (b *remoteBackend) Search(query string, opts hsdb.SearchOptions) (*hsdb.SearchPage, error) {
+	return b.client.SearchFiles(query, opts)
+}
+
+func (b *remoteBackend) Facets(query string) (*hsdb.Facets, error) {
+	return b.client.Facets(query, hsdb.SearchOptions{})
+}
+
+func (b *remoteBackend) Tags(f *types.FileResult) ([]string, error) {
+	return b.client.FileTags(f.FileHash, f.Host, f.FilePath)
+}
+
+func (b *remoteBackend) SetTags(f *types.FileResult, tags []string) error {
+	_, err := b.client.SetTags(f.FileHash, f.Host, f.FilePath, tags)
+	return err
+}
+
+func (b *remoteBackend) Hosts() ([]*hsdb.HostStat, error) {
+	return b.client.Hosts()
+}
+
+func (b *remoteBackend) AllTags() ([]string, error) {
+	return b.client.Tags()
+}
+
+func (b *remoteBackend) File(hash string) (*hsdb.FileDetail, error) {
+	return b.client.File(hash)
+}
+
+func (b *remoteBackend) ExtensionStats(orderBy string, descending bool, host string, limit int) (*hsdb.Stats, error) {
+	return b.client.ExtensionStats(orderBy, descending, host, limit)
+}
+
+func (b *remoteBackend) LargeFiles(threshold int64, limit int) ([]*types.FileResult, error) {
+	return b.client.LargeFiles(threshold, limit)
+}
+
+func (b *remoteBackend) Dupes(opts hsdb.DupeOptions) ([]*hsdb.DupeGroup, error) {
+	return b.client.Dupes(opts)
+}
+
+func (b *remoteBackend) NearDupes(opts hsdb.NearDupeOptions) ([]*hsdb.NearDupe, error) {
+	return b.client.NearDupes(opts)
+}
+
+func (b *remoteBackend) Manifest(hash string) (*scantypes.ChunkManifest, error) {
+	return b.client.Manifest(hash)
+}
+
+func (b *remoteBackend) ChunkSources(hash, host string) ([]*hsdb.ChunkSource, error) {
+	return b.client.ChunkSources(hash, host)
+}
+
+func (b *remoteBackend) Coverage(opts hsdb.CoverageOptions) (*hsdb.CoverageReport, error) {
+	return b.client.Coverage(opts)
+}
+
+func (b *remoteBackend) Diff(from, to hsdb.TreeRef) (*hsdb.TreeDiff, error) {
+	return b.client.Diff(from, to)
+}
+
+func (b *remoteBackend) DU(tree hsdb.TreeRef, opts hsdb.DUOptions) (*hsdb.DUNode, error) {
+	return b.client.DU(tree, opts)
+}
+
+func (b *remoteBackend) List(dir hsdb.TreeRef) (*hsdb.Listing, error) {
+	return b.client.List(dir)
+}
+
+func (b *remoteBackend) Recent(opts hsdb.RecentOptions) ([]*hsdb.Change, error) {
+	return b.client.Recent(opts)
+}
+
+func (b *remoteBackend) Timeline(opts hsdb.RecentOptions) ([]*hsdb.TimelineDay, error) {
+	return b.client.Timeline(opts)
+}
+
+func (b *remoteBackend) Integrity(opts hsdb.IntegrityOptions) ([]*hsdb.IntegrityEvent, error) {
+	return b.client.Integrity(opts)
+}
+
+func (b *remoteBackend) Alerts(opts hsdb.AnomalyOptions) ([]*hsdb.AnomalyAlert, error) {
+	return b.client.Alerts(opts)
+}
+
+func (b *remoteBackend) QuietPeriods() ([]*hsdb.QuietPeriod, error) {
+	return b.client.QuietPeriods()
+}
+
+func (b *remoteBackend) SetQuiet(host string, until time.Time, reason string) (*hsdb.QuietPeriod, error) {
+	return b.client.SetQuiet(host, until, reason)
+}
+
+func (b *remoteBackend) DeleteQuiet(host string) error {
+	return b.client.DeleteQuiet(host)
+}
+
+func (b *remoteBackend) Close() error {
+	return nil
+}
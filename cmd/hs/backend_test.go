@@ -0,0 +1,44 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalBackendOriginalSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "hashup.db")
+	schema, err := os.ReadFile("../../internal/db/testdata/original.sql")
+	require.NoError(t, err)
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec(string(schema) + `
+		INSERT INTO file_hashes (id, file_hash) VALUES (1, 'hash1');
+		INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash)
+		VALUES ('/data/notes.txt', 100, '2024-01-01 00:00:00', 1, 'nas', 'txt', 'hash1');
+	`)
+	require.NoError(t, err)
+	db.Close()
+
+	b, err := openLocalBackend(dbPath)
+	require.NoError(t, err)
+	defer b.Close()
+
+	l, err := b.List(hsdb.TreeRef{Host: "nas", Path: "/data"})
+	require.NoError(t, err)
+	assert.Len(t, l.Files, 1)
+	changes, err := b.Recent(hsdb.RecentOptions{})
+	require.NoError(t, err)
+	assert.Len(t, changes, 1)
+	_, err = b.Timeline(hsdb.RecentOptions{})
+	assert.NoError(t, err)
+	_, err = b.Integrity(hsdb.IntegrityOptions{})
+	assert.NoError(t, err)
+	_, err = b.Alerts(hsdb.AnomalyOptions{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dustin/go-humanize"
+	_ "github.com/mattn/go-sqlite3"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/output"
+	"github.com/urfave/cli/v2"
+)
+
+func commandCoverage() *cli.Command {
+	return &cli.Command{
+		Name:  "coverage",
+		Usage: "Report files with too few copies across hosts",
+		Flags: append([]cli.Flag{
+			&cli.IntFlag{
+				Name:  "min-copies",
+				Usage: "Number of distinct hosts a file must be on to be backed up",
+				Value: 2,
+			},
+			&cli.StringSliceFlag{
+				Name:  "require-host",
+				Usage: "Backup host that must hold a copy (can be repeated)",
+			},
+			&cli.StringSliceFlag{
+				Name:    "host",
+				Aliases: []string{"H"},
+				Usage:   "Only report files on these hosts",
+			},
+			&cli.StringFlag{
+				Name:  "path",
+				Usage: "Only report files under this path prefix",
+			},
+			&cli.StringFlag{
+				Name:  "min-size",
+				Usage: "Minimum file size (e.g. 10MB)",
+				Value: "0",
+			},
+			&cli.IntFlag{
+				Name:  "depth",
+				Usage: "Aggregate by the first N path components instead of the parent directory",
+			},
+			&cli.IntFlag{
+				Name:    "limit",
+				Aliases: []string{"l"},
+				Usage:   "Maximum number of directories to report",
+				Value:   50,
+			},
+		}, append(backendFlags(), outputFlags()...)...),
+		Action: func(c *cli.Context) error {
+			p, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+
+			minSize, err := humanize.ParseBytes(c.String("min-size"))
+			if err != nil {
+				return fmt.Errorf("invalid minimum size: %v", err)
+			}
+
+			opts := hsdb.CoverageOptions{
+				MinCopies:     c.Int("min-copies"),
+				RequiredHosts: c.StringSlice("require-host"),
+				Hosts:         c.StringSlice("host"),
+				PathPrefix:    c.String("path"),
+				MinSize:       int64(minSize),
+				Depth:         c.Int("depth"),
+				Limit:         c.Int("limit"),
+			}
+
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			report, err := b.Coverage(opts)
+			if err != nil {
+				return fmt.Errorf("failed to compute coverage: %v", err)
+			}
+
+			return output.Write(p, report.Dirs, output.Spec[*hsdb.CoverageDir]{
+				Columns: []output.Column[*hsdb.CoverageDir]{
+					{Header: "HOST", Value: func(d *hsdb.CoverageDir) any { return d.Host }},
+					{Header: "DIR", Value: func(d *hsdb.CoverageDir) any { return d.Dir }},
+					{Header: "FILES", Value: func(d *hsdb.CoverageDir) any { return d.Files }},
+					{Header: "SIZE", Value: func(d *hsdb.CoverageDir) any { return output.Bytes(d.Size) }},
+					{Header: "MIN HOSTS", Value: func(d *hsdb.CoverageDir) any { return d.MinHosts }},
+				},
+				Key:      func(d *hsdb.CoverageDir) string { return d.Dir },
+				Document: report,
+				Text: func(w io.Writer) error {
+					printCoverage(w, report)
+					return nil
+				},
+			})
+		},
+	}
+}
+
+func printCoverage(w io.Writer, report *hsdb.CoverageReport) {
+	for _, d := range report.Dirs {
+		where := "only on one host"
+		if d.MinHosts > 1 {
+			where = fmt.Sprintf("on as few as %d hosts", d.MinHosts)
+		}
+		fmt.Fprintf(w, "%s on %s: %s files, %s %s\n",
+			d.Dir, d.Host, humanize.Comma(d.Files), humanize.Bytes(uint64(d.Size)), where)
+	}
+
+	fmt.Fprintf(w, "\n%s files (%s) with fewer than %d copies",
+		humanize.Comma(report.TotalFiles), humanize.Bytes(uint64(report.TotalSize)), report.MinCopies)
+	if len(report.RequiredHosts) > 0 {
+		fmt.Fprintf(w, " or missing from %v", report.RequiredHosts)
+	}
+	fmt.Fprintln(w)
+}
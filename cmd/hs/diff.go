@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dustin/go-humanize"
+	_ "github.com/mattn/go-sqlite3"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/output"
+	"github.com/urfave/cli/v2"
+)
+
+func commandDiff() *cli.Command {
+	return &cli.Command{
+		Name:      "diff",
+		Usage:     "Compare two indexed directory trees",
+		ArgsUsage: "HOST:/PATH HOST:/OTHER/PATH",
+		Flags:     append(backendFlags(), outputFlags()...),
+		Action: func(c *cli.Context) error {
+			p, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+
+			if c.NArg() != 2 {
+				return fmt.Errorf("two trees to compare are required")
+			}
+
+			from, err := hsdb.ParseTreeRef(c.Args().Get(0))
+			if err != nil {
+				return err
+			}
+			to, err := hsdb.ParseTreeRef(c.Args().Get(1))
+			if err != nil {
+				return err
+			}
+
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			diff, err := b.Diff(from, to)
+			if err != nil {
+				return fmt.Errorf("failed to compare trees: %v", err)
+			}
+
+			var rows []*diffRow
+			for _, set := range []struct {
+				status  string
+				entries []*hsdb.DiffEntry
+			}{
+				{"missing", diff.Missing},
+				{"extra", diff.Extra},
+				{"changed", diff.Changed},
+				{"moved", diff.Moved},
+			} {
+				for _, e := range set.entries {
+					rows = append(rows, &diffRow{Status: set.status, DiffEntry: e})
+				}
+			}
+
+			return output.Write(p, rows, output.Spec[*diffRow]{
+				Columns: []output.Column[*diffRow]{
+					{Header: "STATUS", Value: func(r *diffRow) any { return r.Status }},
+					{Header: "PATH", Value: func(r *diffRow) any { return r.Path }},
+					{Header: "TO", Value: func(r *diffRow) any { return r.To }},
+					{Header: "SIZE", Value: func(r *diffRow) any { return output.Bytes(r.Size) }},
+					{Header: "HASH", Value: func(r *diffRow) any { return r.Hash }},
+				},
+				Key:      func(r *diffRow) string { return r.Path },
+				Document: diff,
+				Text: func(w io.Writer) error {
+					printDiff(w, diff)
+					return nil
+				},
+			})
+		},
+	}
+}
+
+// diffRow is a differing file with its status, for row based output.
+type diffRow struct {
+	Status string `json:"status"`
+	*hsdb.DiffEntry
+}
+
+func printDiff(w io.Writer, d *hsdb.TreeDiff) {
+	for _, e := range d.Missing {
+		fmt.Fprintf(w, "- %s (%s)\n", e.Path, humanize.Bytes(uint64(e.Size)))
+	}
+	for _, e := range d.Extra {
+		fmt.Fprintf(w, "+ %s (%s)\n", e.Path, humanize.Bytes(uint64(e.Size)))
+	}
+	for _, e := range d.Changed {
+		fmt.Fprintf(w, "~ %s (%s -> %s)\n", e.Path, e.Hash, e.ToHash)
+	}
+	for _, e := range d.Moved {
+		fmt.Fprintf(w, "> %s -> %s\n", e.Path, e.To)
+	}
+
+	fmt.Fprintf(w, "\n%s vs %s: %d identical, %d missing, %d extra, %d changed, %d moved\n",
+		d.From, d.To, d.Same, len(d.Missing), len(d.Extra), len(d.Changed), len(d.Moved))
+}
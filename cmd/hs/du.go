@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/output"
+	"github.com/urfave/cli/v2"
+)
+
+func commandDU() *cli.Command {
+	return &cli.Command{
+		Name:      "du",
+		Usage:     "Show indexed disk usage by directory",
+		ArgsUsage: "HOST[:/PATH]",
+		Flags: append([]cli.Flag{
+			&cli.IntFlag{
+				Name:    "depth",
+				Aliases: []string{"d"},
+				Usage:   "Directory levels to show below PATH",
+				Value:   1,
+			},
+			&cli.StringFlag{
+				Name:  "sort",
+				Usage: "Sort directories by size, files or name",
+				Value: hsdb.DUSortSize,
+			},
+		}, append(backendFlags(), outputFlags()...)...),
+		Action: func(c *cli.Context) error {
+			p, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+
+			if c.NArg() != 1 {
+				return fmt.Errorf("a host or host:/path argument is required")
+			}
+
+			arg := c.Args().First()
+			if !strings.Contains(arg, ":") {
+				arg += ":/"
+			}
+			tree, err := hsdb.ParseTreeRef(arg)
+			if err != nil {
+				return err
+			}
+
+			opts := hsdb.DUOptions{Depth: c.Int("depth"), Sort: c.String("sort")}
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			root, err := b.DU(tree, opts)
+			if err != nil {
+				return fmt.Errorf("failed to compute disk usage: %v", err)
+			}
+
+			var rows []*duRow
+			root.Walk(func(n *hsdb.DUNode, depth int) {
+				rows = append(rows, &duRow{Path: n.Path, Depth: depth, Size: n.Size, Files: n.Files, Share: share(n.Size, root.Size)})
+			})
+
+			return output.Write(p, rows, output.Spec[*duRow]{
+				Columns: []output.Column[*duRow]{
+					{Header: "SIZE", Value: func(r *duRow) any { return output.Bytes(r.Size) }},
+					{Header: "FILES", Value: func(r *duRow) any { return r.Files }},
+					{Header: "SHARE", Value: func(r *duRow) any { return fmt.Sprintf("%.1f%%", r.Share) }},
+					{Header: "PATH", Value: func(r *duRow) any { return strings.Repeat("  ", r.Depth) + r.Path }},
+				},
+				Key:      func(r *duRow) string { return r.Path },
+				Document: root,
+			})
+		},
+	}
+}
+
+// duRow is a directory of the disk usage tree, for row based output.
+type duRow struct {
+	Path  string  `json:"path"`
+	Depth int     `json:"depth"`
+	Size  int64   `json:"size"`
+	Files int64   `json:"files"`
+	Share float64 `json:"share"`
+}
+
+// share returns size as a percentage of total.
+func share(size, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(size) * 100 / float64(total)
+}
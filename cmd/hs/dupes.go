@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dustin/go-humanize"
+	_ "github.com/mattn/go-sqlite3"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/output"
+	"github.com/urfave/cli/v2"
+)
+
+func commandDupes() *cli.Command {
+	return &cli.Command{
+		Name:  "dupes",
+		Usage: "List duplicated files and the space they waste",
+		Description: "With --near, lists pairs of different contents sharing most of their\n" +
+			"content-defined chunks instead, like edited videos or disk image snapshots.\n" +
+			"Only files scanned with chunking enabled (scanner.chunking or --chunks) are\n" +
+			"compared.",
+		Flags: append([]cli.Flag{
+			&cli.StringSliceFlag{
+				Name:    "host",
+				Aliases: []string{"H"},
+				Usage:   "Only consider files from these hosts",
+			},
+			&cli.StringFlag{
+				Name:  "min-size",
+				Usage: "Minimum file size (e.g. 10MB)",
+				Value: "1",
+			},
+			&cli.StringFlag{
+				Name:  "path",
+				Usage: "Only consider files under this path prefix",
+			},
+			&cli.StringSliceFlag{
+				Name:    "type",
+				Aliases: []string{"t"},
+				Usage:   "File type (image, video, audio, document, archive) or extension",
+			},
+			&cli.IntFlag{
+				Name:    "limit",
+				Aliases: []string{"l"},
+				Usage:   "Maximum number of duplicate groups",
+				Value:   20,
+			},
+			&cli.BoolFlag{
+				Name:  "near",
+				Usage: "List near-duplicates, contents sharing most of their chunks",
+			},
+			&cli.Float64Flag{
+				Name:  "similarity",
+				Usage: "Minimum fraction of the larger content shared with --near",
+				Value: 0.5,
+			},
+		}, append(backendFlags(), outputFlags()...)...),
+		Action: func(c *cli.Context) error {
+			p, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+
+			minSize, err := humanize.ParseBytes(c.String("min-size"))
+			if err != nil {
+				return fmt.Errorf("invalid minimum size: %v", err)
+			}
+
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			if c.Bool("near") {
+				if s := c.Float64("similarity"); s <= 0 || s > 1 {
+					return fmt.Errorf("--similarity must be between 0 and 1")
+				}
+				return nearDupes(b, p, hsdb.NearDupeOptions{
+					Hosts:         c.StringSlice("host"),
+					MinSize:       int64(minSize),
+					PathPrefix:    c.String("path"),
+					Types:         c.StringSlice("type"),
+					MinSimilarity: c.Float64("similarity"),
+					Limit:         c.Int("limit"),
+				})
+			}
+
+			opts := hsdb.DupeOptions{
+				Hosts:      c.StringSlice("host"),
+				MinSize:    int64(minSize),
+				PathPrefix: c.String("path"),
+				Types:      c.StringSlice("type"),
+				Limit:      c.Int("limit"),
+			}
+
+			groups, err := b.Dupes(opts)
+			if err != nil {
+				return fmt.Errorf("failed to find duplicates: %v", err)
+			}
+
+			var rows []*dupeRow
+			for _, g := range groups {
+				for _, f := range g.Files {
+					rows = append(rows, &dupeRow{
+						Hash: g.Hash, Size: g.Size, Copies: g.Copies, Wasted: g.Wasted,
+						Host: f.Host, Path: f.FilePath,
+					})
+				}
+			}
+
+			return output.Write(p, rows, output.Spec[*dupeRow]{
+				Columns: []output.Column[*dupeRow]{
+					{Header: "HASH", Value: func(r *dupeRow) any { return r.Hash }},
+					{Header: "SIZE", Value: func(r *dupeRow) any { return output.Bytes(r.Size) }},
+					{Header: "COPIES", Value: func(r *dupeRow) any { return r.Copies }},
+					{Header: "WASTED", Value: func(r *dupeRow) any { return output.Bytes(r.Wasted) }},
+					{Header: "HOST", Value: func(r *dupeRow) any { return r.Host }},
+					{Header: "PATH", Value: func(r *dupeRow) any { return r.Path }},
+				},
+				Key:      func(r *dupeRow) string { return r.Path },
+				Document: groups,
+				Text: func(w io.Writer) error {
+					printDupes(w, groups)
+					return nil
+				},
+			})
+		},
+	}
+}
+
+// dupeRow is a single copy of a duplicated file, for row based output.
+type dupeRow struct {
+	Hash   string `json:"hash"`
+	Size   int64  `json:"size"`
+	Copies int    `json:"copies"`
+	Wasted int64  `json:"wasted"`
+	Host   string `json:"host"`
+	Path   string `json:"path"`
+}
+
+func printDupes(w io.Writer, groups []*hsdb.DupeGroup) {
+	var wasted int64
+	for _, g := range groups {
+		wasted += g.Wasted
+		fmt.Fprintf(w, "%s  %d copies of %s, %s reclaimable\n",
+			g.Hash, g.Copies, humanize.Bytes(uint64(g.Size)), humanize.Bytes(uint64(g.Wasted)))
+		for _, f := range g.Files {
+			fmt.Fprintf(w, "  %-12s %s\n", f.Host, f.FilePath)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d duplicate groups, %s reclaimable\n", len(groups), humanize.Bytes(uint64(wasted)))
+}
+
+func nearDupes(b backend, p *output.Printer, opts hsdb.NearDupeOptions) error {
+	dupes, err := b.NearDupes(opts)
+	if err != nil {
+		return fmt.Errorf("failed to find near-duplicates: %v", err)
+	}
+
+	var rows []*nearDupeRow
+	for _, d := range dupes {
+		for _, f := range d.Files {
+			rows = append(rows, &nearDupeRow{
+				Hash: d.Hash, OtherHash: d.OtherHash, Shared: d.Shared, Similarity: d.Similarity,
+				FileHash: f.FileHash, Host: f.Host, Path: f.FilePath,
+			})
+		}
+	}
+
+	return output.Write(p, rows, output.Spec[*nearDupeRow]{
+		Columns: []output.Column[*nearDupeRow]{
+			{Header: "HASH", Value: func(r *nearDupeRow) any { return r.Hash }},
+			{Header: "OTHER", Value: func(r *nearDupeRow) any { return r.OtherHash }},
+			{Header: "SIMILARITY", Value: func(r *nearDupeRow) any { return fmt.Sprintf("%.2f", r.Similarity) }},
+			{Header: "SHARED", Value: func(r *nearDupeRow) any { return output.Bytes(r.Shared) }},
+			{Header: "FILE HASH", Value: func(r *nearDupeRow) any { return r.FileHash }},
+			{Header: "HOST", Value: func(r *nearDupeRow) any { return r.Host }},
+			{Header: "PATH", Value: func(r *nearDupeRow) any { return r.Path }},
+		},
+		Key:      func(r *nearDupeRow) string { return r.Path },
+		Document: dupes,
+		Text: func(w io.Writer) error {
+			printNearDupes(w, dupes)
+			return nil
+		},
+	})
+}
+
+// nearDupeRow is a single copy of a near-duplicated content, for row based
+// output.
+type nearDupeRow struct {
+	Hash       string  `json:"hash"`
+	OtherHash  string  `json:"other_hash"`
+	Shared     int64   `json:"shared"`
+	Similarity float64 `json:"similarity"`
+	FileHash   string  `json:"file_hash"`
+	Host       string  `json:"host"`
+	Path       string  `json:"path"`
+}
+
+func printNearDupes(w io.Writer, dupes []*hsdb.NearDupe) {
+	for _, d := range dupes {
+		fmt.Fprintf(w, "%s ~ %s  %.0f%% similar, %s shared\n",
+			d.Hash, d.OtherHash, d.Similarity*100, humanize.Bytes(uint64(d.Shared)))
+		for _, f := range d.Files {
+			fmt.Fprintf(w, "  %s  %-12s %s\n", f.FileHash, f.Host, f.FilePath)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d near-duplicate pairs\n", len(dupes))
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/rubiojr/hashup/internal/agent"
+	"github.com/rubiojr/hashup/internal/crypto"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	scantypes "github.com/rubiojr/hashup/internal/types"
+	"github.com/urfave/cli/v2"
+)
+
+func commandGet() *cli.Command {
+	return &cli.Command{
+		Name:  "get",
+		Usage: "Fetch indexed files from remote hosts",
+		Description: "Locates copies of the content through the index and fetches one over NATS from\n" +
+			"the `hashup agent` of its host, trying the other copies if it fails. Transfers\n" +
+			"are encrypted with the encryption key end to end and the content hash is\n" +
+			"verified before the file is written. Contents scanned with chunking enabled\n" +
+			"are rebuilt from the chunks local indexed files already have when possible,\n" +
+			"only the missing ones are transferred.",
+		ArgsUsage: "HASH|HOST:PATH...",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "to",
+				Usage: "Directory to write files to",
+				Value: ".",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Overwrite existing files",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "How long to wait for each chunk of a file",
+				Value: agent.DefaultTimeout,
+			},
+			&cli.StringFlag{
+				Name:    "nats-url",
+				Usage:   "NATS URL (defaults to main.nats_server_url)",
+				EnvVars: []string{"HASHUP_NATS_URL"},
+			},
+		}, backendFlags()...),
+		Action: func(c *cli.Context) error {
+			if c.NArg() == 0 {
+				return fmt.Errorf("a hash or host:path argument is required")
+			}
+			for _, arg := range c.Args().Slice() {
+				if !strings.Contains(arg, ":") && !hashPattern.MatchString(arg) {
+					return fmt.Errorf("invalid hash %q: expected 16 hexadecimal characters", arg)
+				}
+			}
+			if info, err := os.Stat(c.String("to")); err != nil || !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", c.String("to"))
+			}
+
+			cfg, err := loadConfig(c)
+			if err != nil {
+				return err
+			}
+			if url := c.String("nats-url"); url != "" {
+				cfg.Main.NatsServerURL = url
+			}
+			machine, err := crypto.NewAge(cfg.Main.EncryptionKey)
+			if err != nil {
+				return fmt.Errorf("failed to create crypto instance: %v", err)
+			}
+
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			hostname, err := os.Hostname()
+			if err != nil {
+				return err
+			}
+
+			nc, err := agent.Connect(cfg)
+			if err != nil {
+				return err
+			}
+			defer nc.Close()
+
+			g := &getter{
+				b:      b,
+				client: agent.NewClient(nc, machine, cfg.Agent.Subject, agent.WithTimeout(c.Duration("timeout"))),
+				to:     c.String("to"),
+				force:  c.Bool("force"),
+				host:   hostname,
+			}
+			for _, arg := range c.Args().Slice() {
+				if err := g.get(c.Context, arg); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// hashPattern matches content hashes, hex encoded xxHash64 sums.
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// getter fetches indexed files to a local directory.
+type getter struct {
+	b      backend
+	client *agent.Client
+	to     string
+	force  bool
+	// host is the local host, whose indexed files chunks are read from.
+	host string
+}
+
+// get fetches the content of arg, a hash or a host:path reference.
+func (g *getter) get(ctx context.Context, arg string) error {
+	hash, preferred, err := g.resolve(arg)
+	if err != nil {
+		return err
+	}
+
+	d, err := g.b.File(hash)
+	if err != nil {
+		return fmt.Errorf("failed to look up %s: %v", hash, err)
+	}
+	copies := d.Copies
+	if preferred != nil {
+		// Try the requested copy first
+		i := slices.IndexFunc(copies, func(c *hsdb.FileCopy) bool {
+			return c.Host == preferred.Host && c.FilePath == preferred.Path
+		})
+		if i > 0 {
+			copies = append([]*hsdb.FileCopy{copies[i]}, slices.Delete(copies, i, i+1)...)
+		}
+	}
+	if len(copies) == 0 {
+		return fmt.Errorf("%s: %w", hash, hsdb.ErrFileNotFound)
+	}
+
+	name := path.Base(copies[0].FilePath)
+	if preferred != nil {
+		name = path.Base(preferred.Path)
+	}
+	dest := filepath.Join(g.to, name)
+	if _, err := os.Lstat(dest); err == nil && !g.force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", dest)
+	}
+
+	m, sources := g.chunks(hash)
+
+	var errs []error
+	for _, cp := range copies {
+		size, reused, err := g.fetch(ctx, cp, hash, dest, m, sources)
+		if err == nil {
+			fmt.Printf("%s (%s) from %s:%s", dest, humanize.Bytes(uint64(size)), cp.Host, cp.FilePath)
+			if reused > 0 {
+				fmt.Printf(", %s reused from local files", humanize.Bytes(uint64(reused)))
+			}
+			fmt.Println()
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s:%s: %w", cp.Host, cp.FilePath, err))
+	}
+	return fmt.Errorf("failed to fetch %s:\n%w", hash, errors.Join(errs...))
+}
+
+// resolve returns the hash of the content arg refers to and, for host:path
+// references, the copy to try first.
+func (g *getter) resolve(arg string) (string, *hsdb.TreeRef, error) {
+	if !strings.Contains(arg, ":") {
+		return arg, nil, nil
+	}
+
+	ref, err := hsdb.ParseTreeRef(arg)
+	if err != nil {
+		return "", nil, err
+	}
+	l, err := g.b.List(hsdb.TreeRef{Host: ref.Host, Path: path.Dir(ref.Path)})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to look up %s: %v", arg, err)
+	}
+	for _, f := range l.Files {
+		if f.FilePath == ref.Path {
+			return f.FileHash, &ref, nil
+		}
+	}
+	return "", nil, fmt.Errorf("%s: %w", arg, hsdb.ErrFileNotFound)
+}
+
+// chunks returns the chunk manifest of the content and the local files
+// holding some of its chunks, nil when there are none.
+func (g *getter) chunks(hash string) (*scantypes.ChunkManifest, []*hsdb.ChunkSource) {
+	m, err := g.b.Manifest(hash)
+	if err != nil {
+		// Not chunked, or an index predating chunking
+		return nil, nil
+	}
+	sources, err := g.b.ChunkSources(hash, g.host)
+	if err != nil || len(sources) == 0 {
+		return nil, nil
+	}
+	return m, sources
+}
+
+// fetch writes the copy to dest through a temporary file in the same
+// directory, renamed once the content is verified against hash. It returns
+// the size of the file and how much of it was read from local files.
+func (g *getter) fetch(ctx context.Context, cp *hsdb.FileCopy, hash, dest string, m *scantypes.ChunkManifest, sources []*hsdb.ChunkSource) (int64, int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".hs-get-*")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	var size, reused int64
+	if m != nil {
+		size, reused, err = g.client.FetchChunks(ctx, cp.Host, cp.FilePath, hash, m, sources, tmp)
+	} else {
+		size, err = g.client.Fetch(ctx, cp.Host, cp.FilePath, hash, tmp)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return 0, 0, err
+	}
+	if err := os.Chtimes(tmp.Name(), cp.ModifiedDate, cp.ModifiedDate); err != nil {
+		return 0, 0, err
+	}
+	return size, reused, os.Rename(tmp.Name(), dest)
+}
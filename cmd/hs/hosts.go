@@ -1,11 +1,9 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
-
-	"github.com/dustin/go-humanize"
 	_ "github.com/mattn/go-sqlite3"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/output"
 	"github.com/urfave/cli/v2"
 )
 
@@ -13,56 +11,32 @@ func commandHosts() *cli.Command {
 	return &cli.Command{
 		Name:  "hosts",
 		Usage: "List available hosts",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:     "db",
-				Usage:    "Database path",
-				Value:    "",
-				Required: false,
-			},
-		},
+		Flags: append(backendFlags(), outputFlags()...),
 		Action: func(c *cli.Context) error {
-			db, err := dbConn(c.String("db"))
+			p, err := newPrinter(c)
 			if err != nil {
-				return fmt.Errorf("failed to open database: %v", err)
+				return err
 			}
-			defer db.Close()
-
-			return listHosts(db)
-		},
-	}
-}
-
-func listHosts(db *sql.DB) error {
-	query := `
-		SELECT host, COUNT(*) as count, SUM(file_size) AS total_size
-		FROM file_info
-		GROUP BY host
-	`
-
-	rows, err := db.Query(query)
-	if err != nil {
-		return fmt.Errorf("failed to query database: %v", err)
-	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var count, sum int
-		var host string
-
-		err := rows.Scan(&host, &count, &sum)
-		if err != nil {
-			return fmt.Errorf("failed to scan row: %v", err)
-		}
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
 
-		// humanize sum
-		humanizedSum := humanize.Bytes(uint64(sum))
-		fmt.Printf(fmt.Sprintf("%-30s %-10d %-10s\n", host, count, humanizedSum))
-	}
+			hosts, err := b.Hosts()
+			if err != nil {
+				return err
+			}
 
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating over rows: %v", err)
+			return output.Write(p, hosts, output.Spec[*hsdb.HostStat]{
+				Columns: []output.Column[*hsdb.HostStat]{
+					{Header: "HOST", Value: func(h *hsdb.HostStat) any { return h.Host }},
+					{Header: "FILES", Value: func(h *hsdb.HostStat) any { return h.Files }},
+					{Header: "SIZE", Value: func(h *hsdb.HostStat) any { return output.Bytes(h.Size) }},
+				},
+				Key: func(h *hsdb.HostStat) string { return h.Host },
+			})
+		},
 	}
-
-	return nil
 }
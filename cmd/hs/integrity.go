@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dustin/go-humanize"
+	_ "github.com/mattn/go-sqlite3"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/output"
+	"github.com/urfave/cli/v2"
+)
+
+func commandIntegrity() *cli.Command {
+	return &cli.Command{
+		Name:  "integrity",
+		Usage: "List files suspected of bit-rot",
+		Description: "Lists files whose content changed while their size and modification time did not,\n" +
+			"a sign of silent corruption, with the copies of the expected content on other hosts.\n" +
+			"Run `hashup verify` to re-hash files the scanner cache would skip.",
+		Flags: append([]cli.Flag{
+			&cli.StringSliceFlag{
+				Name:    "host",
+				Aliases: []string{"H"},
+				Usage:   "Only list files from these hosts",
+			},
+			&cli.BoolFlag{
+				Name:  "resolved",
+				Usage: "Include files stored again without the mismatch since",
+			},
+			&cli.IntFlag{
+				Name:    "limit",
+				Aliases: []string{"l"},
+				Usage:   "Maximum number of files",
+				Value:   100,
+			},
+		}, append(backendFlags(), outputFlags()...)...),
+		Action: func(c *cli.Context) error {
+			p, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			events, err := b.Integrity(hsdb.IntegrityOptions{
+				Hosts:    c.StringSlice("host"),
+				Resolved: c.Bool("resolved"),
+				Limit:    c.Int("limit"),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to query integrity events: %v", err)
+			}
+
+			return output.Write(p, events, output.Spec[*hsdb.IntegrityEvent]{
+				Columns: []output.Column[*hsdb.IntegrityEvent]{
+					{Header: "DETECTED", Value: func(e *hsdb.IntegrityEvent) any { return e.Detected.Format("2006-01-02 15:04") }},
+					{Header: "HOST", Value: func(e *hsdb.IntegrityEvent) any { return e.Host }},
+					{Header: "PATH", Value: func(e *hsdb.IntegrityEvent) any { return e.FilePath }},
+					{Header: "EXPECTED", Value: func(e *hsdb.IntegrityEvent) any { return e.ExpectedHash }},
+					{Header: "ACTUAL", Value: func(e *hsdb.IntegrityEvent) any { return e.ActualHash }},
+					{Header: "GOOD COPIES", Value: func(e *hsdb.IntegrityEvent) any { return len(e.GoodCopies) }},
+				},
+				Key: func(e *hsdb.IntegrityEvent) string { return e.FilePath },
+				Text: func(w io.Writer) error {
+					printIntegrity(w, events)
+					return nil
+				},
+			})
+		},
+	}
+}
+
+func printIntegrity(w io.Writer, events []*hsdb.IntegrityEvent) {
+	host := ""
+	for _, e := range events {
+		if e.Host != host {
+			host = e.Host
+			fmt.Fprintf(w, "%s\n", host)
+		}
+
+		status := "detected " + e.Detected.Format("2006-01-02 15:04")
+		if e.Resolved != nil {
+			status = "resolved " + e.Resolved.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "  %s (%s, %s)\n", e.FilePath, humanize.Bytes(uint64(e.FileSize)), status)
+		fmt.Fprintf(w, "    expected %s, found %s\n", e.ExpectedHash, e.ActualHash)
+		if len(e.GoodCopies) == 0 {
+			fmt.Fprintf(w, "    no good copies indexed\n")
+		}
+		for _, f := range e.GoodCopies {
+			fmt.Fprintf(w, "    good copy: %-12s %s\n", f.Host, f.FilePath)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d files suspected of corruption\n", len(events))
+}
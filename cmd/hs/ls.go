@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/output"
+	"github.com/urfave/cli/v2"
+)
+
+func commandLs() *cli.Command {
+	return &cli.Command{
+		Name:      "ls",
+		Usage:     "List an indexed directory",
+		ArgsUsage: "HOST[:/PATH]",
+		Flags:     append(backendFlags(), outputFlags()...),
+		Action: func(c *cli.Context) error {
+			p, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+
+			if c.NArg() != 1 {
+				return fmt.Errorf("a host or host:/path argument is required")
+			}
+
+			arg := c.Args().First()
+			if !strings.Contains(arg, ":") {
+				arg += ":/"
+			}
+			dir, err := hsdb.ParseTreeRef(arg)
+			if err != nil {
+				return err
+			}
+
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			listing, err := b.List(dir)
+			if err != nil {
+				return fmt.Errorf("failed to list directory: %v", err)
+			}
+
+			var rows []*lsRow
+			for _, d := range listing.Dirs {
+				rows = append(rows, &lsRow{Type: "dir", Name: d.Name + "/", Path: d.Path, Size: d.Size, Files: d.Files})
+			}
+			for _, f := range listing.Files {
+				rows = append(rows, &lsRow{
+					Type: "file", Name: f.FilePath[strings.LastIndex(f.FilePath, "/")+1:], Path: f.FilePath,
+					Size: f.FileSize, Files: 1, Modified: &f.ModifiedDate, Hash: f.FileHash,
+				})
+			}
+
+			return output.Write(p, rows, output.Spec[*lsRow]{
+				Columns: []output.Column[*lsRow]{
+					{Header: "SIZE", Value: func(r *lsRow) any { return output.Bytes(r.Size) }},
+					{Header: "FILES", Value: func(r *lsRow) any { return r.Files }},
+					{Header: "MODIFIED", Value: func(r *lsRow) any {
+						if r.Modified == nil {
+							return ""
+						}
+						return *r.Modified
+					}},
+					{Header: "HASH", Value: func(r *lsRow) any { return r.Hash }},
+					{Header: "NAME", Value: func(r *lsRow) any { return r.Name }},
+				},
+				Key:      func(r *lsRow) string { return r.Path },
+				Document: listing,
+			})
+		},
+	}
+}
+
+// lsRow is a directory entry, for row based output.
+type lsRow struct {
+	Type     string     `json:"type"`
+	Name     string     `json:"name"`
+	Path     string     `json:"path"`
+	Size     int64      `json:"size"`
+	Files    int64      `json:"files"`
+	Modified *time.Time `json:"modified,omitempty"`
+	Hash     string     `json:"hash,omitempty"`
+}
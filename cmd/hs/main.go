@@ -11,14 +11,34 @@ func main() {
 	app := &cli.App{
 		Name:  "hs",
 		Usage: "Search for files in a Hashub database",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "config",
+				Usage:   "Path to the configuration file (defaults to ~/.config/hashup/config.toml)",
+				EnvVars: []string{"HASHUP_CONFIG"},
+			},
+		},
 	}
 
 	app.Commands = append(
 		app.Commands,
 		commandSearch(),
+		commandDupes(),
+		commandCoverage(),
+		commandDiff(),
+		commandDU(),
+		commandLs(),
+		commandRecent(),
+		commandTimeline(),
+		commandIntegrity(),
+		commandAlerts(),
+		commandWatch(),
+		commandTUI(),
 		commandHosts(),
 		commandFileStats(),
 		commandLargeFiles(),
+		commandWhich(),
+		commandGet(),
 		commandTag(),
 		commandTags(),
 		commandAdmin(),
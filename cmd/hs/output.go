@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+
+	"github.com/rubiojr/hashup/cmd/hs/types"
+	"github.com/rubiojr/hashup/internal/output"
+	"github.com/urfave/cli/v2"
+)
+
+// outputFlags are the output selection flags shared by all listing commands.
+func outputFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "Output format (table, json, ndjson, csv, null)",
+			Value: output.Table,
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "Go template executed for each result, e.g. '{{.FilePath}}'",
+		},
+		&cli.BoolFlag{
+			Name:    "json",
+			Aliases: []string{"j"},
+			Usage:   "Shorthand for --output json",
+		},
+	}
+}
+
+func newPrinter(c *cli.Context) (*output.Printer, error) {
+	format := c.String("output")
+	if c.Bool("json") {
+		format = output.JSON
+	}
+	return output.New(os.Stdout, format, c.String("format"))
+}
+
+// fileColumns are the table and CSV columns used for lists of files.
+var fileColumns = []output.Column[*types.FileResult]{
+	{Header: "SIZE", Value: func(f *types.FileResult) any { return output.Bytes(f.FileSize) }},
+	{Header: "MODIFIED", Value: func(f *types.FileResult) any { return f.ModifiedDate }},
+	{Header: "HOST", Value: func(f *types.FileResult) any { return f.Host }},
+	{Header: "HASH", Value: func(f *types.FileResult) any { return f.FileHash }},
+	{Header: "PATH", Value: func(f *types.FileResult) any { return f.FilePath }},
+}
+
+func printFiles(p *output.Printer, files []*types.FileResult) error {
+	return output.Write(p, files, output.Spec[*types.FileResult]{
+		Columns: fileColumns,
+		Key:     func(f *types.FileResult) string { return f.FilePath },
+	})
+}
+
+// printSourceFiles prints federated search results, with the index each
+// one comes from.
+func printSourceFiles(p *output.Printer, files []*types.FileResult) error {
+	columns := append([]output.Column[*types.FileResult]{
+		{Header: "SOURCE", Value: func(f *types.FileResult) any { return f.Source }},
+	}, fileColumns...)
+	return output.Write(p, files, output.Spec[*types.FileResult]{
+		Columns: columns,
+		Key:     func(f *types.FileResult) string { return f.FilePath },
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/output"
+	"github.com/urfave/cli/v2"
+)
+
+// recentFlags are the flags shared by recent and timeline.
+func recentFlags() []cli.Flag {
+	return append([]cli.Flag{
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "Show changes since an age (30m, 12h, 7d, 2w) or date (YYYY-MM-DD)",
+			Value: "7d",
+		},
+		&cli.StringFlag{
+			Name:  "host",
+			Usage: "Filter by host",
+		},
+		&cli.StringFlag{
+			Name:  "by",
+			Usage: "Date changes by indexing time (indexed) or file modification time (modified)",
+			Value: hsdb.ByIndexed,
+		},
+	}, append(backendFlags(), outputFlags()...)...)
+}
+
+func recentOptions(c *cli.Context) (hsdb.RecentOptions, error) {
+	since, err := hsdb.ParseSince(c.String("since"), time.Now())
+	if err != nil {
+		return hsdb.RecentOptions{}, err
+	}
+
+	return hsdb.RecentOptions{
+		Since: since,
+		Hosts: []string{c.String("host")},
+		By:    c.String("by"),
+		Limit: c.Int("limit"),
+	}, nil
+}
+
+func commandRecent() *cli.Command {
+	return &cli.Command{
+		Name:  "recent",
+		Usage: "Show files added, changed or removed recently",
+		Description: "Lists new files, new versions of indexed files and files removed from disk,\n" +
+			"newest first. Removals are reported by the scanner when it no longer finds a\n" +
+			"file found by the previous scan of the same directory.",
+		Flags: append(recentFlags(),
+			&cli.IntFlag{
+				Name:  "limit",
+				Usage: "Maximum number of changes to show",
+				Value: 100,
+			},
+		),
+		Action: func(c *cli.Context) error {
+			p, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+
+			opts, err := recentOptions(c)
+			if err != nil {
+				return err
+			}
+
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			changes, err := b.Recent(opts)
+			if err != nil {
+				return fmt.Errorf("failed to query recent changes: %v", err)
+			}
+
+			return output.Write(p, changes, output.Spec[*hsdb.Change]{
+				Columns: []output.Column[*hsdb.Change]{
+					{Header: "STATUS", Value: func(ch *hsdb.Change) any { return ch.Status }},
+					{Header: "INDEXED", Value: func(ch *hsdb.Change) any { return ch.IndexedDate.Format("2006-01-02 15:04") }},
+					{Header: "MODIFIED", Value: func(ch *hsdb.Change) any { return ch.ModifiedDate.Format("2006-01-02 15:04") }},
+					{Header: "SIZE", Value: func(ch *hsdb.Change) any { return output.Bytes(ch.FileSize) }},
+					{Header: "HOST", Value: func(ch *hsdb.Change) any { return ch.Host }},
+					{Header: "PATH", Value: func(ch *hsdb.Change) any { return ch.FilePath }},
+				},
+				Key: func(ch *hsdb.Change) string { return ch.FilePath },
+			})
+		},
+	}
+}
@@ -1,14 +1,16 @@
 package main
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/rubiojr/hashup/cmd/hs/types"
 	"github.com/rubiojr/hashup/internal/api"
 	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/output"
 	"github.com/urfave/cli/v2"
 )
 
@@ -17,19 +19,26 @@ func commandSearch() *cli.Command {
 		Name:    "search",
 		Aliases: []string{"s"},
 		Usage:   "Search for files by filename",
-		Flags: []cli.Flag{
+		Description: `Search terms match file paths. Filters can be combined with them:
+
+   ext:pdf,docx               any of the given extensions
+   type:image,video           extensions in the given categories (image, video, audio, document, archive)
+   host:nas                   files on any of the given hosts
+   size:>10MB                 size comparison (>, >=, <, <=) or range (1MB..1GB)
+   modified:2023-01..2023-06  modification date (YYYY, YYYY-MM or YYYY-MM-DD)
+   tag:tax                    files tagged with any of the given tags
+   path:photos                path contains any of the given strings
+   hash:00ab12                hash starts with the given prefix
+
+   Prefix a term or filter with - to exclude matches, e.g. -path:tmp.`,
+		ArgsUsage: "QUERY",
+		Flags: append([]cli.Flag{
 			&cli.IntFlag{
 				Name:     "limit",
 				Usage:    "Number of results to return",
 				Value:    100,
 				Required: false,
 			},
-			&cli.StringFlag{
-				Name:     "db",
-				Usage:    "Database path",
-				Value:    "",
-				Required: false,
-			},
 			&cli.StringFlag{
 				Name:     "host",
 				Usage:    "Filter by host",
@@ -48,161 +57,124 @@ func commandSearch() *cli.Command {
 				Required: false,
 			},
 			&cli.StringFlag{
-				Name:     "server-url",
-				Usage:    "HashUp API server URL",
-				Required: false,
+				Name:  "sort",
+				Usage: "Sort by " + strings.Join(hsdb.SortKeys, ", "),
+				Value: hsdb.SortRelevance,
 			},
-		},
+			&cli.StringFlag{
+				Name:  "order",
+				Usage: "Sort order, asc or desc (default depends on --sort)",
+			},
+			&cli.StringFlag{
+				Name:  "cursor",
+				Usage: "Continue from a cursor printed by a previous search",
+			},
+			&cli.BoolFlag{
+				Name:  "count",
+				Usage: "Report the total number of matches",
+			},
+			&cli.StringSliceFlag{
+				Name:  "peer",
+				Usage: "Also search the API server at [NAME=]URL, can be repeated",
+			},
+			&cli.DurationFlag{
+				Name:  "peer-timeout",
+				Usage: "Time to wait for peers before leaving their results out",
+				Value: 5 * time.Second,
+			},
+		}, append(backendFlags(), outputFlags()...)...),
 		Action: func(c *cli.Context) error {
-			hostFilter := c.String("host")
-			extFilter := c.String("extension")
-			serverURL := c.String("server-url")
-			limit := c.Int("limit")
-
-			filename := c.Args().Get(0)
 			if c.NArg() == 0 {
-				return fmt.Errorf("filename argument is required")
+				return fmt.Errorf("query argument is required")
 			}
 
-			if serverURL != "" {
-				return searchServer(serverURL, filename, extFilter, hostFilter, limit)
+			query := strings.Join(c.Args().Slice(), " ")
+			if tag := c.String("tag"); tag != "" {
+				query += " tag:" + hsdb.QuoteValue(tag)
 			}
-
-			if c.String("tag") != "" {
-				return searchByTag(c)
+			opts := hsdb.SearchOptions{
+				Extensions: []string{c.String("extension")},
+				Hosts:      []string{c.String("host")},
+				Sort:       c.String("sort"),
+				Order:      c.String("order"),
+				Cursor:     c.String("cursor"),
+				Count:      c.Bool("count"),
+				Limit:      c.Int("limit"),
 			}
 
-			if hostFilter != "" {
-				return searchByHost(c, filename)
+			p, err := newPrinter(c)
+			if err != nil {
+				return err
 			}
 
-			if extFilter != "" {
-				return searchByExt(c, filename)
+			b, err := openBackend(c)
+			if err != nil {
+				return err
 			}
-			return searchFiles(c, filename)
-		},
-	}
-}
-
-func searchByTag(c *cli.Context) error {
-	tag := c.String("tag")
-	limit := c.String("limit")
-	db, err := dbConn(c.String("db"))
-	defer db.Close()
-
-	query := `
-		SELECT file_path, file_size, modified_date, host, extension, file_hash
-		FROM file_info
-		JOIN file_tags ON file_info.id = file_tags.file_id
-		WHERE file_tags.tags LIKE ?
-		LIMIT ?
-	`
-
-	rows, err := db.Query(query, "%"+tag+"%", limit)
-	if err != nil {
-		return fmt.Errorf("failed to query database: %v", err)
-	}
-	defer rows.Close()
-
-	return printRows(rows)
-}
-
-func searchByExt(c *cli.Context, filename string) error {
-	ext := c.String("ext")
-	limit := c.Int("limit")
-	db, err := dbConn(c.String("db"))
-	if err != nil {
-		return fmt.Errorf("failed to get database connection: %v", err)
-	}
-	defer db.Close()
+			defer b.Close()
 
-	r, err := hsdb.Search(db, filename, []string{ext}, []string{}, limit)
-	for _, result := range r {
-		printFileResult(result)
-	}
+			if peers := c.StringSlice("peer"); len(peers) > 0 {
+				return federatedSearch(c, p, b, query, opts, peers)
+			}
 
-	return nil
-}
+			page, err := b.Search(query, opts)
+			var qerr *hsdb.QueryError
+			if errors.As(err, &qerr) {
+				fmt.Fprintln(os.Stderr, qerr.Caret())
+			}
+			if err != nil {
+				return err
+			}
 
-func searchByHost(c *cli.Context, filename string) error {
-	host := c.String("host")
-	limit := c.Int("limit")
-	db, err := dbConn(c.String("db"))
-	if err != nil {
-		return fmt.Errorf("failed to get database connection: %v", err)
-	}
-	defer db.Close()
+			if err := printFiles(p, page.Results); err != nil {
+				return err
+			}
 
-	r, err := hsdb.Search(db, filename, []string{}, []string{host}, limit)
-	for _, result := range r {
-		printFileResult(result)
+			// Pagination details go to stderr so they never mix with
+			// machine readable output.
+			if page.Total >= 0 {
+				fmt.Fprintf(os.Stderr, "%d of %d matches\n", len(page.Results), page.Total)
+			}
+			if page.NextCursor != "" {
+				fmt.Fprintf(os.Stderr, "More results with --cursor %s\n", page.NextCursor)
+			}
+			return nil
+		},
 	}
-	return nil
 }
 
-func searchFiles(c *cli.Context, filename string) error {
-	db, err := dbConn(c.String("db"))
-	if err != nil {
-		return fmt.Errorf("failed to get database connection: %v", err)
-	}
-	defer db.Close()
+// federatedSearch searches the index of b and the given peers, reporting
+// the peers left out on stderr.
+func federatedSearch(c *cli.Context, p *output.Printer, b backend, query string, opts hsdb.SearchOptions, peers []string) error {
+	timeout := c.Duration("peer-timeout")
 
-	r, err := hsdb.Search(db, filename, []string{}, []string{}, 100)
-	for _, result := range r {
-		printFileResult(result)
-	}
+	sources := []api.Source{{Name: b.Name(), Search: b.Search}}
 
-	return nil
-}
-
-func printRows(rows *sql.Rows) error {
-	for rows.Next() {
-		var filePath string
-		var fileSize int64
-		var modifiedDate string
-		var host, extension, file_hash string
-
-		err := rows.Scan(&filePath, &fileSize, &modifiedDate, &host, &extension, &file_hash)
+	for _, peer := range peers {
+		name, serverURL, ok := strings.Cut(peer, "=")
+		if !ok || strings.Contains(name, "/") {
+			name, serverURL = api.PeerName(peer), peer
+		}
+		client, err := apiClient(serverURL, api.WithTimeout(timeout))
 		if err != nil {
-			return fmt.Errorf("failed to scan row: %v", err)
+			return err
 		}
-
-		fmt.Printf("File Path: %s\n", filePath)
-		fmt.Printf("File Size: %d bytes\n", fileSize)
-		fmt.Printf("Modified Date: %s\n", modifiedDate)
-		fmt.Printf("Host: %s\n", host)
-		fmt.Printf("Extension: %s\n", extension)
-		fmt.Printf("Hash: %s\n", file_hash)
-		fmt.Println(strings.Repeat("-", 40))
+		sources = append(sources, api.ClientSource(name, client))
 	}
 
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating over rows: %v", err)
-	}
-
-	return nil
-}
-
-func searchServer(serverURL string, filename string, extFilter string, hostFilter string, limit int) error {
-	client := api.NewClient(serverURL)
-	r, err := client.Search(filename, []string{extFilter}, []string{hostFilter}, limit)
+	page, err := api.FederatedSearch(sources, query, opts, timeout)
 	if err != nil {
-		return fmt.Errorf("failed to search server: %v", err)
+		return fmt.Errorf("federated search failed: %v", err)
 	}
 
-	for _, result := range r {
-		printFileResult(result)
+	if err := printSourceFiles(p, page.Results); err != nil {
+		return err
+	}
+	for _, e := range page.Errors {
+		fmt.Fprintf(os.Stderr, "Warning: %s left out: %s\n", e.Source, e.Error)
+	}
+	if page.Total >= 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d matches\n", len(page.Results), page.Total)
 	}
-
 	return nil
 }
-
-func printFileResult(result *types.FileResult) {
-	fmt.Printf("File Path: %s\n", result.FilePath)
-	fmt.Printf("File Size: %d bytes\n", result.FileSize)
-	fmt.Printf("Modified Date: %s\n", result.ModifiedDate)
-	fmt.Printf("Host: %s\n", result.Host)
-	fmt.Printf("Extension: %s\n", result.Extension)
-	fmt.Printf("Hash: %s\n", result.FileHash)
-	fmt.Println(strings.Repeat("-", 40))
-}
@@ -1,13 +1,14 @@
 package main
 
 import (
-	"database/sql"
 	"fmt"
 	"os"
-	"sort"
-	"strings"
+	"path/filepath"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/rubiojr/hashup/cmd/hs/types"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/util"
 	"github.com/urfave/cli/v2"
 )
 
@@ -15,20 +16,14 @@ func commandTag() *cli.Command {
 	return &cli.Command{
 		Name:  "tag",
 		Usage: "Tag a file in the database",
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.StringSliceFlag{
 				Name:     "tags",
 				Aliases:  []string{"t"},
 				Usage:    "Tags to add (comma-separated)",
 				Required: true,
 			},
-			&cli.StringFlag{
-				Name:    "db",
-				Aliases: []string{"d"},
-				Usage:   "Path to the database file",
-				Value:   "hs.db",
-			},
-		},
+		}, backendFlags()...),
 		Action: func(c *cli.Context) error {
 			if c.NArg() == 0 {
 				return fmt.Errorf("file path argument is required")
@@ -37,91 +32,56 @@ func commandTag() *cli.Command {
 			filePath := c.Args().Get(0)
 			tags := c.StringSlice("tags")
 
-			// Connect to SQLite database
-			db, err := dbConn(c.String("db"))
+			b, err := openBackend(c)
 			if err != nil {
-				return fmt.Errorf("failed to open database: %v", err)
+				return err
 			}
-			defer db.Close()
+			defer b.Close()
 
-			return tagFile(db, filePath, tags)
+			return tagFile(b, filePath, tags)
 		},
 	}
 }
 
-func tagFile(db *sql.DB, filePath string, tags []string) error {
-	// order tags
-	sort.Strings(tags)
-
+// tagFile tags the indexed copy of the local file at filePath on this
+// host, preferring the one at the same path when several are indexed.
+func tagFile(b backend, filePath string, tags []string) error {
 	hostname, err := os.Hostname()
 	if err != nil {
 		return err
 	}
 
-	// Calculate file hash
-	fileHash, err := calculateXXHash(filePath)
+	absPath, err := filepath.Abs(filePath)
 	if err != nil {
 		return err
 	}
 
-	// Begin transaction
-	tx, err := db.Begin()
+	fileHash, err := util.ComputeFileHash(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
+		return err
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Find the file_id using the hash
-	var fileID int
-	err = tx.QueryRow(`
-		SELECT file_info.id
-		FROM file_info
-		WHERE file_hash = ? AND host = ? LIMIT 1
-	`, fileHash, hostname).Scan(&fileID)
 
-	if err == sql.ErrNoRows {
-		return fmt.Errorf("file %s not found in database", fileHash)
-	} else if err != nil {
+	query := fmt.Sprintf("hash:%s host:%s", fileHash, hsdb.QuoteValue(hostname))
+	page, err := b.Search(query, hsdb.SearchOptions{Sort: hsdb.SortPath, Limit: 1000})
+	if err != nil {
 		return fmt.Errorf("failed to query database: %v", err)
 	}
 
-	// Check if tags already exist for this file
-	var tagID int
-	err = tx.QueryRow(`
-		SELECT id
-		FROM file_tags
-		WHERE file_id = ? LIMIT 1
-	`, fileID).Scan(&tagID)
-
-	if err == sql.ErrNoRows {
-		// No tags exist yet, insert new tags
-		_, err = tx.Exec(`
-			INSERT INTO file_tags (file_id, tags) VALUES (?, ?)
-		`, fileID, strings.Join(tags, ","))
-
-		if err != nil {
-			return fmt.Errorf("failed to insert new tags: %v", err)
+	var file *types.FileResult
+	for _, f := range page.Results {
+		if f.FileHash != fileHash || f.Host != hostname {
+			continue
 		}
-	} else if err != nil {
-		return fmt.Errorf("failed to query existing tags: %v", err)
-	} else {
-		// Update existing tags
-		_, err = tx.Exec(`
-			UPDATE file_tags SET tags = ? WHERE id = ?
-		`, strings.Join(tags, ","), tagID)
-
-		if err != nil {
-			return fmt.Errorf("failed to update tags: %v", err)
+		if file == nil || f.FilePath == absPath {
+			file = f
 		}
 	}
+	if file == nil {
+		return fmt.Errorf("file %s not found in database", fileHash)
+	}
 
-	// Commit the transaction
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
+	if err := b.SetTags(file, tags); err != nil {
+		return err
 	}
 
 	fmt.Printf("Added tags %v to %s\n", tags, filePath)
@@ -1,11 +1,8 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
-	"strings"
-
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/rubiojr/hashup/internal/output"
 	"github.com/urfave/cli/v2"
 )
 
@@ -13,63 +10,30 @@ func commandTags() *cli.Command {
 	return &cli.Command{
 		Name:  "tags",
 		Usage: "List all tags",
+		Flags: append(backendFlags(), outputFlags()...),
 		Action: func(c *cli.Context) error {
-			dbPath, err := getDBPath()
+			p, err := newPrinter(c)
 			if err != nil {
 				return err
 			}
 
-			// Connect to SQLite database
-			db, err := sql.Open("sqlite3", dbPath)
+			b, err := openBackend(c)
 			if err != nil {
-				return fmt.Errorf("failed to open database: %v", err)
+				return err
 			}
-			defer db.Close()
-
-			return listTags(db)
-		},
-	}
-}
-
-func listTags(db *sql.DB) error {
-	query := `
-		SELECT DISTINCT tags FROM file_tags
-	`
-
-	rows, err := db.Query(query)
-	if err != nil {
-		return fmt.Errorf("failed to query database: %v", err)
-	}
-	defer rows.Close()
+			defer b.Close()
 
-	// Use a map to store unique tags
-	uniqueTags := make(map[string]struct{})
-
-	for rows.Next() {
-		var tagString string
-		err := rows.Scan(&tagString)
-		if err != nil {
-			return fmt.Errorf("failed to scan row: %v", err)
-		}
-
-		// Split the comma-separated tags and add them to the map
-		tags := strings.Split(tagString, ",")
-		for _, tag := range tags {
-			tag = strings.TrimSpace(tag)
-			if tag != "" {
-				uniqueTags[tag] = struct{}{}
+			tags, err := b.AllTags()
+			if err != nil {
+				return err
 			}
-		}
-	}
 
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating over rows: %v", err)
-	}
-
-	// Print all unique tags
-	for tag := range uniqueTags {
-		fmt.Println(tag)
+			return output.Write(p, tags, output.Spec[string]{
+				Columns: []output.Column[string]{
+					{Header: "TAG", Value: func(t string) any { return t }},
+				},
+				Key: func(t string) string { return t },
+			})
+		},
 	}
-
-	return nil
 }
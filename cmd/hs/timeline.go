@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/output"
+	"github.com/urfave/cli/v2"
+)
+
+func commandTimeline() *cli.Command {
+	return &cli.Command{
+		Name:  "timeline",
+		Usage: "Show per day file changes and bytes by host",
+		Flags: recentFlags(),
+		Action: func(c *cli.Context) error {
+			p, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+
+			opts, err := recentOptions(c)
+			if err != nil {
+				return err
+			}
+
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			days, err := b.Timeline(opts)
+			if err != nil {
+				return fmt.Errorf("failed to query timeline: %v", err)
+			}
+
+			return output.Write(p, days, output.Spec[*hsdb.TimelineDay]{
+				Columns: []output.Column[*hsdb.TimelineDay]{
+					{Header: "DAY", Value: func(d *hsdb.TimelineDay) any { return d.Day }},
+					{Header: "HOST", Value: func(d *hsdb.TimelineDay) any { return d.Host }},
+					{Header: "NEW", Value: func(d *hsdb.TimelineDay) any { return d.New }},
+					{Header: "MODIFIED", Value: func(d *hsdb.TimelineDay) any { return d.Modified }},
+					{Header: "REMOVED", Value: func(d *hsdb.TimelineDay) any { return d.Removed }},
+					{Header: "FILES", Value: func(d *hsdb.TimelineDay) any { return d.Files }},
+					{Header: "SIZE", Value: func(d *hsdb.TimelineDay) any { return output.Bytes(d.Size) }},
+				},
+				Key: func(d *hsdb.TimelineDay) string { return d.Day + " " + d.Host },
+			})
+		},
+	}
+}
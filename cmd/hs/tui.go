@@ -0,0 +1,24 @@
+package main
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/rubiojr/hashup/internal/tui"
+	"github.com/urfave/cli/v2"
+)
+
+func commandTUI() *cli.Command {
+	return &cli.Command{
+		Name:  "tui",
+		Usage: "Search the index interactively",
+		Flags: backendFlags(),
+		Action: func(c *cli.Context) error {
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			return tui.Run(b)
+		},
+	}
+}
@@ -9,4 +9,6 @@ type FileResult struct {
 	Host         string    `json:"host"`
 	Extension    string    `json:"extension"`
 	FileHash     string    `json:"file_hash"`
+	// Source is the index a federated search result comes from
+	Source string `json:"source,omitempty"`
 }
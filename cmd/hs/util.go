@@ -1,116 +1,39 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
-	"io"
 	"os"
-	"path/filepath"
-	"strings"
 
-	"github.com/cespare/xxhash/v2"
-	"github.com/rubiojr/hashup/cmd/hs/types"
+	"github.com/rubiojr/hashup/internal/api"
+	"github.com/urfave/cli/v2"
 )
 
-func dbConn(path string) (*sql.DB, error) {
-	dbPath := path
-	var err error
-	if path == "" {
-		dbPath, err = getDBPath()
+// apiClient returns a client for the API server at serverURL, using the
+// token and CA certificate in HASHUP_API_TOKEN and HASHUP_API_CA_CERT if
+// set.
+func apiClient(serverURL string, opts ...api.ClientOption) (*api.Client, error) {
+	if token := os.Getenv("HASHUP_API_TOKEN"); token != "" {
+		opts = append(opts, api.WithToken(token))
+	}
+	if caCert := os.Getenv("HASHUP_API_CA_CERT"); caCert != "" {
+		pool, err := api.LoadCACert(caCert)
 		if err != nil {
 			return nil, err
 		}
+		opts = append(opts, api.WithRootCAs(pool))
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %v", err)
-	}
-
-	return db, nil
-}
-
-func getDBPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %v", err)
-	}
-	dbDir := filepath.Join(homeDir, ".local", "share", "hashup")
-	return filepath.Join(dbDir, "hashup.db"), nil
-}
-
-func calculateXXHash(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open file: %v", err)
-	}
-	defer file.Close()
-
-	hash := xxhash.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", fmt.Errorf("failed to calculate hash: %v", err)
-	}
-
-	return fmt.Sprintf("%x", hash.Sum64()), nil
+	return api.NewClient(serverURL, opts...), nil
 }
 
-func dbSearch(db *sql.DB, query string, extensions []string, limit int) ([]*types.FileResult, error) {
-	query = strings.Replace(query, " ", "%", -1)
-	sqlQuery := `
-		SELECT file_path, file_size, modified_date, host, extension, file_hash
-		FROM file_info
-		WHERE (file_path LIKE ? OR file_hash LIKE ?)
-	`
-
-	if len(extensions) > 0 {
-		placeholders := make([]string, len(extensions))
-		for i := range extensions {
-			placeholders[i] = "?"
-		}
-		sqlQuery += fmt.Sprintf(" AND extension IN (%s)", strings.Join(placeholders, ","))
-	}
-
-	sqlQuery += `
-		ORDER BY modified_date DESC
-	`
-
-	var args []any
-	args = append(args, "%"+query+"%", "%"+query+"%")
-
-	if len(extensions) > 0 {
-		for _, ext := range extensions {
-			args = append(args, strings.TrimSpace(ext))
-		}
+// getDBPath returns the database path set with --db, or store.db_path.
+func getDBPath(c *cli.Context) (string, error) {
+	if dbPath := c.String("db"); dbPath != "" {
+		return dbPath, nil
 	}
 
-	sqlQuery += fmt.Sprintf("LIMIT %d", limit)
-
-	rows, err := db.Query(sqlQuery, args...)
+	cfg, err := loadConfig(c)
 	if err != nil {
-		return nil, fmt.Errorf("Database error: %v", err)
+		return "", err
 	}
-	defer rows.Close()
-
-	var results []*types.FileResult
-	for rows.Next() {
-		var result types.FileResult
-		err := rows.Scan(
-			&result.FilePath,
-			&result.FileSize,
-			&result.ModifiedDate,
-			&result.Host,
-			&result.Extension,
-			&result.FileHash,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("Error scanning row: %v", err)
-		}
-		results = append(results, &result)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("Error iterating over rows: %v", err)
-	}
-
-	return results, nil
+	return cfg.Store.DBPath, nil
 }
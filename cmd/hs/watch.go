@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/output"
+	"github.com/rubiojr/hashup/internal/webhook"
+	"github.com/urfave/cli/v2"
+)
+
+func commandWatch() *cli.Command {
+	dbFlag := &cli.StringFlag{
+		Name:  "db",
+		Usage: "Database path",
+	}
+
+	return &cli.Command{
+		Name:  "watch",
+		Usage: "Manage saved searches that notify webhooks of new matches",
+		Description: "The store checks every newly indexed file against the saved searches and\n" +
+			"POSTs matches to their webhook. Requests are signed with the search secret,\n" +
+			"the X-Hashup-Signature header is sha256= followed by the hex HMAC-SHA256 of\n" +
+			"the body. Failed deliveries are retried with backoff and every delivery is\n" +
+			"logged, see `hs watch deliveries`.",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "add",
+				Usage:     "Save a search",
+				ArgsUsage: "NAME QUERY",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "webhook",
+						Usage:    "URL notified of matches",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "secret",
+						Usage: "Secret used to sign requests (generated if not set)",
+					},
+					dbFlag,
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 2 {
+						return fmt.Errorf("a name and a query are required")
+					}
+					return withWatchDB(c, func(db *sql.DB) error {
+						s := &hsdb.SavedSearch{
+							Name:       c.Args().Get(0),
+							Query:      c.Args().Get(1),
+							WebhookURL: c.String("webhook"),
+							Secret:     c.String("secret"),
+						}
+						if err := hsdb.CreateSavedSearch(db, s); err != nil {
+							return err
+						}
+						fmt.Printf("Saved search %s, webhook secret: %s\n", s.Name, s.Secret)
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "rm",
+				Usage:     "Delete a saved search",
+				ArgsUsage: "NAME",
+				Flags:     []cli.Flag{dbFlag},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return fmt.Errorf("a saved search name is required")
+					}
+					return withWatchDB(c, func(db *sql.DB) error {
+						if err := hsdb.DeleteSavedSearch(db, c.Args().First()); err != nil {
+							return err
+						}
+						fmt.Printf("Deleted saved search %s\n", c.Args().First())
+						return nil
+					})
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "List saved searches",
+				Flags: append([]cli.Flag{dbFlag}, outputFlags()...),
+				Action: func(c *cli.Context) error {
+					p, err := newPrinter(c)
+					if err != nil {
+						return err
+					}
+					return withWatchDB(c, func(db *sql.DB) error {
+						searches, err := hsdb.SavedSearches(db)
+						if err != nil {
+							return err
+						}
+						return output.Write(p, searches, output.Spec[*hsdb.SavedSearch]{
+							Columns: []output.Column[*hsdb.SavedSearch]{
+								{Header: "NAME", Value: func(s *hsdb.SavedSearch) any { return s.Name }},
+								{Header: "QUERY", Value: func(s *hsdb.SavedSearch) any { return s.Query }},
+								{Header: "WEBHOOK", Value: func(s *hsdb.SavedSearch) any { return s.WebhookURL }},
+								{Header: "CREATED", Value: func(s *hsdb.SavedSearch) any { return s.Created.Format("2006-01-02 15:04") }},
+							},
+							Key: func(s *hsdb.SavedSearch) string { return s.Name },
+						})
+					})
+				},
+			},
+			{
+				Name:      "test",
+				Usage:     "Send a ping event to the webhook of a saved search",
+				ArgsUsage: "NAME",
+				Flags:     []cli.Flag{dbFlag},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return fmt.Errorf("a saved search name is required")
+					}
+					return withWatchDB(c, func(db *sql.DB) error {
+						s, err := hsdb.GetSavedSearch(db, c.Args().First())
+						if err != nil {
+							return err
+						}
+						dispatcher := webhook.NewDispatcher(db, webhook.WithAttempts(1))
+						defer dispatcher.Close()
+						d, err := dispatcher.Ping(context.Background(), s)
+						if err != nil {
+							return err
+						}
+						if d.Status != hsdb.DeliveryDelivered {
+							return fmt.Errorf("delivery to %s failed: %s", s.WebhookURL, d.Error)
+						}
+						fmt.Printf("Delivered ping to %s (status %d)\n", s.WebhookURL, d.ResponseCode)
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "deliveries",
+				Usage:     "Show the webhook delivery log",
+				ArgsUsage: "[NAME]",
+				Flags: append([]cli.Flag{
+					dbFlag,
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "Maximum number of deliveries to show",
+						Value: 50,
+					},
+				}, outputFlags()...),
+				Action: func(c *cli.Context) error {
+					p, err := newPrinter(c)
+					if err != nil {
+						return err
+					}
+					return withWatchDB(c, func(db *sql.DB) error {
+						deliveries, err := hsdb.Deliveries(db, c.Args().First(), c.Int("limit"))
+						if err != nil {
+							return err
+						}
+						return output.Write(p, deliveries, output.Spec[*hsdb.Delivery]{
+							Columns: []output.Column[*hsdb.Delivery]{
+								{Header: "UPDATED", Value: func(d *hsdb.Delivery) any { return d.Updated.Format("2006-01-02 15:04:05") }},
+								{Header: "SEARCH", Value: func(d *hsdb.Delivery) any { return d.SearchName }},
+								{Header: "EVENT", Value: func(d *hsdb.Delivery) any { return d.Event }},
+								{Header: "STATUS", Value: func(d *hsdb.Delivery) any { return d.Status }},
+								{Header: "ATTEMPTS", Value: func(d *hsdb.Delivery) any { return d.Attempts }},
+								{Header: "CODE", Value: func(d *hsdb.Delivery) any { return d.ResponseCode }},
+								{Header: "HOST", Value: func(d *hsdb.Delivery) any { return d.Host }},
+								{Header: "PATH", Value: func(d *hsdb.Delivery) any { return d.FilePath }},
+							},
+							Key: func(d *hsdb.Delivery) string { return d.FilePath },
+						})
+					})
+				},
+			},
+		},
+	}
+}
+
+// withWatchDB runs fn with the database, creating the saved searches
+// tables if needed.
+func withWatchDB(c *cli.Context, fn func(*sql.DB) error) error {
+	dbPath, err := getDBPath(c)
+	if err != nil {
+		return err
+	}
+
+	db, err := hsdb.OpenDatabase(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	return fn(db)
+}
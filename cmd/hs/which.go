@@ -0,0 +1,270 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	_ "github.com/mattn/go-sqlite3"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/output"
+	"github.com/rubiojr/hashup/internal/util"
+	"github.com/urfave/cli/v2"
+)
+
+func commandWhich() *cli.Command {
+	return &cli.Command{
+		Name:  "which",
+		Usage: "Find the indexed copies of local files",
+		Description: "Hashes the given files and lists every host and path indexed with the same content.\n" +
+			"Given directories, reports which of the files below them have copies elsewhere.",
+		ArgsUsage: "FILE... | DIR...",
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{
+				Name:  "missing",
+				Usage: "Directories: only list files without copies elsewhere",
+			},
+			&cli.BoolFlag{
+				Name:  "hidden",
+				Usage: "Directories: include hidden files and directories",
+			},
+		}, append(backendFlags(), outputFlags()...)...),
+		Action: func(c *cli.Context) error {
+			p, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+
+			if c.NArg() == 0 {
+				return fmt.Errorf("a file or directory argument is required")
+			}
+
+			var files, dirs []string
+			for _, arg := range c.Args().Slice() {
+				info, err := os.Stat(arg)
+				if err != nil {
+					return err
+				}
+				if info.IsDir() {
+					dirs = append(dirs, arg)
+				} else {
+					files = append(files, arg)
+				}
+			}
+			if len(files) > 0 && len(dirs) > 0 {
+				return fmt.Errorf("files and directories can't be mixed")
+			}
+
+			hostname, err := os.Hostname()
+			if err != nil {
+				return err
+			}
+
+			b, err := openBackend(c)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			w := &which{b: b, hostname: hostname, copies: map[string][]*hsdb.FileCopy{}}
+			if len(dirs) > 0 {
+				return w.dirs(p, dirs, c.Bool("hidden"), c.Bool("missing"))
+			}
+			return w.files(p, files)
+		},
+	}
+}
+
+// which finds the indexed copies of local files.
+type which struct {
+	b        backend
+	hostname string
+	// copies caches the copies of each hash looked up
+	copies map[string][]*hsdb.FileCopy
+}
+
+// lookup hashes the local file at path and returns its indexed copies,
+// leaving out the file itself.
+func (w *which) lookup(path string) (string, []*hsdb.FileCopy, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", nil, err
+	}
+	hash, err := util.ComputeFileHash(absPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to hash %s: %v", path, err)
+	}
+
+	copies, ok := w.copies[hash]
+	if !ok {
+		d, err := w.b.File(hash)
+		if err != nil && !errors.Is(err, hsdb.ErrFileNotFound) {
+			return "", nil, fmt.Errorf("failed to look up %s: %v", path, err)
+		}
+		if d != nil {
+			copies = d.Copies
+		}
+		w.copies[hash] = copies
+	}
+
+	var others []*hsdb.FileCopy
+	for _, c := range copies {
+		if c.Host != w.hostname || c.FilePath != absPath {
+			others = append(others, c)
+		}
+	}
+	return hash, others, nil
+}
+
+// copyRow is an indexed copy of a local file.
+type copyRow struct {
+	File     string    `json:"file"`
+	Hash     string    `json:"hash"`
+	Host     string    `json:"host"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+func (w *which) files(p *output.Printer, files []string) error {
+	var rows []*copyRow
+	var missing []string
+	for _, f := range files {
+		hash, copies, err := w.lookup(f)
+		if err != nil {
+			return err
+		}
+		if len(copies) == 0 {
+			missing = append(missing, f)
+		}
+		for _, c := range copies {
+			rows = append(rows, &copyRow{
+				File: f, Hash: hash, Host: c.Host, Path: c.FilePath,
+				Size: c.FileSize, Modified: c.ModifiedDate,
+			})
+		}
+	}
+
+	return output.Write(p, rows, output.Spec[*copyRow]{
+		Columns: []output.Column[*copyRow]{
+			{Header: "FILE", Value: func(r *copyRow) any { return r.File }},
+			{Header: "HOST", Value: func(r *copyRow) any { return r.Host }},
+			{Header: "PATH", Value: func(r *copyRow) any { return r.Path }},
+			{Header: "MODIFIED", Value: func(r *copyRow) any { return r.Modified }},
+		},
+		Key: func(r *copyRow) string { return r.Path },
+		Text: func(out io.Writer) error {
+			printCopies(out, files, rows, missing)
+			return nil
+		},
+	})
+}
+
+func printCopies(w io.Writer, files []string, rows []*copyRow, missing []string) {
+	for _, f := range files {
+		if slices.Contains(missing, f) {
+			fmt.Fprintf(w, "%s: no copies elsewhere\n", f)
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", f)
+		for _, r := range rows {
+			if r.File == f {
+				fmt.Fprintf(w, "  %-12s %s\n", r.Host, r.Path)
+			}
+		}
+	}
+}
+
+// fileRow reports whether a file below a directory has copies elsewhere.
+type fileRow struct {
+	File   string   `json:"file"`
+	Hash   string   `json:"hash"`
+	Size   int64    `json:"size"`
+	Copies int      `json:"copies"`
+	Hosts  []string `json:"hosts"`
+}
+
+func (w *which) dirs(p *output.Printer, dirs []string, hidden, onlyMissing bool) error {
+	var rows []*fileRow
+	var total, missing int
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !hidden && path != dir && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			hash, copies, err := w.lookup(path)
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			total++
+			if len(copies) == 0 {
+				missing++
+			} else if onlyMissing {
+				return nil
+			}
+			r := &fileRow{File: path, Hash: hash, Size: info.Size(), Copies: len(copies), Hosts: []string{}}
+			for _, c := range copies {
+				if !slices.Contains(r.Hosts, c.Host) {
+					r.Hosts = append(r.Hosts, c.Host)
+				}
+			}
+			rows = append(rows, r)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return output.Write(p, rows, output.Spec[*fileRow]{
+		Columns: []output.Column[*fileRow]{
+			{Header: "COPIES", Value: func(r *fileRow) any { return r.Copies }},
+			{Header: "HOSTS", Value: func(r *fileRow) any { return strings.Join(r.Hosts, ",") }},
+			{Header: "SIZE", Value: func(r *fileRow) any { return output.Bytes(r.Size) }},
+			{Header: "FILE", Value: func(r *fileRow) any { return r.File }},
+		},
+		Key: func(r *fileRow) string { return r.File },
+		Text: func(out io.Writer) error {
+			printFileRows(out, rows, total, missing)
+			return nil
+		},
+	})
+}
+
+func printFileRows(w io.Writer, rows []*fileRow, total, missing int) {
+	var size int64
+	for _, r := range rows {
+		hosts := "-"
+		if r.Copies > 0 {
+			hosts = strings.Join(r.Hosts, ",")
+		} else {
+			size += r.Size
+		}
+		fmt.Fprintf(w, "%3d  %-24s %s\n", r.Copies, hosts, r.File)
+	}
+
+	fmt.Fprintf(w, "\n%d of %d files have copies elsewhere, %d files (%s) only here\n",
+		total-missing, total, missing, humanize.Bytes(uint64(size)))
+}
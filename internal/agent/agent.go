@@ -0,0 +1,281 @@
+// Package agent serves indexed files to remote clients over NATS
+// request/reply.
+//
+// An agent runs on a scanner host and answers on <subject>.<host>, see
+// Subject. Requests and responses are JSON encrypted with the shared
+// encryption key, so the NATS server only relays ciphertext. Files are
+// transferred in chunks of ChunkSize bytes, one request per chunk, only
+// when they still have the requested hash, and verified again by the
+// Client before they are handed over. Contents with a chunk manifest can be rebuilt from the
+// chunks the receiving host already has, requesting only the missing byte
+// ranges, see Client.FetchChunks.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rubiojr/hashup/internal/crypto"
+	"github.com/rubiojr/hashup/internal/log"
+	"github.com/rubiojr/hashup/internal/util"
+	"github.com/rubiojr/hashup/pkg/config"
+)
+
+// ChunkSize is the size of the file chunks sent in each response, small
+// enough for the default NATS max payload once encrypted and armored.
+const ChunkSize = 256 << 10
+
+// maxVerified is how many verified files agents remember, see
+// Agent.verify.
+const maxVerified = 1024
+
+// Errors reported by agents.
+var (
+	ErrNotFound   = errors.New("file not found")
+	ErrNotAllowed = errors.New("file is not below the agent roots")
+	// ErrWrongHash is returned when the file doesn't have the requested
+	// hash, like files changed since they were indexed.
+	ErrWrongHash = errors.New("file does not have the requested hash")
+)
+
+// Request asks for the chunk of the file at Path starting at Offset, up
+// to Length bytes when set.
+type Request struct {
+	Hash   string `json:"hash"`
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length,omitempty"`
+}
+
+// Response is a chunk of a file, the last one is shorter than requested.
+type Response struct {
+	// Size is the size of the file when the chunk was read.
+	Size  int64  `json:"size"`
+	Data  []byte `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Subject returns the subject the agent of host answers on. Characters
+// NATS gives a meaning to in subjects are replaced in host names.
+func Subject(prefix, host string) string {
+	token := strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t':
+			return '_'
+		}
+		return r
+	}, host)
+	return prefix + "." + token
+}
+
+// Connect connects to the NATS server of cfg, with mutual TLS when a
+// client key is configured.
+func Connect(cfg *config.Config) (*nats.Conn, error) {
+	var opts []nats.Option
+	if cfg.Main.ClientKey != "" {
+		opts = append(opts,
+			nats.ClientCert(cfg.Main.ClientCert, cfg.Main.ClientKey),
+			nats.RootCAs(cfg.Main.CACert),
+		)
+	}
+
+	nc, err := nats.Connect(cfg.Main.NatsServerURL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %v", err)
+	}
+	return nc, nil
+}
+
+// Agent answers file requests for the files below its roots.
+type Agent struct {
+	nc      *nats.Conn
+	machine crypto.Machine
+	subject string
+	roots   []string
+
+	mu sync.Mutex
+	// verified are the files found to have the requested hash
+	verified map[verifiedFile]bool
+}
+
+// verifiedFile is a version of a file with the hash it was verified to
+// have.
+type verifiedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+// New returns an agent answering on subject with the files below roots.
+func New(nc *nats.Conn, machine crypto.Machine, subject string, roots []string) (*Agent, error) {
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("at least one root directory is required")
+	}
+
+	a := &Agent{nc: nc, machine: machine, subject: subject, verified: map[verifiedFile]bool{}}
+	for _, root := range roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, err
+		}
+		resolved, err := filepath.EvalSymlinks(abs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve root %s: %v", root, err)
+		}
+		a.roots = append(a.roots, resolved)
+	}
+	return a, nil
+}
+
+// Serve answers requests until ctx is done.
+func (a *Agent) Serve(ctx context.Context) error {
+	sub, err := a.nc.Subscribe(a.subject, a.handle)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %v", a.subject, err)
+	}
+	defer sub.Unsubscribe()
+
+	<-ctx.Done()
+	return nil
+}
+
+func (a *Agent) handle(msg *nats.Msg) {
+	req := &Request{}
+	if err := decode(a.machine, msg.Data, req); err != nil {
+		// Not encrypted with our key, nothing worth answering.
+		log.Errorf("failed to decode file request: %v\n", err)
+		return
+	}
+
+	if req.Offset == 0 {
+		log.Printf("sending %s (%s)\n", req.Path, req.Hash)
+	}
+	resp, err := a.read(req)
+	if err != nil {
+		log.Debugf("failed to read %s: %v\n", req.Path, err)
+		resp = &Response{Error: err.Error()}
+	}
+
+	data, err := encode(a.machine, resp)
+	if err != nil {
+		log.Errorf("failed to encode response: %v\n", err)
+		return
+	}
+	if err := msg.Respond(data); err != nil {
+		log.Errorf("failed to respond to file request: %v\n", err)
+	}
+}
+
+// read returns the chunk of the file requested, if the file has the
+// requested hash.
+func (a *Agent) read(req *Request) (*Response, error) {
+	path, err := a.resolve(req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, req.Path)
+	}
+	if err := a.verify(path, info, req.Hash); err != nil {
+		return nil, err
+	}
+
+	length := int64(ChunkSize)
+	if req.Length > 0 {
+		length = min(req.Length, length)
+	}
+	buf := make([]byte, length)
+	n, err := f.ReadAt(buf, req.Offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &Response{Size: info.Size(), Data: buf[:n]}, nil
+}
+
+// resolve returns the real path of the requested file, if it is below a
+// root.
+func (a *Agent) resolve(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("%w: %s", ErrNotAllowed, path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, root := range a.roots {
+		if rel, err := filepath.Rel(root, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotAllowed, path)
+}
+
+// verify returns ErrWrongHash unless the file at path, as described by
+// info, has the given hash. Files are hashed once per version rather than
+// once per chunk requested.
+func (a *Agent) verify(path string, info os.FileInfo, hash string) error {
+	key := verifiedFile{path: path, size: info.Size(), modTime: info.ModTime(), hash: hash}
+	a.mu.Lock()
+	ok := a.verified[key]
+	a.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	got, err := util.ComputeFileHash(path)
+	if err != nil {
+		return err
+	}
+	if got != hash {
+		return fmt.Errorf("%w: %s", ErrWrongHash, path)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.verified) >= maxVerified {
+		clear(a.verified)
+	}
+	a.verified[key] = true
+	return nil
+}
+
+func encode(machine crypto.Machine, v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return machine.Encrypt(data)
+}
+
+func decode(machine crypto.Machine, data []byte, v any) error {
+	plain, err := machine.Decrypt(data)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt: %v", err)
+	}
+	return json.Unmarshal(plain, v)
+}
@@ -0,0 +1,184 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/rubiojr/hashup/internal/chunker"
+	"github.com/rubiojr/hashup/internal/crypto"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConn(t *testing.T) *nats.Conn {
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true})
+	require.NoError(t, err)
+	go ns.Start()
+	require.True(t, ns.ReadyForConnections(5*time.Second))
+	t.Cleanup(ns.Shutdown)
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+func TestFetch(t *testing.T) {
+	nc := testConn(t)
+	_, key, err := crypto.GenerateAgeKeyPair()
+	require.NoError(t, err)
+	machine, err := crypto.NewAge(key)
+	require.NoError(t, err)
+
+	root := t.TempDir()
+	content := bytes.Repeat([]byte("hashup"), ChunkSize/3)
+	path := filepath.Join(root, "big.bin")
+	require.NoError(t, os.WriteFile(path, content, 0600))
+	hash, err := util.ComputeFileHash(path)
+	require.NoError(t, err)
+	empty := filepath.Join(root, "empty")
+	require.NoError(t, os.WriteFile(empty, nil, 0600))
+	emptyHash, err := util.ComputeFileHash(empty)
+	require.NoError(t, err)
+	outside := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(outside, []byte("secret"), 0600))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "link")))
+
+	a, err := New(nc, machine, Subject("HASHUP.get", "nas.local"), []string{root})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Serve(ctx)
+
+	c := NewClient(nc, machine, "HASHUP.get", WithTimeout(5*time.Second))
+	require.Eventually(t, func() bool {
+		_, err := c.Fetch(ctx, "nas.local", empty, emptyHash, &bytes.Buffer{})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var buf bytes.Buffer
+	n, err := c.Fetch(ctx, "nas.local", path, hash, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, content, buf.Bytes())
+
+	buf.Reset()
+	n, err = c.Fetch(ctx, "nas.local", empty, emptyHash, &buf)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	// Agents only send files with the requested hash
+	_, err = c.Fetch(ctx, "nas.local", path, "0000000000000000", &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrWrongHash)
+	_, err = c.Fetch(ctx, "nas.local", path, "", &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrWrongHash)
+	require.NoError(t, os.WriteFile(empty, []byte("changed"), 0600))
+	_, err = c.Fetch(ctx, "nas.local", empty, emptyHash, &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrWrongHash)
+
+	_, err = c.Fetch(ctx, "nas.local", filepath.Join(root, "missing"), hash, &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Symlinks can't escape the roots
+	_, err = c.Fetch(ctx, "nas.local", outside, hash, &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrNotAllowed)
+	_, err = c.Fetch(ctx, "nas.local", filepath.Join(root, "link"), hash, &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrNotAllowed)
+
+	_, err = c.Fetch(ctx, "laptop", path, hash, &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrNoAgent)
+
+	// Requests encrypted with another key are not answered
+	_, other, err := crypto.GenerateAgeKeyPair()
+	require.NoError(t, err)
+	otherMachine, err := crypto.NewAge(other)
+	require.NoError(t, err)
+	_, err = NewClient(nc, otherMachine, "HASHUP.get", WithTimeout(200*time.Millisecond)).
+		Fetch(ctx, "nas.local", path, hash, &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestFetchChunks(t *testing.T) {
+	nc := testConn(t)
+	_, key, err := crypto.GenerateAgeKeyPair()
+	require.NoError(t, err)
+	machine, err := crypto.NewAge(key)
+	require.NoError(t, err)
+
+	// The remote file is a local one with bytes inserted in the middle
+	local := make([]byte, 2<<20)
+	rand.New(rand.NewSource(1)).Read(local)
+	content := append(append(append([]byte{}, local[:1<<20]...), "inserted"...), local[1<<20:]...)
+
+	root := t.TempDir()
+	path := filepath.Join(root, "disk.img")
+	require.NoError(t, os.WriteFile(path, content, 0600))
+	hash, err := util.ComputeFileHash(path)
+	require.NoError(t, err)
+	localPath := filepath.Join(t.TempDir(), "disk.img")
+	require.NoError(t, os.WriteFile(localPath, local, 0600))
+
+	m, err := chunker.Manifest(bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+	lm, err := chunker.Manifest(bytes.NewReader(local), int64(len(local)))
+	require.NoError(t, err)
+	offsets := map[uint64]int64{}
+	var offset int64
+	for i, h := range lm.Hashes {
+		offsets[h] = offset
+		offset += int64(lm.Sizes[i])
+	}
+	var sources []*hsdb.ChunkSource
+	for i, h := range m.Hashes {
+		// Stale sources are skipped
+		sources = append(sources, &hsdb.ChunkSource{Seq: i, Path: filepath.Join(root, "missing")})
+		if off, ok := offsets[h]; ok {
+			sources = append(sources, &hsdb.ChunkSource{Seq: i, Path: localPath, Offset: off})
+		}
+	}
+
+	a, err := New(nc, machine, Subject("HASHUP.get", "nas"), []string{root})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Serve(ctx)
+
+	c := NewClient(nc, machine, "HASHUP.get", WithTimeout(5*time.Second))
+	var buf bytes.Buffer
+	require.Eventually(t, func() bool {
+		buf.Reset()
+		_, _, err = c.FetchChunks(ctx, "nas", path, hash, m, nil, &buf)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, content, buf.Bytes())
+
+	buf.Reset()
+	n, reused, err := c.FetchChunks(ctx, "nas", path, hash, m, sources, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, content, buf.Bytes())
+	assert.Greater(t, reused, int64(len(local))/2)
+	assert.Less(t, reused, n)
+
+	// The local file changed since it was indexed
+	require.NoError(t, os.WriteFile(localPath, bytes.Repeat([]byte{0}, len(local)), 0600))
+	buf.Reset()
+	_, reused, err = c.FetchChunks(ctx, "nas", path, hash, m, sources, &buf)
+	require.NoError(t, err)
+	assert.Zero(t, reused)
+	assert.Equal(t, content, buf.Bytes())
+}
+
+func TestSubject(t *testing.T) {
+	assert.Equal(t, "HASHUP.get.nas_local", Subject("HASHUP.get", "nas.local"))
+	assert.Equal(t, "HASHUP.get.a_b_", Subject("HASHUP.get", "a*b>"))
+}
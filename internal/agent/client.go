@@ -0,0 +1,257 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/nats-io/nats.go"
+	"github.com/rubiojr/hashup/internal/crypto"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/types"
+)
+
+// Errors returned by Client.Fetch.
+var (
+	// ErrNoAgent is returned when no agent answers for the host.
+	ErrNoAgent = errors.New("no agent running on host")
+	// ErrHashMismatch is returned when the received content doesn't hash
+	// to the requested hash, like files changed since they were indexed.
+	ErrHashMismatch = errors.New("received content does not match the hash")
+	// ErrFileChanged is returned when the file changes size during the
+	// transfer.
+	ErrFileChanged = errors.New("file changed during the transfer")
+)
+
+// DefaultTimeout is how long clients wait for each chunk by default.
+const DefaultTimeout = 30 * time.Second
+
+type ClientOption func(*Client)
+
+// WithTimeout sets how long to wait for each chunk, DefaultTimeout by
+// default.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// Client fetches files from agents.
+type Client struct {
+	nc      *nats.Conn
+	machine crypto.Machine
+	prefix  string
+	timeout time.Duration
+}
+
+// NewClient returns a client requesting files on the subjects with the
+// given prefix, see Subject.
+func NewClient(nc *nats.Conn, machine crypto.Machine, prefix string, opts ...ClientOption) *Client {
+	c := &Client{nc: nc, machine: machine, prefix: prefix, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Fetch writes the file at path on host to w, returning its size. The
+// content is verified against hash once received, w must be discarded
+// when an error is returned.
+func (c *Client) Fetch(ctx context.Context, host, path, hash string, w io.Writer) (int64, error) {
+	subject := Subject(c.prefix, host)
+	hasher := xxhash.New()
+	out := io.MultiWriter(w, hasher)
+
+	var offset int64
+	size := int64(-1)
+	for {
+		resp, err := c.request(ctx, subject, &Request{Hash: hash, Path: path, Offset: offset})
+		if err != nil {
+			return offset, err
+		}
+		if size >= 0 && resp.Size != size {
+			return offset, ErrFileChanged
+		}
+		size = resp.Size
+
+		if _, err := out.Write(resp.Data); err != nil {
+			return offset, err
+		}
+		offset += int64(len(resp.Data))
+		if offset >= size || len(resp.Data) == 0 {
+			break
+		}
+	}
+
+	if offset != size {
+		return offset, ErrFileChanged
+	}
+	if got := fmt.Sprintf("%016x", hasher.Sum64()); got != hash {
+		return offset, fmt.Errorf("%w: expected %s, got %s", ErrHashMismatch, hash, got)
+	}
+	return offset, nil
+}
+
+// FetchChunks is Fetch for contents with a chunk manifest, reading the
+// chunks found in local files instead of transferring them. Local chunks
+// are verified against the manifest and the missing ones requested from
+// the agent. It returns the size of the file and how much of it was read
+// locally.
+func (c *Client) FetchChunks(ctx context.Context, host, path, hash string, m *types.ChunkManifest, sources []*hsdb.ChunkSource, w io.Writer) (int64, int64, error) {
+	t := &transfer{
+		c:       c,
+		ctx:     ctx,
+		subject: Subject(c.prefix, host),
+		req:     Request{Hash: hash, Path: path},
+		hasher:  xxhash.New(),
+		files:   map[string]*os.File{},
+	}
+	defer t.close()
+	t.out = io.MultiWriter(w, t.hasher)
+	for _, s := range m.Sizes {
+		t.size += int64(s)
+	}
+
+	bySeq := map[int][]*hsdb.ChunkSource{}
+	for _, s := range sources {
+		bySeq[s.Seq] = append(bySeq[s.Seq], s)
+	}
+
+	var offset, reused int64
+	for i, size := range m.Sizes {
+		if data := t.local(bySeq[i], size, m.Hashes[i]); data != nil {
+			if err := t.flush(); err != nil {
+				return offset, reused, err
+			}
+			if _, err := t.out.Write(data); err != nil {
+				return offset, reused, err
+			}
+			reused += int64(size)
+		} else {
+			// Missing chunks are requested in contiguous ranges
+			if t.pending == 0 {
+				t.start = offset
+			}
+			t.pending += int64(size)
+		}
+		offset += int64(size)
+	}
+	if err := t.flush(); err != nil {
+		return offset, reused, err
+	}
+
+	if got := fmt.Sprintf("%016x", t.hasher.Sum64()); got != hash {
+		return offset, reused, fmt.Errorf("%w: expected %s, got %s", ErrHashMismatch, hash, got)
+	}
+	return offset, reused, nil
+}
+
+// transfer is the state of a FetchChunks call.
+type transfer struct {
+	c       *Client
+	ctx     context.Context
+	subject string
+	req     Request
+	size    int64
+	out     io.Writer
+	hasher  *xxhash.Digest
+	// files are the local files opened to read chunks from, nil when they
+	// can't be opened.
+	files map[string]*os.File
+	// start and pending are the range of missing chunks not requested yet.
+	start   int64
+	pending int64
+}
+
+// local returns the chunk read from the first source holding it, nil if
+// none does.
+func (t *transfer) local(sources []*hsdb.ChunkSource, size uint32, hash uint64) []byte {
+	for _, s := range sources {
+		f, ok := t.files[s.Path]
+		if !ok {
+			f, _ = os.Open(s.Path)
+			t.files[s.Path] = f
+		}
+		if f == nil {
+			continue
+		}
+
+		data := make([]byte, size)
+		if _, err := f.ReadAt(data, s.Offset); err != nil {
+			continue
+		}
+		if xxhash.Sum64(data) == hash {
+			return data
+		}
+	}
+	return nil
+}
+
+// flush requests the pending range from the agent.
+func (t *transfer) flush() error {
+	for t.pending > 0 {
+		req := t.req
+		req.Offset = t.start
+		req.Length = t.pending
+		resp, err := t.c.request(t.ctx, t.subject, &req)
+		if err != nil {
+			return err
+		}
+		if resp.Size != t.size || len(resp.Data) == 0 {
+			return ErrFileChanged
+		}
+
+		// Agents predating ranged requests send whole chunks.
+		data := resp.Data[:min(int64(len(resp.Data)), t.pending)]
+		if _, err := t.out.Write(data); err != nil {
+			return err
+		}
+		t.start += int64(len(data))
+		t.pending -= int64(len(data))
+	}
+	return nil
+}
+
+func (t *transfer) close() {
+	for _, f := range t.files {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
+
+func (c *Client) request(ctx context.Context, subject string, req *Request) (*Response, error) {
+	data, err := encode(c.machine, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	msg, err := c.nc.RequestWithContext(ctx, subject, data)
+	if errors.Is(err, nats.ErrNoResponders) {
+		return nil, ErrNoAgent
+	}
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+
+	resp := &Response{}
+	if err := decode(c.machine, msg.Data, resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	if resp.Error != "" {
+		for _, e := range []error{ErrNotFound, ErrNotAllowed, ErrWrongHash} {
+			if strings.HasPrefix(resp.Error, e.Error()) {
+				return nil, fmt.Errorf("%w%s", e, strings.TrimPrefix(resp.Error, e.Error()))
+			}
+		}
+		return nil, errors.New(resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// QuietRequest is the body of PUT /alerts/quiet/{host}.
+type QuietRequest struct {
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Alerts returns the anomaly alerts raised by the store, newest first.
+func (c *Client) Alerts(opts hsdb.AnomalyOptions) ([]*hsdb.AnomalyAlert, error) {
+	params := url.Values{}
+	params.Set("since", opts.Since.Format(time.RFC3339))
+	params.Set("host", strings.Join(opts.Hosts, ","))
+	params.Set("limit", strconv.Itoa(opts.Limit))
+
+	var alerts []*hsdb.AnomalyAlert
+	if err := c.get("/alerts", params, &alerts); err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+// QuietPeriods returns the hosts whose anomaly alerts are silenced.
+func (c *Client) QuietPeriods() ([]*hsdb.QuietPeriod, error) {
+	var periods []*hsdb.QuietPeriod
+	if err := c.get("/alerts/quiet", url.Values{}, &periods); err != nil {
+		return nil, err
+	}
+
+	return periods, nil
+}
+
+// SetQuiet silences the anomaly alerts of host until the given time.
+func (c *Client) SetQuiet(host string, until time.Time, reason string) (*hsdb.QuietPeriod, error) {
+	q := &hsdb.QuietPeriod{}
+	body := &QuietRequest{Until: until, Reason: reason}
+	if _, err := c.do(http.MethodPut, "/alerts/quiet/"+url.PathEscape(host), url.Values{}, body, q); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// DeleteQuiet ends the quiet period of host, returning hsdb.ErrNotQuiet if
+// it has none.
+func (c *Client) DeleteQuiet(host string) error {
+	var status map[string]string
+	_, err := c.do(http.MethodDelete, "/alerts/quiet/"+url.PathEscape(host), url.Values{}, nil, &status)
+	var serr *StatusError
+	if errors.As(err, &serr) && serr.Code == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", hsdb.ErrNotQuiet, host)
+	}
+	return err
+}
+
+func alertsHandler(dbs *databases, limit int) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		since := r.URL.Query().Get("since")
+		if since == "" {
+			since = defaultSince
+		}
+		t, err := hsdb.ParseSince(since, time.Now())
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+		limit, err := limitParam(r, limit)
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		db := dbs.reader(r)
+
+		alerts, err := hsdb.AnomalyAlerts(db, hsdb.AnomalyOptions{
+			Hosts: listParam(r, "host"),
+			Since: t,
+			Limit: limit,
+		})
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, alerts)
+	})
+}
+
+func quietPeriodsHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db := dbs.reader(r)
+
+		periods, err := hsdb.QuietPeriods(db)
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, periods)
+	})
+}
+
+func setQuietHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req QuietRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			statusJSON(http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err), w, r)
+			return
+		}
+		if !req.Until.After(time.Now()) {
+			statusJSON(http.StatusBadRequest, fmt.Errorf("until must be in the future"), w, r)
+			return
+		}
+
+		rw, err := dbs.writer()
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		q := &hsdb.QuietPeriod{Host: chi.URLParam(r, "host"), Until: req.Until, Reason: req.Reason}
+		if err := hsdb.SetQuiet(rw, q); err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, q)
+	})
+}
+
+func deleteQuietHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw, err := dbs.writer()
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		err = hsdb.DeleteQuiet(rw, chi.URLParam(r, "host"))
+		if errors.Is(err, hsdb.ErrNotQuiet) {
+			statusJSON(http.StatusNotFound, err, w, r)
+			return
+		}
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		statusJSON(http.StatusOK, nil, w, r)
+	})
+}
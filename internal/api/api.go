@@ -1,7 +1,10 @@
 package api
 
 import (
-	"database/sql"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +12,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -19,35 +23,213 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/rubiojr/hashup/cmd/hs/types"
 	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/log"
+	"github.com/rubiojr/hashup/internal/templates"
 	"github.com/rubiojr/hashup/pkg/config"
 )
 
-func Serve(cfgPath string, addr string) error {
+// ServeOptions override the [api] configuration settings when set.
+type ServeOptions struct {
+	Addr  string
+	Limit int
+	// UI serves the web search page
+	UI bool
+}
+
+// Serve serves the API using the [api] section of the configuration at
+// cfgPath.
+func Serve(cfgPath string, opts ServeOptions) error {
 	cfg, err := config.LoadConfig(cfgPath)
 	if err != nil {
 		return fmt.Errorf("Failed to load config: %v", err)
 	}
-	dbPath := cfg.Store.DBPath
+	if opts.Addr != "" {
+		cfg.API.ListenAddr = opts.Addr
+	}
+	if opts.Limit > 0 {
+		cfg.API.DefaultLimit = opts.Limit
+	}
+	if opts.UI {
+		cfg.API.UI = true
+	}
+
+	dbs, err := openDatabases(cfg.Store.DBPath)
+	if err != nil {
+		return err
+	}
+	defer dbs.Close()
+
+	handler := newRouter(dbs, cfg.API)
+	if cfg.API.TLSCert != "" && cfg.API.TLSKey != "" {
+		log.Printf("Serving API on https://%s", cfg.API.ListenAddr)
+		return http.ListenAndServeTLS(cfg.API.ListenAddr, cfg.API.TLSCert, cfg.API.TLSKey, handler)
+	}
+
+	log.Printf("Serving API on http://%s", cfg.API.ListenAddr)
+	return http.ListenAndServe(cfg.API.ListenAddr, handler)
+}
+
+// newRouter returns the API handler serving dbs.
+func newRouter(dbs *databases, cfg config.APIConfig) http.Handler {
+	limit := cfg.DefaultLimit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	// Event streams stay open, the other endpoints time out.
+	queryTimeout := time.Duration(cfg.QueryTimeout) * time.Second
+	if queryTimeout <= 0 {
+		queryTimeout = 60 * time.Second
+	}
+	timeout := middleware.Timeout(queryTimeout)
+
+	// Clients are limited after authentication, when their token is known.
+	throttle := func(next http.Handler) http.Handler { return next }
+	if cfg.RateLimit > 0 {
+		throttle = rateLimit(cfg.RateLimit, cfg.RateBurst)
+	}
+
+	// Changes always need a token, reading only when require_auth is set.
+	authenticateWrites := authenticate(dbs)
+	if cfg.RequireAuth {
+		authenticateWrites = func(next http.Handler) http.Handler { return next }
+	}
 
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
-	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(cors(cfg.AllowedOrigins))
 	r.Use(render.SetContentType(render.ContentTypeJSON))
-	r.Get("/search", searchHandler(dbPath))
-	http.ListenAndServe(addr, r)
-
-	http.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+	r.Route(apiVersion, func(r chi.Router) {
+		r.With(throttle, timeout).Get("/health", healthHandler(dbs))
+		r.Group(func(r chi.Router) {
+			if cfg.RequireAuth {
+				r.Use(authenticate(dbs))
+			}
+			r.Use(throttle)
+			r.Get("/events", eventsHandler(dbs))
+			r.Get("/events/ws", eventsWSHandler(dbs, cfg.AllowedOrigins))
+			r.Group(func(r chi.Router) {
+				r.Use(timeout)
+				routes(r, dbs, limit)
+				r.Get("/search/federated", federatedSearchHandler(dbs, limit, cfg))
+				r.Get("/hosts", hostsHandler(dbs))
+				r.Get("/stats/extensions", extensionStatsHandler(dbs))
+				r.Get("/files/large", largeFilesHandler(dbs, limit))
+				r.Get("/files/{hash}", fileHandler(dbs))
+				r.Get("/files/{hash}/tags", fileTagsHandler(dbs))
+				r.With(authenticateWrites).Put("/files/{hash}/tags", setFileTagsHandler(dbs))
+				r.Get("/files/{hash}/chunks", manifestHandler(dbs))
+				r.Get("/files/{hash}/chunks/sources", chunkSourcesHandler(dbs))
+				r.Get("/dupes/near", nearDupesHandler(dbs, limit))
+				r.Get("/tags", tagsHandler(dbs))
+				r.Get("/integrity", integrityHandler(dbs, limit))
+				r.Get("/alerts", alertsHandler(dbs, limit))
+				r.Get("/alerts/quiet", quietPeriodsHandler(dbs))
+				r.With(authenticateWrites).Put("/alerts/quiet/{host}", setQuietHandler(dbs))
+				r.With(authenticateWrites).Delete("/alerts/quiet/{host}", deleteQuietHandler(dbs))
+			})
+		})
 	})
+	// Unversioned routes predate /v1 and are kept for older clients.
+	r.Group(func(r chi.Router) {
+		r.Use(timeout)
+		if cfg.RequireAuth {
+			r.Use(authenticate(dbs))
+		}
+		r.Use(throttle)
+		routes(r, dbs, limit)
+	})
+	// The web search page asks browsers for a token once and keeps it in a
+	// cookie, see authenticateUI.
+	if cfg.UI {
+		r.Group(func(r chi.Router) {
+			r.Use(timeout)
+			if cfg.RequireAuth {
+				r.Use(authenticateUI(dbs))
+			}
+			r.Use(throttle)
+			r.Get("/", uiIndexHandler())
+			r.Get("/ui/results", uiResultsHandler(dbs, limit))
+		})
+		if cfg.RequireAuth {
+			r.With(throttle, timeout).Post("/ui/login", uiLoginHandler(dbs))
+		}
+		r.With(throttle, timeout).Handle("/ui/static/*", http.StripPrefix("/ui/", http.FileServerFS(templates.Static)))
+	}
 
-	return nil
+	return r
 }
 
+// routes registers the endpoints served both with and without the API
+// version prefix.
+func routes(r chi.Router, dbs *databases, limit int) {
+	r.Get("/search", searchHandler(dbs, limit))
+	r.Get("/facets", facetsHandler(dbs))
+	r.Get("/dupes", dupesHandler(dbs, limit))
+	r.Get("/coverage", coverageHandler(dbs))
+	r.Get("/diff", diffHandler(dbs))
+	r.Get("/du", duHandler(dbs))
+	r.Get("/ls", lsHandler(dbs))
+	r.Get("/recent", recentHandler(dbs, limit))
+	r.Get("/timeline", timelineHandler(dbs))
+}
+
+// apiVersion prefixes the paths of the current API version. Client
+// requests are sent to it.
+const apiVersion = "/v1"
+
+// Pagination headers of the /search endpoint.
+const (
+	headerNextCursor = "X-Next-Cursor"
+	headerTotalCount = "X-Total-Count"
+)
+
 type Client struct {
 	client    *http.Client
 	serverURL string
+	token     string
+}
+
+type ClientOption func(*Client)
+
+// WithToken authenticates requests with an API token created by
+// `hashup api token create`.
+func WithToken(token string) ClientOption {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithRootCAs verifies the server certificate with the given CAs instead
+// of the system ones.
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(c *Client) {
+		c.client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+}
+
+// WithTimeout sets the time limit of requests, 10 seconds by default.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.client.Timeout = timeout
+	}
+}
+
+// LoadCACert reads a PEM encoded CA certificate for WithRootCAs.
+func LoadCACert(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
 }
 
-func NewClient(serverURL string) *Client {
+func NewClient(serverURL string, opts ...ClientOption) *Client {
 	client := &http.Client{
 		Timeout: 10 * time.Second,
 		Transport: &http.Transport{
@@ -58,25 +240,116 @@ func NewClient(serverURL string) *Client {
 			TLSHandshakeTimeout: 5 * time.Second,
 		},
 	}
-	return &Client{client: client, serverURL: serverURL}
+	c := &Client{client: client, serverURL: strings.TrimSuffix(serverURL, "/")}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
+// Search runs a query (see db.ParseQuery) on the server, optionally
+// restricted to any of the given extensions and hosts.
 func (c *Client) Search(query string, exts []string, hosts []string, limit int) ([]*types.FileResult, error) {
-	// Build the URL with query parameters
 	params := url.Values{}
+	params.Set("q", query)
 	params.Set("ext", strings.Join(exts, ","))
 	params.Set("host", strings.Join(hosts, ","))
 	params.Set("limit", strconv.Itoa(limit))
-	urlStr := fmt.Sprintf("%s/search?q=%s&%s", c.serverURL, url.QueryEscape(query), params.Encode())
+
+	var results []*types.FileResult
+	if err := c.get("/search", params, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// SearchFiles runs a query (see db.ParseQuery) on the server and returns
+// a page of results. Pass the returned NextCursor in opts.Cursor to fetch
+// the following page.
+func (c *Client) SearchFiles(query string, opts hsdb.SearchOptions) (*hsdb.SearchPage, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("ext", strings.Join(opts.Extensions, ","))
+	params.Set("host", strings.Join(opts.Hosts, ","))
+	params.Set("sort", opts.Sort)
+	params.Set("order", opts.Order)
+	params.Set("cursor", opts.Cursor)
+	params.Set("count", strconv.FormatBool(opts.Count))
+	params.Set("limit", strconv.Itoa(opts.Limit))
+
+	page := &hsdb.SearchPage{Total: -1}
+	header, err := c.request("/search", params, &page.Results)
+	if err != nil {
+		return nil, err
+	}
+
+	page.NextCursor = header.Get(headerNextCursor)
+	if total := header.Get(headerTotalCount); total != "" {
+		page.Total, err = strconv.ParseInt(total, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s header: %v", headerTotalCount, err)
+		}
+	}
+
+	return page, nil
+}
+
+// StatusError is returned by the client when the server answers a request
+// with an error status.
+type StatusError struct {
+	Code int
+	// Msg is the error reported by the server, if any.
+	Msg string
+}
+
+func (e *StatusError) Error() string {
+	if e.Msg != "" {
+		return fmt.Sprintf("server returned error: %s (status: %d)", e.Msg, e.Code)
+	}
+	return fmt.Sprintf("server returned non-OK status: %d", e.Code)
+}
+
+// get fetches path with the given query parameters and decodes the JSON
+// response into v.
+func (c *Client) get(path string, params url.Values, v any) error {
+	_, err := c.request(path, params, v)
+	return err
+}
+
+// request is get returning the response headers.
+func (c *Client) request(path string, params url.Values, v any) (http.Header, error) {
+	return c.do(http.MethodGet, path, params, nil, v)
+}
+
+// do sends a request with body encoded as JSON, if not nil, to path below
+// the API version prefix and decodes the JSON response into v.
+func (c *Client) do(method, path string, params url.Values, body, v any) (http.Header, error) {
+	urlStr := fmt.Sprintf("%s%s%s?%s", c.serverURL, apiVersion, path, params.Encode())
+
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
 
 	// Create request
-	req, err := http.NewRequest("GET", urlStr, nil)
+	req, err := http.NewRequest(method, urlStr, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
 	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	// Execute request
 	resp, err := c.client.Do(req)
@@ -94,23 +367,27 @@ func (c *Client) Search(query string, exts []string, hosts []string, limit int)
 		var errorResp struct {
 			Error string `json:"error"`
 		}
-		if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error != "" {
-			return nil, fmt.Errorf("server returned error: %s (status: %d)", errorResp.Error, resp.StatusCode)
+		serr := &StatusError{Code: resp.StatusCode}
+		if err := json.Unmarshal(body, &errorResp); err == nil {
+			serr.Msg = errorResp.Error
 		}
-
-		return nil, fmt.Errorf("server returned non-OK status: %d", resp.StatusCode)
+		return nil, serr
 	}
 
 	// Parse response body
-	var results []*types.FileResult
-	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return results, nil
+	return resp.Header, nil
 }
 
 func statusJSON(code int, err error, w http.ResponseWriter, r *http.Request) {
+	// Queries interrupted by the request timeout fail with "interrupted".
+	if code >= 500 && errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		code, err = http.StatusGatewayTimeout, errors.New("query timed out")
+	}
+
 	if err != nil {
 		w.WriteHeader(code)
 		render.JSON(w, r, map[string]string{
@@ -127,14 +404,9 @@ func statusJSON(code int, err error, w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func searchHandler(dbPath string) http.HandlerFunc {
+func searchHandler(dbs *databases, limit int) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		db, err := sql.Open("sqlite3", dbPath)
-		if err != nil {
-			statusJSON(http.StatusInternalServerError, err, w, r)
-			return
-		}
-		defer db.Close()
+		db := dbs.reader(r)
 
 		query := r.URL.Query().Get("q")
 		if query == "" {
@@ -142,35 +414,91 @@ func searchHandler(dbPath string) http.HandlerFunc {
 			return
 		}
 
-		ext := r.URL.Query().Get("ext")
-		exts := []string{}
-		if ext != "" {
-			exts = append(exts, ext)
-		}
-
-		host := r.URL.Query().Get("host")
-		hosts := []string{}
-		if host != "" {
-			hosts = append(hosts, host)
-		}
-
-		limit := r.URL.Query().Get("limit")
-		if limit == "" {
-			limit = "100"
-		}
+		// ext and host predate the query language and are still
+		// accepted as additional comma separated filters.
+		exts := listParam(r, "ext")
+		hosts := listParam(r, "host")
 
-		ilimit, err := strconv.Atoi(limit)
+		ilimit, err := limitParam(r, limit)
 		if err != nil {
-			statusJSON(http.StatusBadRequest, errors.New("invalid limit parameter"), w, r)
+			statusJSON(http.StatusBadRequest, err, w, r)
 			return
 		}
 
-		results, err := hsdb.Search(db, query, exts, hosts, ilimit)
+		count, _ := strconv.ParseBool(r.URL.Query().Get("count"))
+		page, err := hsdb.SearchFiles(db, query, hsdb.SearchOptions{
+			Extensions: exts,
+			Hosts:      hosts,
+			Sort:       r.URL.Query().Get("sort"),
+			Order:      r.URL.Query().Get("order"),
+			Cursor:     r.URL.Query().Get("cursor"),
+			Count:      count,
+			Limit:      ilimit,
+		})
+		var qerr *hsdb.QueryError
+		if errors.As(err, &qerr) || errors.Is(err, hsdb.ErrInvalidSort) || errors.Is(err, hsdb.ErrInvalidCursor) {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
 		if err != nil {
 			statusJSON(http.StatusInternalServerError, err, w, r)
 			return
 		}
 
-		render.JSON(w, r, results)
+		// The body stays a plain list of results for older clients,
+		// pagination details travel in headers.
+		if page.NextCursor != "" {
+			w.Header().Set(headerNextCursor, page.NextCursor)
+		}
+		if page.Total >= 0 {
+			w.Header().Set(headerTotalCount, strconv.FormatInt(page.Total, 10))
+		}
+		render.JSON(w, r, page.Results)
 	})
 }
+
+// intParam returns the integer query parameter name, or def when missing.
+func intParam(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, errInvalidParam(name)
+	}
+	return i, nil
+}
+
+// maxLimit is the largest number of results a request may ask for, unless
+// the default limit is larger.
+const maxLimit = 1000
+
+// limitParam returns the limit query parameter, or def when missing.
+// Limits above maxLimit, or def if larger, are rejected.
+func limitParam(r *http.Request, def int) (int, error) {
+	limit, err := intParam(r, "limit", def)
+	if err != nil {
+		return 0, err
+	}
+	if bound := max(maxLimit, def); limit > bound {
+		return 0, fmt.Errorf("invalid limit parameter, the maximum is %d", bound)
+	}
+	return limit, nil
+}
+
+func errInvalidParam(name string) error {
+	return fmt.Errorf("invalid %s parameter", name)
+}
+
+// listParam returns the comma separated query parameter name as a slice.
+func listParam(r *http.Request, name string) []string {
+	var values []string
+	for _, v := range strings.Split(r.URL.Query().Get(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
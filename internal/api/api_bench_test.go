@@ -0,0 +1,87 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// benchIndex returns a database with files indexed files.
+func benchIndex(b *testing.B, files int) string {
+	dbPath := filepath.Join(b.TempDir(), "bench.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	if err != nil {
+		b.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		b.Fatalf("Failed to begin transaction: %v", err)
+	}
+	for i := 0; i < files; i++ {
+		hash := fmt.Sprintf("%016x", i)
+		res, err := tx.Exec("INSERT INTO file_hashes (file_hash) VALUES (?)", hash)
+		if err != nil {
+			b.Fatalf("Failed to insert hash: %v", err)
+		}
+		id, _ := res.LastInsertId()
+		_, err = tx.Exec(`
+			INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash)
+			VALUES (?, ?, '2024-01-01 00:00:00', ?, ?, 'pdf', ?)`,
+			fmt.Sprintf("/docs/%d/report-%d.pdf", i%100, i), i, id, fmt.Sprintf("host%d", i%5), hash)
+		if err != nil {
+			b.Fatalf("Failed to insert file: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatalf("Failed to commit: %v", err)
+	}
+
+	return dbPath
+}
+
+// benchSearch runs concurrent search requests against handler.
+func benchSearch(b *testing.B, handler http.Handler) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/search?q=report+host:host1&limit=20", nil))
+			if rr.Code != http.StatusOK {
+				b.Fatalf("Search failed with status %d: %s", rr.Code, rr.Body.String())
+			}
+		}
+	})
+}
+
+// BenchmarkSearchOpenPerRequest searches the way the API did before sharing
+// a connection pool, opening the database for every request.
+func BenchmarkSearchOpenPerRequest(b *testing.B) {
+	dbPath := benchIndex(b, 5000)
+
+	benchSearch(b, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db, err := sql.Open("sqlite3", dbPath)
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+		defer db.Close()
+
+		if _, err := hsdb.SearchFiles(db, r.URL.Query().Get("q"), hsdb.SearchOptions{Limit: 20}); err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+		}
+	}))
+}
+
+func BenchmarkSearchPool(b *testing.B) {
+	dbPath := benchIndex(b, 5000)
+
+	benchSearch(b, searchHandler(testDatabases(b, dbPath), 100))
+}
@@ -1,20 +1,45 @@
 package api
 
 import (
+	"bufio"
+	"context"
+	"crypto/x509"
+	"database/sql"
 	"encoding/json"
+	"html"
+	"io"
 	"net/http"
+	"net/http/cookiejar"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/rubiojr/hashup/cmd/hs/types"
 	hsdb "github.com/rubiojr/hashup/internal/db"
+	scantypes "github.com/rubiojr/hashup/internal/types"
+	"github.com/rubiojr/hashup/pkg/config"
 )
 
+// testDatabases opens the databases served by the handlers, closing them
+// when the test ends.
+func testDatabases(t testing.TB, dbPath string) *databases {
+	dbs, err := openDatabases(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dbs.Close() })
+	return dbs
+}
+
 func TestSearchHandler(t *testing.T) {
 	// Create a temporary database for testing
 	tempDir := t.TempDir()
@@ -45,7 +70,7 @@ func TestSearchHandler(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Create a handler for testing
-	handler := searchHandler(dbPath)
+	handler := searchHandler(testDatabases(t, dbPath), 100)
 
 	// Test cases
 	testCases := []struct {
@@ -72,6 +97,18 @@ func TestSearchHandler(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectedCount:  0,
 		},
+		{
+			name:           "Search with filters",
+			query:          "testfile ext:pdf,doc -host:otherhost",
+			expectedStatus: http.StatusOK,
+			expectedCount:  1,
+		},
+		{
+			name:           "Search with invalid query",
+			query:          "testfile size:>huge",
+			expectedStatus: http.StatusBadRequest,
+			expectedCount:  0,
+		},
 		{
 			name:           "Search with empty query",
 			query:          "",
@@ -83,7 +120,7 @@ func TestSearchHandler(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// Create a request
-			req, err := http.NewRequest("GET", "/search?q="+tc.query, nil)
+			req, err := http.NewRequest("GET", "/search?q="+url.QueryEscape(tc.query), nil)
 			assert.NoError(t, err)
 
 			// Create a response recorder
@@ -113,4 +150,1006 @@ func TestSearchHandler(t *testing.T) {
 			}
 		})
 	}
+
+	// Limits are bounded
+	for limit, status := range map[string]int{"1000": http.StatusOK, "1001": http.StatusBadRequest} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/search?q=testfile&limit="+limit, nil))
+		assert.Equal(t, status, rr.Code, limit)
+	}
+}
+
+func TestDupesHandler(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		INSERT INTO file_hashes (id, file_hash) VALUES (1, 'hash1'), (2, 'hash2');
+		INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash) VALUES
+		('/a/movie.mkv', 5000000, '2024-01-01 00:00:00', 1, 'laptop', 'mkv', 'hash1'),
+		('/b/movie.mkv', 5000000, '2024-01-01 00:00:00', 1, 'nas', 'mkv', 'hash1'),
+		('/a/notes.txt', 10, '2024-01-01 00:00:00', 2, 'laptop', 'txt', 'hash2'),
+		('/b/notes.txt', 10, '2024-01-01 00:00:00', 2, 'nas', 'txt', 'hash2');
+	`)
+	assert.NoError(t, err)
+
+	srv := httptest.NewServer(dupesHandler(testDatabases(t, dbPath), 100))
+	defer srv.Close()
+	client := NewClient(srv.URL)
+
+	groups, err := client.Dupes(hsdb.DupeOptions{MinSize: 1000})
+	assert.NoError(t, err)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, "hash1", groups[0].Hash)
+	assert.Equal(t, int64(5000000), groups[0].Wasted)
+	assert.Equal(t, []string{"laptop", "nas"}, groups[0].Hosts)
+
+	groups, err = client.Dupes(hsdb.DupeOptions{Types: []string{"document"}})
+	assert.NoError(t, err)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, "hash2", groups[0].Hash)
+}
+
+func TestSearchPagination(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		INSERT INTO file_hashes (id, file_hash) VALUES (1, 'hash1');
+		INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash) VALUES
+		('/a/report-1.pdf', 30, '2024-01-01 00:00:00', 1, 'laptop', 'pdf', 'hash1'),
+		('/a/report-2.pdf', 10, '2024-01-02 00:00:00', 1, 'laptop', 'pdf', 'hash1'),
+		('/a/report-3.pdf', 20, '2024-01-03 00:00:00', 1, 'nas', 'pdf', 'hash1');
+	`)
+	assert.NoError(t, err)
+
+	srv := httptest.NewServer(searchHandler(testDatabases(t, dbPath), 100))
+	defer srv.Close()
+	client := NewClient(srv.URL)
+
+	opts := hsdb.SearchOptions{Sort: hsdb.SortSize, Order: "asc", Count: true, Limit: 2}
+	page, err := client.SearchFiles("report", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), page.Total)
+	assert.Len(t, page.Results, 2)
+	assert.Equal(t, "/a/report-2.pdf", page.Results[0].FilePath)
+	assert.NotEmpty(t, page.NextCursor)
+
+	opts.Cursor = page.NextCursor
+	page, err = client.SearchFiles("report", opts)
+	assert.NoError(t, err)
+	assert.Len(t, page.Results, 1)
+	assert.Equal(t, "/a/report-1.pdf", page.Results[0].FilePath)
+	assert.Empty(t, page.NextCursor)
+
+	_, err = client.SearchFiles("report", hsdb.SearchOptions{Sort: "color"})
+	assert.ErrorContains(t, err, "status: 400")
+}
+
+func TestDUHandler(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		INSERT INTO file_hashes (id, file_hash) VALUES (1, 'hash1');
+		INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash) VALUES
+		('/data/movies/a.mkv', 300, '2024-01-01 00:00:00', 1, 'nas', 'mkv', 'hash1'),
+		('/data/music/b.mp3', 100, '2024-01-01 00:00:00', 1, 'nas', 'mp3', 'hash1');
+	`)
+	assert.NoError(t, err)
+
+	srv := httptest.NewServer(duHandler(testDatabases(t, dbPath)))
+	defer srv.Close()
+	client := NewClient(srv.URL)
+
+	root, err := client.DU(hsdb.TreeRef{Host: "nas", Path: "/data"}, hsdb.DUOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(400), root.Size)
+	assert.Len(t, root.Children, 2)
+	assert.Equal(t, "/data/movies", root.Children[0].Path)
+
+	_, err = client.DU(hsdb.TreeRef{Host: "nas", Path: "/data"}, hsdb.DUOptions{Sort: "color"})
+	assert.ErrorContains(t, err, "status: 400")
+}
+
+func TestLsHandler(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		INSERT INTO file_hashes (id, file_hash) VALUES (1, 'hash1');
+		INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash) VALUES
+		('/data/movies/a.mkv', 300, '2024-01-01 00:00:00', 1, 'nas', 'mkv', 'hash1'),
+		('/data/notes.txt', 100, '2024-01-01 00:00:00', 1, 'nas', 'txt', 'hash1');
+	`)
+	assert.NoError(t, err)
+	assert.NoError(t, hsdb.RebuildDirs(db))
+
+	srv := httptest.NewServer(lsHandler(testDatabases(t, dbPath)))
+	defer srv.Close()
+	client := NewClient(srv.URL)
+
+	listing, err := client.List(hsdb.TreeRef{Host: "nas", Path: "/data"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(400), listing.Dir.Size)
+	assert.Len(t, listing.Dirs, 1)
+	assert.Equal(t, int64(300), listing.Dirs[0].Size)
+	assert.Len(t, listing.Files, 1)
+	assert.Equal(t, "/data/notes.txt", listing.Files[0].FilePath)
+
+	_, err = client.List(hsdb.TreeRef{Host: "nas", Path: "/missing"})
+	assert.ErrorContains(t, err, "status: 404")
+}
+
+func TestOriginalSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	schema, err := os.ReadFile("../db/testdata/original.sql")
+	assert.NoError(t, err)
+	db, err := sql.Open("sqlite3", dbPath)
+	assert.NoError(t, err)
+	_, err = db.Exec(string(schema) + `
+		INSERT INTO file_hashes (id, file_hash) VALUES (1, 'hash1');
+		INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash)
+		VALUES ('/data/notes.txt', 100, '2024-01-01 00:00:00', 1, 'nas', 'txt', 'hash1');
+	`)
+	assert.NoError(t, err)
+	db.Close()
+
+	srv := httptest.NewServer(newRouter(testDatabases(t, dbPath), config.APIConfig{}))
+	defer srv.Close()
+	client := NewClient(srv.URL)
+
+	listing, err := client.List(hsdb.TreeRef{Host: "nas", Path: "/data"})
+	assert.NoError(t, err)
+	assert.Len(t, listing.Files, 1)
+	changes, err := client.Recent(hsdb.RecentOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	_, err = client.Timeline(hsdb.RecentOptions{})
+	assert.NoError(t, err)
+	_, err = client.Integrity(hsdb.IntegrityOptions{})
+	assert.NoError(t, err)
+	_, err = client.Alerts(hsdb.AnomalyOptions{})
+	assert.NoError(t, err)
+}
+
+func TestRecentHandler(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now().UTC()
+	_, err = db.Exec(`
+		INSERT INTO file_hashes (id, file_hash) VALUES (1, 'hash1'), (2, 'hash2');
+		INSERT INTO file_info (file_path, file_size, modified_date, updated_date, hash_id, host, extension, file_hash) VALUES
+		('/data/a.txt', 100, '2024-01-01 00:00:00', ?, 1, 'nas', 'txt', 'hash1'),
+		('/data/a.txt', 300, '2024-01-02 00:00:00', ?, 2, 'nas', 'txt', 'hash2'),
+		('/data/b.txt', 100, '2024-01-01 00:00:00', ?, 1, 'laptop', 'txt', 'hash1');
+	`,
+		now.AddDate(0, 0, -30).Format("2006-01-02 15:04:05"),
+		now.Add(-time.Hour).Format("2006-01-02 15:04:05"),
+		now.Add(-2*time.Hour).Format("2006-01-02 15:04:05"),
+	)
+	assert.NoError(t, err)
+
+	srv := httptest.NewServer(recentHandler(testDatabases(t, dbPath), 100))
+	defer srv.Close()
+	client := NewClient(srv.URL)
+
+	changes, err := client.Recent(hsdb.RecentOptions{Since: now.AddDate(0, 0, -7), Hosts: []string{"nas"}})
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, hsdb.ChangeModified, changes[0].Status)
+		assert.Equal(t, "hash1", changes[0].PrevHash)
+	}
+
+	_, err = client.Recent(hsdb.RecentOptions{Since: now, By: "accessed"})
+	assert.ErrorContains(t, err, "status: 400")
+
+	resp, err := http.Get(srv.URL + "?since=yesterday")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	tsrv := httptest.NewServer(timelineHandler(testDatabases(t, dbPath)))
+	defer tsrv.Close()
+
+	days, err := NewClient(tsrv.URL).Timeline(hsdb.RecentOptions{Since: now.AddDate(0, 0, -7)})
+	assert.NoError(t, err)
+	var files, size int64
+	for _, d := range days {
+		files += d.Files
+		size += d.Size
+	}
+	assert.Equal(t, int64(2), files)
+	assert.Equal(t, int64(400), size)
+}
+
+func TestIntegrityHandler(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		INSERT INTO file_hashes (id, file_hash) VALUES (1, 'hash1');
+		INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash) VALUES
+		('/data/a.txt', 100, '2024-01-01 00:00:00', 1, 'nas', 'txt', 'hash1'),
+		('/backup/a.txt', 100, '2024-01-01 00:00:00', 1, 'laptop', 'txt', 'hash1');
+	`)
+	assert.NoError(t, err)
+	_, err = hsdb.RecordIntegrityEvent(db, &hsdb.IntegrityEvent{
+		Host: "nas", FilePath: "/data/a.txt", FileID: 1, FileSize: 100,
+		ModifiedDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ExpectedHash: "hash1", ActualHash: "hash2",
+	})
+	assert.NoError(t, err)
+
+	srv := httptest.NewServer(integrityHandler(testDatabases(t, dbPath), 100))
+	defer srv.Close()
+	client := NewClient(srv.URL)
+
+	events, err := client.Integrity(hsdb.IntegrityOptions{Hosts: []string{"nas"}})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "hash2", events[0].ActualHash)
+		if assert.Len(t, events[0].GoodCopies, 1) {
+			assert.Equal(t, "laptop", events[0].GoodCopies[0].Host)
+		}
+	}
+
+	events, err = client.Integrity(hsdb.IntegrityOptions{Hosts: []string{"laptop"}})
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestAlertRoutes(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	assert.NoError(t, hsdb.CreateAnomalyAlert(db, &hsdb.AnomalyAlert{
+		Host: "nas", Kind: hsdb.AnomalyMassChange, WindowStart: time.Now(), Files: 600, Threshold: 500,
+		Paths: []string{"/data/a.doc"},
+	}))
+
+	srv := httptest.NewServer(newRouter(testDatabases(t, dbPath), config.APIConfig{}))
+	defer srv.Close()
+	client := NewClient(srv.URL)
+
+	alerts, err := client.Alerts(hsdb.AnomalyOptions{Since: time.Now().Add(-time.Hour)})
+	assert.NoError(t, err)
+	if assert.Len(t, alerts, 1) {
+		assert.Equal(t, "nas", alerts[0].Host)
+		assert.Equal(t, []string{"/data/a.doc"}, alerts[0].Paths)
+	}
+
+	alerts, err = client.Alerts(hsdb.AnomalyOptions{Hosts: []string{"laptop"}, Since: time.Now().Add(-time.Hour)})
+	assert.NoError(t, err)
+	assert.Empty(t, alerts)
+
+	// Changing quiet periods needs a token even when reading doesn't
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	_, err = client.SetQuiet("nas", until, "restore")
+	assert.ErrorContains(t, err, "status: 401")
+	assert.ErrorContains(t, client.DeleteQuiet("nas"), "status: 401")
+	token, err := hsdb.CreateToken(db, "test")
+	assert.NoError(t, err)
+	client = NewClient(srv.URL, WithToken(token))
+
+	q, err := client.SetQuiet("nas", until, "restore")
+	assert.NoError(t, err)
+	assert.Equal(t, "nas", q.Host)
+	assert.Equal(t, until, q.Until)
+
+	_, err = client.SetQuiet("nas", time.Now().Add(-time.Hour), "")
+	assert.Error(t, err)
+
+	periods, err := client.QuietPeriods()
+	assert.NoError(t, err)
+	if assert.Len(t, periods, 1) {
+		assert.Equal(t, "restore", periods[0].Reason)
+	}
+
+	assert.NoError(t, client.DeleteQuiet("nas"))
+	assert.ErrorIs(t, client.DeleteQuiet("nas"), hsdb.ErrNotQuiet)
+}
+
+func TestChunkRoutes(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		INSERT INTO file_hashes (id, file_hash) VALUES (1, 'hash1'), (2, 'hash2');
+		INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash) VALUES
+		('/vm/disk.img', 300, '2024-01-01 00:00:00', 1, 'nas', 'img', 'hash1'),
+		('/vm/disk.img', 300, '2024-01-01 00:00:00', 2, 'laptop', 'img', 'hash2');
+	`)
+	assert.NoError(t, err)
+	sizes := []uint32{100, 100, 100}
+	_, err = hsdb.SaveManifest(db, "hash1", 300, &scantypes.ChunkManifest{AvgSize: 64, Hashes: []uint64{1, 2, 3}, Sizes: sizes})
+	assert.NoError(t, err)
+	_, err = hsdb.SaveManifest(db, "hash2", 300, &scantypes.ChunkManifest{AvgSize: 64, Hashes: []uint64{1, 2, 4}, Sizes: sizes})
+	assert.NoError(t, err)
+
+	srv := httptest.NewServer(newRouter(testDatabases(t, dbPath), config.APIConfig{}))
+	defer srv.Close()
+	client := NewClient(srv.URL)
+
+	dupes, err := client.NearDupes(hsdb.NearDupeOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, dupes, 1) {
+		assert.Equal(t, int64(200), dupes[0].Shared)
+		assert.Len(t, dupes[0].Files, 2)
+	}
+	dupes, err = client.NearDupes(hsdb.NearDupeOptions{MinSimilarity: 0.9})
+	assert.NoError(t, err)
+	assert.Empty(t, dupes)
+
+	m, err := client.Manifest("hash2")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 4}, m.Hashes)
+	_, err = client.Manifest("hash3")
+	assert.ErrorIs(t, err, hsdb.ErrNoManifest)
+
+	sources, err := client.ChunkSources("hash2", "nas")
+	assert.NoError(t, err)
+	assert.Equal(t, []*hsdb.ChunkSource{
+		{Seq: 0, Path: "/vm/disk.img", Offset: 0},
+		{Seq: 1, Path: "/vm/disk.img", Offset: 100},
+	}, sources)
+}
+
+func TestV1Routes(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		INSERT INTO file_hashes (id, file_hash) VALUES (1, 'hash1'), (2, 'hash2');
+		INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash) VALUES
+		('/data/movie.mkv', 3000000000, '2024-01-01 00:00:00', 1, 'nas', 'mkv', 'hash1'),
+		('/backup/movie.mkv', 3000000000, '2024-01-01 00:00:00', 1, 'laptop', 'mkv', 'hash1'),
+		('/data/notes.txt', 100, '2024-01-01 00:00:00', 2, 'nas', 'txt', 'hash2');
+	`)
+	assert.NoError(t, err)
+
+	srv := httptest.NewServer(newRouter(testDatabases(t, dbPath), config.APIConfig{}))
+	defer srv.Close()
+	client := NewClient(srv.URL)
+
+	health, err := client.Health()
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", health.Status)
+
+	hosts, err := client.Hosts()
+	assert.NoError(t, err)
+	assert.Equal(t, []*hsdb.HostStat{
+		{Host: "laptop", Files: 1, Size: 3000000000},
+		{Host: "nas", Files: 2, Size: 3000000100},
+	}, hosts)
+
+	stats, err := client.ExtensionStats("count", true, "nas", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "nas", stats.Host)
+	assert.Len(t, stats.Extensions, 1)
+	assert.Equal(t, int64(2), stats.TotalCount)
+	assert.Equal(t, int64(1), stats.OtherCount)
+
+	large, err := client.LargeFiles(1000, 1)
+	assert.NoError(t, err)
+	assert.Len(t, large, 1)
+	assert.Equal(t, "hash1", large[0].FileHash)
+
+	// Tagging needs a token even when reading doesn't
+	_, err = client.SetTags("hash1", "nas", "/data/movie.mkv", []string{"movies"})
+	assert.ErrorContains(t, err, "status: 401")
+	token, err := hsdb.CreateToken(db, "test")
+	assert.NoError(t, err)
+	client = NewClient(srv.URL, WithToken(token))
+
+	tags, err := client.SetTags("hash1", "nas", "/data/movie.mkv", []string{"movies", "hd", "movies"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hd", "movies"}, tags)
+
+	tags, err = client.FileTags("hash1", "nas", "/data/movie.mkv")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hd", "movies"}, tags)
+
+	tags, err = client.Tags()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hd", "movies"}, tags)
+
+	_, err = client.SetTags("hash1", "", "", []string{"x"})
+	assert.ErrorContains(t, err, "status: 400")
+	_, err = client.FileTags("hash2", "nas", "/data/movie.mkv")
+	assert.ErrorContains(t, err, "status: 404")
+
+	file, err := client.File("hash1")
+	assert.NoError(t, err)
+	if assert.Len(t, file.Copies, 2) {
+		assert.Equal(t, "laptop", file.Copies[0].Host)
+		assert.Empty(t, file.Copies[0].Tags)
+		assert.Equal(t, "/data/movie.mkv", file.Copies[1].FilePath)
+		assert.Equal(t, []string{"hd", "movies"}, file.Copies[1].Tags)
+	}
+
+	_, err = client.File("missing")
+	assert.ErrorIs(t, err, hsdb.ErrFileNotFound)
+
+	// Endpoints predating /v1 are still served without the prefix.
+	resp, err := http.Get(srv.URL + "/search?q=notes")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAuth(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	token, err := hsdb.CreateToken(db, "test")
+	assert.NoError(t, err)
+
+	srv := httptest.NewServer(newRouter(testDatabases(t, dbPath), config.APIConfig{
+		RequireAuth:    true,
+		AllowedOrigins: []string{"https://app.example.com"},
+	}))
+	defer srv.Close()
+
+	_, err = NewClient(srv.URL).Hosts()
+	assert.ErrorContains(t, err, "status: 401")
+	_, err = NewClient(srv.URL, WithToken("hsk_nope")).Hosts()
+	assert.ErrorContains(t, err, "status: 401")
+
+	_, err = NewClient(srv.URL, WithToken(token)).Hosts()
+	assert.NoError(t, err)
+
+	// Health checks don't need a token
+	_, err = NewClient(srv.URL).Health()
+	assert.NoError(t, err)
+
+	// Unversioned routes are protected too
+	resp, err := http.Get(srv.URL + "/search?q=x")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/tags", nil)
+	req.Header.Set("X-API-Key", token)
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Preflight requests from allowed origins are answered without a token
+	req, _ = http.NewRequest(http.MethodOptions, srv.URL+"/v1/tags", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "DELETE")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Methods"), "DELETE")
+
+	req.Header.Set("Origin", "https://evil.example.com")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func TestClientTLS(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	db.Close()
+
+	srv := httptest.NewTLSServer(newRouter(testDatabases(t, dbPath), config.APIConfig{}))
+	defer srv.Close()
+
+	_, err = NewClient(srv.URL).Health()
+	assert.ErrorContains(t, err, "certificate")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	_, err = NewClient(srv.URL, WithRootCAs(pool)).Health()
+	assert.NoError(t, err)
+}
+
+func TestUI(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		INSERT INTO file_hashes (id, file_hash) VALUES (1, 'hash1');
+		INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash) VALUES
+		('/docs/report-1.pdf', 100, '2024-01-01 00:00:00', 1, 'nas', 'pdf', 'hash1'),
+		('/docs/report-2.pdf', 100, '2024-01-01 00:00:00', 1, 'nas', 'pdf', 'hash1'),
+		('/docs/report-3.txt', 100, '2024-01-01 00:00:00', 1, 'laptop', 'txt', 'hash1');
+	`)
+	assert.NoError(t, err)
+
+	get := func(srv *httptest.Server, path string) (int, string) {
+		resp, err := http.Get(srv.URL + path)
+		if !assert.NoError(t, err) {
+			return 0, ""
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	srv := httptest.NewServer(newRouter(testDatabases(t, dbPath), config.APIConfig{}))
+	code, _ := get(srv, "/")
+	assert.Equal(t, http.StatusNotFound, code)
+	srv.Close()
+
+	srv = httptest.NewServer(newRouter(testDatabases(t, dbPath), config.APIConfig{UI: true, DefaultLimit: 2}))
+	defer srv.Close()
+
+	code, body := get(srv, "/")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `src="/ui/static/htmx.min.js?v=1.9.6"`)
+	assert.NotContains(t, body, "unpkg.com")
+
+	code, body = get(srv, "/ui/static/htmx.min.js")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "htmx")
+
+	code, body = get(srv, "/ui/results?q=report")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "3 results")
+	assert.Contains(t, body, `name="host" value="laptop"`)
+	assert.Contains(t, body, `name="ext" value="pdf"`)
+	assert.Contains(t, body, "More results")
+	assert.Equal(t, 2, strings.Count(body, `class="result"`))
+
+	// Following pages only carry results
+	next := regexp.MustCompile(`hx-get="([^"]+)"`).FindStringSubmatch(body)
+	if assert.Len(t, next, 2) {
+		code, body = get(srv, html.UnescapeString(next[1]))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 1, strings.Count(body, `class="result"`))
+		assert.NotContains(t, body, "facets")
+		assert.NotContains(t, body, "More results")
+	}
+
+	code, body = get(srv, "/ui/results?q=report&host=laptop")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "1 result<")
+	assert.Contains(t, body, `value="laptop" checked`)
+
+	_, body = get(srv, "/ui/results?q=size:huge")
+	assert.Contains(t, body, `class="error"`)
+}
+
+func TestUIAuth(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	token, err := hsdb.CreateToken(db, "browser")
+	assert.NoError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO file_hashes (id, file_hash) VALUES (1, 'hash1');
+		INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash) VALUES
+		('/docs/report.pdf', 100, '2024-01-01 00:00:00', 1, 'nas', 'pdf', 'hash1');
+	`)
+	assert.NoError(t, err)
+
+	srv := httptest.NewServer(newRouter(testDatabases(t, dbPath), config.APIConfig{UI: true, RequireAuth: true}))
+	defer srv.Close()
+
+	jar, err := cookiejar.New(nil)
+	assert.NoError(t, err)
+	client := &http.Client{Jar: jar}
+	do := func(req *http.Request) (*http.Response, string) {
+		resp, err := client.Do(req)
+		if !assert.NoError(t, err) {
+			return &http.Response{}, ""
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+	get := func(path string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		return do(req)
+	}
+	login := func(token string) (*http.Response, string) {
+		resp, err := client.PostForm(srv.URL+"/ui/login", url.Values{"token": {token}})
+		if !assert.NoError(t, err) {
+			return &http.Response{}, ""
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	// Browsers without a session get the login form
+	resp, body := get("/")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, body, `action="/ui/login"`)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/ui/results?q=report", nil)
+	req.Header.Set("HX-Request", "true")
+	resp, body = do(req)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "/", resp.Header.Get("HX-Redirect"))
+	assert.NotContains(t, body, "report.pdf")
+
+	resp, _ = get("/ui/static/htmx.min.js")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, body = login("hsk_nope")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, body, "Invalid API token")
+
+	// Logging in redirects to the search page
+	resp, body = login(token)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `hx-get="/ui/results"`)
+	cookies := jar.Cookies(resp.Request.URL)
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, uiCookie, cookies[0].Name)
+	}
+
+	resp, body = get("/ui/results?q=report")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "report.pdf")
+
+	// The cookie only opens the web search page, not the API
+	resp, _ = get("/search?q=report")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp, _ = get("/v1/hosts")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// API tokens in headers work too
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/ui/results?q=report", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestEvents(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	rec, err := hsdb.NewEventRecorder(db)
+	assert.NoError(t, err)
+	defer rec.Close()
+	for _, e := range []*hsdb.Event{
+		{Type: hsdb.EventHostSeen, Host: "nas"},
+		{Type: hsdb.EventFileIndexed, Host: "nas", FilePath: "/data/a.txt"},
+		{Type: hsdb.EventFileIndexed, Host: "laptop", FilePath: "/home/b.txt"},
+		{Type: hsdb.EventFileUpdated, Host: "nas", FilePath: "/data/a.txt"},
+	} {
+		assert.NoError(t, rec.Record(e))
+	}
+
+	defer func(d time.Duration) { eventsPollInterval = d }(eventsPollInterval)
+	eventsPollInterval = 10 * time.Millisecond
+
+	srv := httptest.NewServer(newRouter(testDatabases(t, dbPath), config.APIConfig{AllowedOrigins: []string{"https://app.example.com"}}))
+	defer srv.Close()
+
+	// Events recorded while streaming are sent too
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var got []*hsdb.Event
+	done := make(chan error)
+	go func() {
+		done <- NewClient(srv.URL).Events(ctx, hsdb.EventFilter{
+			AfterID: 1,
+			Types:   []string{hsdb.EventFileIndexed},
+			Hosts:   []string{"nas"},
+		}, func(e *hsdb.Event) error {
+			got = append(got, e)
+			if len(got) == 1 {
+				return rec.Record(&hsdb.Event{Type: hsdb.EventFileIndexed, Host: "nas", FilePath: "/data/c.txt"})
+			}
+			cancel()
+			return nil
+		})
+	}()
+	assert.NoError(t, <-done)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "/data/a.txt", got[0].FilePath)
+		assert.Equal(t, int64(5), got[1].ID)
+		assert.Equal(t, "/data/c.txt", got[1].FilePath)
+	}
+
+	// Browsers resume streams with Last-Event-ID
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/events", nil)
+	req.Header.Set("Last-Event-ID", "3")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	scanner := bufio.NewScanner(resp.Body)
+	var lines []string
+	for len(lines) < 3 && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	resp.Body.Close()
+	if assert.Len(t, lines, 3) {
+		assert.Equal(t, "id: 4", lines[0])
+		assert.Equal(t, "event: file_updated", lines[1])
+	}
+
+	for _, params := range []string{"type=file_renamed", "since_id=x", "q=size:huge"} {
+		resp, err := http.Get(srv.URL + "/v1/events?" + params)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, params)
+	}
+
+	// WebSocket streams
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/events/ws?since_id=0&host=laptop"
+	wctx, wcancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer wcancel()
+	c, _, err := websocket.Dial(wctx, wsURL, nil)
+	if assert.NoError(t, err) {
+		e := &hsdb.Event{}
+		assert.NoError(t, wsjson.Read(wctx, c, e))
+		assert.Equal(t, int64(3), e.ID)
+		assert.Equal(t, "/home/b.txt", e.FilePath)
+		c.Close(websocket.StatusNormalClosure, "")
+	}
+
+	for origin, ok := range map[string]bool{
+		"https://app.example.com":  true,
+		"https://evil.example.com": false,
+	} {
+		c, _, err := websocket.Dial(wctx, wsURL, &websocket.DialOptions{
+			HTTPHeader: http.Header{"Origin": []string{origin}},
+		})
+		if ok && assert.NoError(t, err, origin) {
+			c.Close(websocket.StatusNormalClosure, "")
+		} else if !ok {
+			assert.Error(t, err, origin)
+		}
+	}
+}
+
+func TestFederatedSearch(t *testing.T) {
+	newIndex := func(files string) string {
+		dbPath := filepath.Join(t.TempDir(), "test.db")
+		db, err := hsdb.OpenDatabase(dbPath)
+		assert.NoError(t, err)
+		defer db.Close()
+		_, err = db.Exec(`
+			INSERT INTO file_hashes (id, file_hash) VALUES (1, 'hash1'), (2, 'hash2');
+			INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash) VALUES ` + files)
+		assert.NoError(t, err)
+		return dbPath
+	}
+
+	localDB := newIndex(`
+		('/docs/report.pdf', 100, '2024-01-01 00:00:00', 1, 'laptop', 'pdf', 'hash1'),
+		('/docs/report.txt', 300, '2024-01-03 00:00:00', 2, 'laptop', 'txt', 'hash2')`)
+	officeDB := newIndex(`
+		('/share/report.pdf', 200, '2024-01-02 00:00:00', 1, 'office-nas', 'pdf', 'hash1'),
+		('/docs/report.pdf', 100, '2024-01-01 00:00:00', 1, 'laptop', 'pdf', 'hash1')`)
+
+	office := httptest.NewServer(newRouter(testDatabases(t, officeDB), config.APIConfig{}))
+	defer office.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	sources := []Source{
+		DBSource("local", testDatabases(t, localDB).ro.DB()),
+		ClientSource("office", NewClient(office.URL)),
+		ClientSource("down", NewClient(down.URL)),
+		ClientSource("slow", NewClient(slow.URL)),
+	}
+	page, err := FederatedSearch(sources, "report", hsdb.SearchOptions{Sort: hsdb.SortModified, Count: true}, 200*time.Millisecond)
+	assert.NoError(t, err)
+
+	var got []string
+	for _, r := range page.Results {
+		got = append(got, r.Source+":"+r.Host+":"+r.FilePath)
+	}
+	assert.Equal(t, []string{
+		"local:laptop:/docs/report.txt",
+		"office:office-nas:/share/report.pdf",
+		"local:laptop:/docs/report.pdf",
+	}, got)
+	assert.Equal(t, int64(4), page.Total)
+	if assert.Len(t, page.Errors, 2) {
+		assert.Equal(t, "down", page.Errors[0].Source)
+		assert.Equal(t, "slow", page.Errors[1].Source)
+		assert.Contains(t, page.Errors[1].Error, "timed out")
+	}
+
+	page, err = FederatedSearch(sources[:2], "report", hsdb.SearchOptions{Sort: hsdb.SortSize, Order: "asc", Limit: 2}, time.Second)
+	assert.NoError(t, err)
+	if assert.Len(t, page.Results, 2) {
+		assert.Equal(t, int64(100), page.Results[0].FileSize)
+		assert.Equal(t, int64(200), page.Results[1].FileSize)
+	}
+	assert.Equal(t, int64(-1), page.Total)
+
+	_, err = FederatedSearch(sources[2:3], "report", hsdb.SearchOptions{}, time.Second)
+	assert.Error(t, err)
+	_, err = FederatedSearch(sources, "report", hsdb.SearchOptions{Cursor: "x"}, time.Second)
+	assert.ErrorIs(t, err, hsdb.ErrInvalidCursor)
+
+	// The API federates with the configured peers
+	srv := httptest.NewServer(newRouter(testDatabases(t, localDB), config.APIConfig{
+		Name:        "home",
+		PeerTimeout: 1,
+		Peers: []config.PeerConfig{
+			{Name: "office", URL: office.URL},
+			{URL: down.URL},
+		},
+	}))
+	defer srv.Close()
+
+	page, err = NewClient(srv.URL).FederatedSearch("report ext:pdf", hsdb.SearchOptions{})
+	assert.NoError(t, err)
+	got = nil
+	for _, r := range page.Results {
+		got = append(got, r.Source+":"+r.FilePath)
+	}
+	assert.ElementsMatch(t, []string{"home:/docs/report.pdf", "office:/share/report.pdf"}, got)
+	if assert.Len(t, page.Errors, 1) {
+		assert.Equal(t, PeerName(down.URL), page.Errors[0].Source)
+	}
+
+	for _, params := range []string{"", "q=size:huge", "q=report&sort=name"} {
+		resp, err := http.Get(srv.URL + "/v1/search/federated?" + params)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, params)
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	result := func(source, host, path string) *types.FileResult {
+		return &types.FileResult{Source: source, Host: host, FilePath: path}
+	}
+	// office ranks the file both sources found first, local last
+	lists := [][]*types.FileResult{
+		{result("local", "nas", "/a"), result("local", "nas", "/b"), result("local", "laptop", "/report.pdf")},
+		{result("office", "laptop", "/report.pdf"), result("office", "office-nas", "/c")},
+	}
+
+	var got []string
+	for _, r := range mergeResults(lists, hsdb.SearchOptions{}) {
+		got = append(got, r.Source+":"+r.Host+":"+r.FilePath)
+	}
+	assert.Equal(t, []string{
+		"local:nas:/a",
+		"office:office-nas:/c",
+		"local:nas:/b",
+		"local:laptop:/report.pdf",
+	}, got)
+}
+
+func TestQueryTimeout(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	db.Close()
+
+	handler := searchHandler(testDatabases(t, dbPath), 100)
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/search?q=report", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+	assert.Contains(t, rr.Body.String(), "query timed out")
+}
+
+func TestRateLimit(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	db.Close()
+
+	srv := httptest.NewServer(newRouter(testDatabases(t, dbPath), config.APIConfig{RateLimit: 0.5, RateBurst: 2}))
+	defer srv.Close()
+
+	get := func(token string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/search?q=report", nil)
+		assert.NoError(t, err)
+		if token != "" {
+			req.Header.Set(headerAPIKey, token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	for range 2 {
+		assert.Equal(t, http.StatusOK, get("").StatusCode)
+	}
+	resp := get("")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+
+	// Tokens are only trusted once validated
+	assert.Equal(t, http.StatusTooManyRequests, get("hsk_test").StatusCode)
+
+	db, err = hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	token, err := hsdb.CreateToken(db, "test")
+	assert.NoError(t, err)
+	db.Close()
+
+	authSrv := httptest.NewServer(newRouter(testDatabases(t, dbPath), config.APIConfig{
+		RequireAuth: true,
+		RateLimit:   0.5,
+		RateBurst:   2,
+	}))
+	defer authSrv.Close()
+	srv.URL = authSrv.URL
+
+	// Clients sending a valid token are limited separately
+	for range 2 {
+		assert.Equal(t, http.StatusOK, get(token).StatusCode)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, get(token).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, get("hsk_nope").StatusCode)
+}
+
+func TestOpenDatabases(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	_, err := openDatabases(dbPath)
+	assert.Error(t, err)
+	assert.NoFileExists(t, dbPath)
+
+	db, err := hsdb.OpenDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+	token, err := hsdb.CreateToken(db, "test")
+	assert.NoError(t, err)
+
+	// Reads don't open the read-write connection
+	dbs := testDatabases(t, dbPath)
+	srv := httptest.NewServer(newRouter(dbs, config.APIConfig{RequireAuth: true}))
+	defer srv.Close()
+	_, err = NewClient(srv.URL, WithToken(token)).Hosts()
+	assert.NoError(t, err)
+
+	// Token use is recorded in the background, once a minute at most
+	dbs.touches.Wait()
+	tokens, err := hsdb.Tokens(db)
+	assert.NoError(t, err)
+	if assert.Len(t, tokens, 1) {
+		assert.NotNil(t, tokens[0].LastUsed)
+	}
+	_, err = NewClient(srv.URL, WithToken(token)).Hosts()
+	assert.NoError(t, err)
+	assert.Len(t, dbs.tokenUses, 1)
 }
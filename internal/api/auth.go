@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// headerAPIKey is an alternative to bearer authorization for clients that
+// cannot set the Authorization header.
+const headerAPIKey = "X-API-Key"
+
+// authenticate rejects requests without a valid API token, sent either as
+// a bearer token or in the X-API-Key header.
+func authenticate(dbs *databases) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := requestToken(r)
+			if token == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="hashup"`)
+				statusJSON(http.StatusUnauthorized, errors.New("API token required"), w, r)
+				return
+			}
+
+			name, err := checkToken(dbs, r, token)
+			if errors.Is(err, hsdb.ErrInvalidToken) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="hashup", error="invalid_token"`)
+				statusJSON(http.StatusUnauthorized, err, w, r)
+				return
+			}
+			if err != nil {
+				statusJSON(http.StatusInternalServerError, err, w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withTokenName(r.Context(), name)))
+		})
+	}
+}
+
+// checkToken returns the name of the API token sent with r, or
+// hsdb.ErrInvalidToken unless it is valid. The use of the token is
+// recorded in the background.
+func checkToken(dbs *databases, r *http.Request, token string) (string, error) {
+	name, err := hsdb.CheckToken(dbs.reader(r), token)
+	if err != nil {
+		return "", err
+	}
+	dbs.recordTokenUse(name)
+	return name, nil
+}
+
+type tokenNameKey struct{}
+
+// withTokenName returns a copy of ctx carrying the name of the API token
+// that authenticated the request.
+func withTokenName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, tokenNameKey{}, name)
+}
+
+// tokenName returns the name of the API token that authenticated the
+// request, if any.
+func tokenName(ctx context.Context) string {
+	name, _ := ctx.Value(tokenNameKey{}).(string)
+	return name
+}
+
+// requestToken returns the API token sent with r, either as a bearer token
+// or in the X-API-Key header.
+func requestToken(r *http.Request) string {
+	token := r.Header.Get(headerAPIKey)
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, credentials, _ := strings.Cut(auth, " ")
+		if strings.EqualFold(scheme, "Bearer") {
+			token = strings.TrimSpace(credentials)
+		}
+	}
+	return token
+}
+
+// cors allows browsers on the given origins to call the API. "*" allows
+// any origin.
+func cors(origins []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || !(slices.Contains(origins, "*") || slices.Contains(origins, origin)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Add("Vary", "Origin")
+			h.Set("Access-Control-Expose-Headers", headerNextCursor+", "+headerTotalCount)
+
+			// Answer preflight requests without running the handler.
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+				h.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, "+headerAPIKey)
+				h.Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/types"
+)
+
+// NearDupes returns pairs of contents sharing most of their chunks from the
+// server.
+func (c *Client) NearDupes(opts hsdb.NearDupeOptions) ([]*hsdb.NearDupe, error) {
+	params := url.Values{}
+	params.Set("host", strings.Join(opts.Hosts, ","))
+	params.Set("min_size", strconv.FormatInt(opts.MinSize, 10))
+	params.Set("path", opts.PathPrefix)
+	params.Set("type", strings.Join(opts.Types, ","))
+	params.Set("min_similarity", strconv.FormatFloat(opts.MinSimilarity, 'f', -1, 64))
+	params.Set("limit", strconv.Itoa(opts.Limit))
+
+	var dupes []*hsdb.NearDupe
+	if err := c.get("/dupes/near", params, &dupes); err != nil {
+		return nil, err
+	}
+
+	return dupes, nil
+}
+
+// Manifest returns the chunk manifest of the content with the given hash.
+// Contents without one return an error wrapping hsdb.ErrNoManifest.
+func (c *Client) Manifest(hash string) (*types.ChunkManifest, error) {
+	var m types.ChunkManifest
+	err := c.get("/files/"+url.PathEscape(hash)+"/chunks", url.Values{}, &m)
+	var serr *StatusError
+	if errors.As(err, &serr) && serr.Code == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", hsdb.ErrNoManifest, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// ChunkSources returns the files of host holding chunks of the content
+// with the given hash, see hsdb.ChunkSources.
+func (c *Client) ChunkSources(hash, host string) ([]*hsdb.ChunkSource, error) {
+	params := url.Values{}
+	params.Set("host", host)
+
+	var sources []*hsdb.ChunkSource
+	if err := c.get("/files/"+url.PathEscape(hash)+"/chunks/sources", params, &sources); err != nil {
+		return nil, err
+	}
+
+	return sources, nil
+}
+
+func nearDupesHandler(dbs *databases, defaultLimit int) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db := dbs.reader(r)
+
+		limit, err := limitParam(r, defaultLimit)
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		var minSize uint64
+		if v := r.URL.Query().Get("min_size"); v != "" {
+			minSize, err = humanize.ParseBytes(v)
+			if err != nil {
+				statusJSON(http.StatusBadRequest, errInvalidParam("min_size"), w, r)
+				return
+			}
+		}
+
+		var minSimilarity float64
+		if v := r.URL.Query().Get("min_similarity"); v != "" {
+			minSimilarity, err = strconv.ParseFloat(v, 64)
+			if err != nil || minSimilarity > 1 {
+				statusJSON(http.StatusBadRequest, errInvalidParam("min_similarity"), w, r)
+				return
+			}
+		}
+
+		dupes, err := hsdb.NearDupes(db, hsdb.NearDupeOptions{
+			Hosts:         listParam(r, "host"),
+			MinSize:       int64(minSize),
+			PathPrefix:    r.URL.Query().Get("path"),
+			Types:         listParam(r, "type"),
+			MinSimilarity: minSimilarity,
+			Limit:         limit,
+		})
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, dupes)
+	})
+}
+
+func manifestHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m, err := hsdb.Manifest(dbs.reader(r), chi.URLParam(r, "hash"))
+		if errors.Is(err, hsdb.ErrNoManifest) {
+			statusJSON(http.StatusNotFound, err, w, r)
+			return
+		}
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, m)
+	})
+}
+
+func chunkSourcesHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.URL.Query().Get("host")
+		if host == "" {
+			statusJSON(http.StatusBadRequest, fmt.Errorf("host parameter is required"), w, r)
+			return
+		}
+
+		sources, err := hsdb.ChunkSources(dbs.reader(r), chi.URLParam(r, "hash"), host)
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+		if sources == nil {
+			sources = []*hsdb.ChunkSource{}
+		}
+
+		render.JSON(w, r, sources)
+	})
+}
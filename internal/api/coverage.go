@@ -0,0 +1,76 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/go-chi/render"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// Coverage returns the backup coverage report from the server.
+func (c *Client) Coverage(opts hsdb.CoverageOptions) (*hsdb.CoverageReport, error) {
+	params := url.Values{}
+	params.Set("min_copies", strconv.Itoa(opts.MinCopies))
+	params.Set("require_host", strings.Join(opts.RequiredHosts, ","))
+	params.Set("host", strings.Join(opts.Hosts, ","))
+	params.Set("path", opts.PathPrefix)
+	params.Set("min_size", strconv.FormatInt(opts.MinSize, 10))
+	params.Set("depth", strconv.Itoa(opts.Depth))
+	params.Set("limit", strconv.Itoa(opts.Limit))
+
+	var report hsdb.CoverageReport
+	if err := c.get("/coverage", params, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+func coverageHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db := dbs.reader(r)
+
+		opts := hsdb.CoverageOptions{
+			RequiredHosts: listParam(r, "require_host"),
+			Hosts:         listParam(r, "host"),
+			PathPrefix:    r.URL.Query().Get("path"),
+		}
+
+		for name, v := range map[string]*int{"min_copies": &opts.MinCopies, "depth": &opts.Depth} {
+			n, err := intParam(r, name, 0)
+			if err != nil {
+				statusJSON(http.StatusBadRequest, err, w, r)
+				return
+			}
+			*v = n
+		}
+		limit, err := limitParam(r, 0)
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+		opts.Limit = limit
+
+		if v := r.URL.Query().Get("min_size"); v != "" {
+			minSize, err := humanize.ParseBytes(v)
+			if err != nil {
+				statusJSON(http.StatusBadRequest, fmt.Errorf("invalid min_size parameter"), w, r)
+				return
+			}
+			opts.MinSize = int64(minSize)
+		}
+
+		report, err := hsdb.Coverage(db, opts)
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, report)
+	})
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/log"
+)
+
+// tokenUseInterval is how often the use of an API token is recorded at
+// most, so that authenticated requests don't all wait for the writer.
+const tokenUseInterval = time.Minute
+
+// databases are the connections shared by the handlers: a read-only pool
+// for queries and a read-write connection for tagging and token use, only
+// opened when one of them needs it.
+type databases struct {
+	ro   *hsdb.Pool
+	path string
+
+	mu sync.Mutex
+	rw *sql.DB
+	// tokenUses is when the use of each API token was last recorded.
+	tokenUses map[string]time.Time
+	touches   sync.WaitGroup
+}
+
+// openDatabases opens the existing database at dbPath for reading,
+// bringing its schema up to date first if it was created by an older
+// version.
+func openDatabases(dbPath string) (*databases, error) {
+	ro, err := hsdb.OpenReadOnly(dbPath, max(4, runtime.NumCPU()))
+	if err != nil {
+		return nil, err
+	}
+	d := &databases{ro: ro, path: dbPath, tokenUses: map[string]time.Time{}}
+
+	outdated, err := hsdb.Outdated(ro.WithContext(context.Background()))
+	if err == nil && outdated {
+		_, err = d.writer()
+	}
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// reader returns the read-only pool bound to the request context, so that
+// queries stop when the client goes away or the request times out.
+func (d *databases) reader(r *http.Request) hsdb.Querier {
+	return d.ro.WithContext(r.Context())
+}
+
+// writer returns the read-write connection, opening it and bringing the
+// schema up to date the first time.
+func (d *databases) writer() (*sql.DB, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.rw != nil {
+		return d.rw, nil
+	}
+
+	rw, err := hsdb.OpenDatabase(d.path)
+	if err != nil {
+		if rw != nil {
+			rw.Close()
+		}
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	// SQLite allows a single writer at a time.
+	rw.SetMaxOpenConns(1)
+	d.rw = rw
+
+	return rw, nil
+}
+
+// recordTokenUse updates the last use of the API token called name in the
+// background, once every tokenUseInterval at most.
+func (d *databases) recordTokenUse(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(d.tokenUses[name]) < tokenUseInterval {
+		return
+	}
+	d.tokenUses[name] = now
+
+	d.touches.Add(1)
+	go func() {
+		defer d.touches.Done()
+
+		rw, err := d.writer()
+		if err == nil {
+			err = hsdb.TouchToken(rw, name)
+		}
+		if err != nil {
+			log.Errorf("failed to record the use of token %s: %v", name, err)
+		}
+	}()
+}
+
+func (d *databases) Close() error {
+	d.touches.Wait()
+	d.ro.Close()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.rw == nil {
+		return nil
+	}
+	return d.rw.Close()
+}
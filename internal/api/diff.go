@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/render"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// Diff compares two indexed trees on the server.
+func (c *Client) Diff(from, to hsdb.TreeRef) (*hsdb.TreeDiff, error) {
+	params := url.Values{}
+	params.Set("from", from.String())
+	params.Set("to", to.String())
+
+	var diff hsdb.TreeDiff
+	if err := c.get("/diff", params, &diff); err != nil {
+		return nil, err
+	}
+
+	return &diff, nil
+}
+
+func diffHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		from, err := hsdb.ParseTreeRef(r.URL.Query().Get("from"))
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		to, err := hsdb.ParseTreeRef(r.URL.Query().Get("to"))
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		db := dbs.reader(r)
+
+		diff, err := hsdb.Diff(db, from, to)
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, diff)
+	})
+}
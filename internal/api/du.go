@@ -0,0 +1,56 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/render"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// DU returns the disk usage of an indexed tree on the server.
+func (c *Client) DU(tree hsdb.TreeRef, opts hsdb.DUOptions) (*hsdb.DUNode, error) {
+	params := url.Values{}
+	params.Set("tree", tree.String())
+	params.Set("depth", strconv.Itoa(opts.Depth))
+	params.Set("sort", opts.Sort)
+
+	var root hsdb.DUNode
+	if err := c.get("/du", params, &root); err != nil {
+		return nil, err
+	}
+
+	return &root, nil
+}
+
+func duHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tree, err := hsdb.ParseTreeRef(r.URL.Query().Get("tree"))
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		depth, err := intParam(r, "depth", 1)
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		db := dbs.reader(r)
+
+		root, err := hsdb.DU(db, tree, hsdb.DUOptions{Depth: depth, Sort: r.URL.Query().Get("sort")})
+		if errors.Is(err, hsdb.ErrInvalidSort) {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, root)
+	})
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/go-chi/render"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// Dupes returns groups of duplicated files from the server.
+func (c *Client) Dupes(opts hsdb.DupeOptions) ([]*hsdb.DupeGroup, error) {
+	params := url.Values{}
+	params.Set("host", strings.Join(opts.Hosts, ","))
+	params.Set("min_size", strconv.FormatInt(opts.MinSize, 10))
+	params.Set("path", opts.PathPrefix)
+	params.Set("type", strings.Join(opts.Types, ","))
+	params.Set("limit", strconv.Itoa(opts.Limit))
+
+	var groups []*hsdb.DupeGroup
+	if err := c.get("/dupes", params, &groups); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func dupesHandler(dbs *databases, defaultLimit int) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db := dbs.reader(r)
+
+		limit, err := limitParam(r, defaultLimit)
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		var minSize uint64
+		if v := r.URL.Query().Get("min_size"); v != "" {
+			minSize, err = humanize.ParseBytes(v)
+			if err != nil {
+				statusJSON(http.StatusBadRequest, fmt.Errorf("invalid min_size parameter"), w, r)
+				return
+			}
+		}
+
+		groups, err := hsdb.Dupes(db, hsdb.DupeOptions{
+			Hosts:      listParam(r, "host"),
+			MinSize:    int64(minSize),
+			PathPrefix: r.URL.Query().Get("path"),
+			Types:      listParam(r, "type"),
+			Limit:      limit,
+		})
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, groups)
+	})
+}
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+var (
+	// eventsPollInterval is how often streams check the database for new
+	// events recorded by the store.
+	eventsPollInterval = time.Second
+	// eventsHeartbeat is how often idle streams send a keepalive so
+	// proxies don't close them.
+	eventsHeartbeat = 15 * time.Second
+)
+
+// eventsBatch is the maximum number of events read per poll.
+const eventsBatch = 100
+
+// Events streams the events matching filter from the server, calling fn for
+// each of them until ctx is cancelled or fn returns an error. Only events
+// newer than filter.AfterID are sent, or those recorded after the call
+// when it is zero.
+func (c *Client) Events(ctx context.Context, filter hsdb.EventFilter, fn func(*hsdb.Event) error) error {
+	params := url.Values{}
+	params.Set("type", strings.Join(filter.Types, ","))
+	params.Set("host", strings.Join(filter.Hosts, ","))
+	params.Set("q", filter.Query)
+	if filter.AfterID > 0 {
+		params.Set("since_id", strconv.FormatInt(filter.AfterID, 10))
+	}
+	urlStr := fmt.Sprintf("%s%s/events?%s", c.serverURL, apiVersion, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	// The client timeout would end the stream.
+	client := &http.Client{Transport: c.client.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResp struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&errorResp); err == nil && errorResp.Error != "" {
+			return fmt.Errorf("server returned error: %s (status: %d)", errorResp.Error, resp.StatusCode)
+		}
+		return fmt.Errorf("server returned non-OK status: %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	var event string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+			continue
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			// Ids are repeated in the data, comments are heartbeats.
+			continue
+		}
+
+		if event == "error" {
+			msg, _ := strconv.Unquote(data)
+			return fmt.Errorf("server returned error: %s", msg)
+		}
+		e := &hsdb.Event{}
+		if err := json.Unmarshal([]byte(data), e); err != nil {
+			return fmt.Errorf("failed to decode event: %w", err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read events: %w", err)
+	}
+
+	return nil
+}
+
+// eventFilter parses the type, host, q and since_id parameters of the event
+// streams. Server-Sent Events clients resuming a stream send the last id
+// received in the Last-Event-ID header instead.
+func eventFilter(r *http.Request) (hsdb.EventFilter, error) {
+	f := hsdb.EventFilter{
+		Types: listParam(r, "type"),
+		Hosts: listParam(r, "host"),
+		Query: r.URL.Query().Get("q"),
+		Limit: eventsBatch,
+	}
+	for _, t := range f.Types {
+		if !slices.Contains(hsdb.EventTypes, t) {
+			return f, fmt.Errorf("invalid event type %q, expected one of %s", t, strings.Join(hsdb.EventTypes, ", "))
+		}
+	}
+
+	since := r.URL.Query().Get("since_id")
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		since = id
+	}
+	if since == "" {
+		f.AfterID = -1
+		return f, nil
+	}
+
+	id, err := strconv.ParseInt(since, 10, 64)
+	if err != nil || id < 0 {
+		return f, errInvalidParam("since_id")
+	}
+	f.AfterID = id
+	return f, nil
+}
+
+// startEvents checks the filter of an event stream, starting it at the
+// latest event when it has no starting point.
+func startEvents(db hsdb.Querier, f *hsdb.EventFilter) (int, error) {
+	if f.AfterID < 0 {
+		id, err := hsdb.LastEventID(db)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		f.AfterID = id
+	}
+
+	// Reject invalid queries before the stream starts.
+	_, err := hsdb.Events(db, hsdb.EventFilter{AfterID: f.AfterID, Query: f.Query, Limit: 1})
+	var qerr *hsdb.QueryError
+	if errors.As(err, &qerr) {
+		return http.StatusBadRequest, err
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusOK, nil
+}
+
+// streamEvents polls the database for events matching f, calling send for
+// each of them and heartbeat when idle, until ctx is cancelled or either
+// returns an error.
+func streamEvents(ctx context.Context, db hsdb.Querier, f hsdb.EventFilter, send func(*hsdb.Event) error, heartbeat func() error) error {
+	poll := time.NewTicker(eventsPollInterval)
+	defer poll.Stop()
+	lastSent := time.Now()
+
+	for {
+		// Filtered out events are skipped by moving past the latest id,
+		// read first so that events recorded meanwhile are not missed.
+		lastID, err := hsdb.LastEventID(db)
+		if err != nil {
+			return err
+		}
+		events, err := hsdb.Events(db, f)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if err := send(e); err != nil {
+				return err
+			}
+			f.AfterID = e.ID
+			lastSent = time.Now()
+		}
+		if len(events) == f.Limit {
+			continue
+		}
+		f.AfterID = max(f.AfterID, lastID)
+
+		if time.Since(lastSent) >= eventsHeartbeat {
+			if err := heartbeat(); err != nil {
+				return err
+			}
+			lastSent = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-poll.C:
+		}
+	}
+}
+
+// eventsHandler streams events as Server-Sent Events.
+func eventsHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, err := eventFilter(r)
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		db := dbs.reader(r)
+		if code, err := startEvents(db, &f); err != nil {
+			statusJSON(code, err, w, r)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		send := func(e *hsdb.Event) error {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+		heartbeat := func() error {
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+
+		err = streamEvents(r.Context(), db, f, send, heartbeat)
+		if err != nil && r.Context().Err() == nil {
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", strconv.Quote(err.Error()))
+			rc.Flush()
+		}
+	})
+}
+
+// eventsWSHandler streams events as JSON WebSocket messages. Browsers may
+// connect from the allowed CORS origins.
+func eventsWSHandler(dbs *databases, origins []string) http.HandlerFunc {
+	var patterns []string
+	for _, o := range origins {
+		if u, err := url.Parse(o); err == nil && u.Host != "" {
+			o = u.Host
+		}
+		patterns = append(patterns, o)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, err := eventFilter(r)
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		db := dbs.reader(r)
+		if code, err := startEvents(db, &f); err != nil {
+			statusJSON(code, err, w, r)
+			return
+		}
+
+		c, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: patterns})
+		if err != nil {
+			return
+		}
+		defer c.CloseNow()
+
+		// Nothing is expected from the client, reading handles pings and
+		// cancels ctx when it goes away.
+		ctx := c.CloseRead(r.Context())
+		send := func(e *hsdb.Event) error {
+			wctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+			return wsjson.Write(wctx, c, e)
+		}
+		heartbeat := func() error {
+			pctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+			return c.Ping(pctx)
+		}
+
+		err = streamEvents(ctx, dbs.ro.WithContext(ctx), f, send, heartbeat)
+		if err != nil && ctx.Err() == nil {
+			c.Close(websocket.StatusInternalError, "failed to stream events")
+			return
+		}
+		c.Close(websocket.StatusNormalClosure, "")
+	})
+}
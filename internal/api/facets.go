@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/render"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// Facets counts the files matching a query on the server by host, type and
+// extension.
+func (c *Client) Facets(query string, opts hsdb.SearchOptions) (*hsdb.Facets, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("ext", strings.Join(opts.Extensions, ","))
+	params.Set("host", strings.Join(opts.Hosts, ","))
+
+	var facets hsdb.Facets
+	if err := c.get("/facets", params, &facets); err != nil {
+		return nil, err
+	}
+
+	return &facets, nil
+}
+
+func facetsHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db := dbs.reader(r)
+
+		facets, err := hsdb.SearchFacets(db, r.URL.Query().Get("q"), hsdb.SearchOptions{
+			Extensions: listParam(r, "ext"),
+			Hosts:      listParam(r, "host"),
+		})
+		var qerr *hsdb.QueryError
+		if errors.As(err, &qerr) {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, facets)
+	})
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/rubiojr/hashup/cmd/hs/types"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/pkg/config"
+)
+
+// Source is a search index queried by FederatedSearch.
+type Source struct {
+	Name   string
+	Search func(query string, opts hsdb.SearchOptions) (*hsdb.SearchPage, error)
+}
+
+// DBSource searches db.
+func DBSource(name string, db hsdb.Querier) Source {
+	return Source{Name: name, Search: func(query string, opts hsdb.SearchOptions) (*hsdb.SearchPage, error) {
+		return hsdb.SearchFiles(db, query, opts)
+	}}
+}
+
+// ClientSource searches the API server of c.
+func ClientSource(name string, c *Client) Source {
+	return Source{Name: name, Search: c.SearchFiles}
+}
+
+// PeerSources returns the sources of the [[api.peers]] configuration,
+// with requests limited to timeout.
+func PeerSources(peers []config.PeerConfig, timeout time.Duration) ([]Source, error) {
+	var sources []Source
+	for _, p := range peers {
+		opts := []ClientOption{WithTimeout(timeout)}
+		if p.Token != "" {
+			opts = append(opts, WithToken(p.Token))
+		}
+		if p.CACert != "" {
+			pool, err := LoadCACert(p.CACert)
+			if err != nil {
+				return nil, fmt.Errorf("peer %s: %v", p.Name, err)
+			}
+			opts = append(opts, WithRootCAs(pool))
+		}
+
+		name := p.Name
+		if name == "" {
+			name = PeerName(p.URL)
+		}
+		sources = append(sources, ClientSource(name, NewClient(p.URL, opts...)))
+	}
+	return sources, nil
+}
+
+// PeerName returns the host of serverURL, the default name of peers.
+func PeerName(serverURL string) string {
+	if u, err := url.Parse(serverURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return serverURL
+}
+
+// SourceError reports a source left out of federated search results.
+type SourceError struct {
+	Source string `json:"source"`
+	Error  string `json:"error"`
+}
+
+// FederatedPage holds the merged results of a federated search.
+type FederatedPage struct {
+	Results []*types.FileResult `json:"results"`
+	// Total is the number of matches in the sources that answered, or -1
+	// when not requested.
+	Total  int64         `json:"total"`
+	Errors []SourceError `json:"errors,omitempty"`
+}
+
+// FederatedSearch runs query on every source concurrently and merges the
+// results. Files found by several sources, by host and path, are reported
+// once, from the first source listed. Sources failing or not answering
+// within timeout are reported in Errors, the search only fails when all of
+// them do. Cursors are not supported.
+func FederatedSearch(sources []Source, query string, opts hsdb.SearchOptions, timeout time.Duration) (*FederatedPage, error) {
+	if opts.Cursor != "" {
+		return nil, fmt.Errorf("%w: federated searches don't support cursors", hsdb.ErrInvalidCursor)
+	}
+
+	type answer struct {
+		i    int
+		page *hsdb.SearchPage
+		err  error
+	}
+	// Buffered so that late sources don't block once we stop waiting.
+	answers := make(chan answer, len(sources))
+	for i, s := range sources {
+		go func() {
+			page, err := s.Search(query, opts)
+			answers <- answer{i, page, err}
+		}()
+	}
+
+	pages := make([]*hsdb.SearchPage, len(sources))
+	errs := make([]error, len(sources))
+	for i := range errs {
+		errs[i] = fmt.Errorf("timed out after %s", timeout)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+wait:
+	for range sources {
+		select {
+		case a := <-answers:
+			pages[a.i], errs[a.i] = a.page, a.err
+		case <-timer.C:
+			break wait
+		}
+	}
+
+	fp := &FederatedPage{Results: []*types.FileResult{}, Total: -1}
+	var lists [][]*types.FileResult
+	var firstErr error
+	for i, s := range sources {
+		if err := errs[i]; err != nil {
+			fp.Errors = append(fp.Errors, SourceError{Source: s.Name, Error: err.Error()})
+			firstErr = cmp.Or(firstErr, err)
+			continue
+		}
+
+		for _, r := range pages[i].Results {
+			r.Source = s.Name
+		}
+		lists = append(lists, pages[i].Results)
+		if opts.Count && pages[i].Total >= 0 {
+			fp.Total = max(fp.Total, 0) + pages[i].Total
+		}
+	}
+	if len(lists) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+
+	fp.Results = mergeResults(lists, opts)
+	if opts.Limit > 0 && len(fp.Results) > opts.Limit {
+		fp.Results = fp.Results[:opts.Limit]
+	}
+	return fp, nil
+}
+
+// mergeResults merges the per source results, keeping duplicates from the
+// first source listed. Results ranked by relevance are interleaved, other
+// orders are kept.
+func mergeResults(lists [][]*types.FileResult, opts hsdb.SearchOptions) []*types.FileResult {
+	// Duplicates are dropped before interleaving, so that a later source
+	// ranking a file higher doesn't win it.
+	seen := map[string]bool{}
+	unique := make([][]*types.FileResult, len(lists))
+	for i, l := range lists {
+		for _, r := range l {
+			key := r.Host + "\x00" + r.FilePath
+			if !seen[key] {
+				seen[key] = true
+				unique[i] = append(unique[i], r)
+			}
+		}
+	}
+
+	var merged []*types.FileResult
+	for i := 0; ; i++ {
+		added := false
+		for _, l := range unique {
+			if i < len(l) {
+				merged = append(merged, l[i])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+
+	compare := resultOrder(opts)
+	if compare != nil {
+		slices.SortStableFunc(merged, compare)
+	}
+	return merged
+}
+
+// resultOrder returns the comparison matching the sort options, nil for
+// relevance.
+func resultOrder(opts hsdb.SearchOptions) func(a, b *types.FileResult) int {
+	var compare func(a, b *types.FileResult) int
+	desc := false
+	switch opts.Sort {
+	case hsdb.SortModified:
+		compare = func(a, b *types.FileResult) int { return a.ModifiedDate.Compare(b.ModifiedDate) }
+		desc = true
+	case hsdb.SortSize:
+		compare = func(a, b *types.FileResult) int { return cmp.Compare(a.FileSize, b.FileSize) }
+		desc = true
+	case hsdb.SortPath:
+		compare = func(a, b *types.FileResult) int { return strings.Compare(a.FilePath, b.FilePath) }
+	case hsdb.SortHost:
+		compare = func(a, b *types.FileResult) int {
+			return cmp.Or(strings.Compare(a.Host, b.Host), strings.Compare(a.FilePath, b.FilePath))
+		}
+	default:
+		return nil
+	}
+
+	switch strings.ToLower(opts.Order) {
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	}
+	if desc {
+		return func(a, b *types.FileResult) int { return compare(b, a) }
+	}
+	return compare
+}
+
+// FederatedSearch runs a federated search on the server, querying its
+// configured peers too.
+func (c *Client) FederatedSearch(query string, opts hsdb.SearchOptions) (*FederatedPage, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("ext", strings.Join(opts.Extensions, ","))
+	params.Set("host", strings.Join(opts.Hosts, ","))
+	params.Set("sort", opts.Sort)
+	params.Set("order", opts.Order)
+	params.Set("count", strconv.FormatBool(opts.Count))
+	params.Set("limit", strconv.Itoa(opts.Limit))
+
+	page := &FederatedPage{}
+	if err := c.get("/search/federated", params, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// federatedSearchHandler searches the local database and the configured
+// peers.
+func federatedSearchHandler(dbs *databases, limit int, cfg config.APIConfig) http.HandlerFunc {
+	timeout := time.Duration(cfg.PeerTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	peers, peersErr := PeerSources(cfg.Peers, timeout)
+	name := cmp.Or(cfg.Name, "local")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if peersErr != nil {
+			statusJSON(http.StatusInternalServerError, peersErr, w, r)
+			return
+		}
+
+		query := r.URL.Query().Get("q")
+		if query == "" {
+			statusJSON(http.StatusBadRequest, errors.New("q query parameter is required"), w, r)
+			return
+		}
+		// Invalid queries would fail on every peer.
+		if _, err := hsdb.ParseQuery(query); err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		ilimit, err := limitParam(r, limit)
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		sort := r.URL.Query().Get("sort")
+		if sort != "" && !slices.Contains(hsdb.SortKeys, sort) {
+			statusJSON(http.StatusBadRequest, fmt.Errorf("%w %q", hsdb.ErrInvalidSort, sort), w, r)
+			return
+		}
+
+		count, _ := strconv.ParseBool(r.URL.Query().Get("count"))
+		sources := append([]Source{DBSource(name, dbs.reader(r))}, peers...)
+		page, err := FederatedSearch(sources, query, hsdb.SearchOptions{
+			Extensions: listParam(r, "ext"),
+			Hosts:      listParam(r, "host"),
+			Sort:       sort,
+			Order:      r.URL.Query().Get("order"),
+			Count:      count,
+			Limit:      ilimit,
+		}, timeout)
+		if errors.Is(err, hsdb.ErrInvalidSort) {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+		if err != nil {
+			statusJSON(http.StatusBadGateway, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, page)
+	})
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// TagsRequest is the body of PUT /v1/files/{hash}/tags.
+type TagsRequest struct {
+	Host string   `json:"host"`
+	Path string   `json:"path"`
+	Tags []string `json:"tags"`
+}
+
+// File returns the copies of the content with the given hash on the
+// server. Unknown content returns an error wrapping hsdb.ErrFileNotFound.
+func (c *Client) File(hash string) (*hsdb.FileDetail, error) {
+	var d hsdb.FileDetail
+	err := c.get("/files/"+url.PathEscape(hash), url.Values{}, &d)
+	var serr *StatusError
+	if errors.As(err, &serr) && serr.Code == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", hsdb.ErrFileNotFound, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// FileTags returns the tags of the copy of hash at path on host.
+func (c *Client) FileTags(hash, host, path string) ([]string, error) {
+	params := url.Values{}
+	params.Set("host", host)
+	params.Set("path", path)
+
+	var tags []string
+	if err := c.get("/files/"+url.PathEscape(hash)+"/tags", params, &tags); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// SetTags replaces the tags of the copy of hash at path on host, returning
+// the saved tags. An empty list removes them.
+func (c *Client) SetTags(hash, host, path string, tags []string) ([]string, error) {
+	var saved []string
+	body := &TagsRequest{Host: host, Path: path, Tags: tags}
+	_, err := c.do(http.MethodPut, "/files/"+url.PathEscape(hash)+"/tags", url.Values{}, body, &saved)
+	if err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// Tags returns every tag in use on the server.
+func (c *Client) Tags() ([]string, error) {
+	var tags []string
+	if err := c.get("/tags", url.Values{}, &tags); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func fileHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db := dbs.reader(r)
+
+		d, err := hsdb.File(db, chi.URLParam(r, "hash"))
+		if errors.Is(err, hsdb.ErrFileNotFound) {
+			statusJSON(http.StatusNotFound, err, w, r)
+			return
+		}
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, d)
+	})
+}
+
+func fileTagsHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db := dbs.reader(r)
+
+		q := r.URL.Query()
+		id, err := fileID(db, chi.URLParam(r, "hash"), q.Get("host"), q.Get("path"))
+		if err != nil {
+			fileError(err, w, r)
+			return
+		}
+
+		tags, err := hsdb.FileTags(db, id)
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, tags)
+	})
+}
+
+func setFileTagsHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req TagsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			statusJSON(http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err), w, r)
+			return
+		}
+
+		db := dbs.reader(r)
+
+		id, err := fileID(db, chi.URLParam(r, "hash"), req.Host, req.Path)
+		if err != nil {
+			fileError(err, w, r)
+			return
+		}
+
+		rw, err := dbs.writer()
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		if err := hsdb.SetTags(rw, id, req.Tags); err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		tags, err := hsdb.FileTags(db, id)
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, tags)
+	})
+}
+
+func tagsHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db := dbs.reader(r)
+
+		tags, err := hsdb.AllTags(db)
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, tags)
+	})
+}
+
+// errMissingFile is returned when a tags request does not say which copy
+// of the content to tag.
+var errMissingFile = errors.New("host and path are required")
+
+func fileID(db hsdb.Querier, hash, host, path string) (int64, error) {
+	if host == "" || path == "" {
+		return 0, errMissingFile
+	}
+	return hsdb.FileID(db, host, path, hash)
+}
+
+// fileError responds to errors looking up a file.
+func fileError(err error, w http.ResponseWriter, r *http.Request) {
+	switch {
+	case errors.Is(err, errMissingFile):
+		statusJSON(http.StatusBadRequest, err, w, r)
+	case errors.Is(err, hsdb.ErrFileNotFound):
+		statusJSON(http.StatusNotFound, err, w, r)
+	default:
+		statusJSON(http.StatusInternalServerError, err, w, r)
+	}
+}
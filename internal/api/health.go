@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"runtime/debug"
+
+	"github.com/go-chi/render"
+)
+
+// Health is the response of the health endpoint.
+type Health struct {
+	Status  string `json:"status"`
+	Version string `json:"version"`
+}
+
+// Health checks that the server is up and can read its database.
+func (c *Client) Health() (*Health, error) {
+	var h Health
+	if err := c.get("/health", url.Values{}, &h); err != nil {
+		return nil, err
+	}
+
+	return &h, nil
+}
+
+func healthHandler(dbs *databases) http.HandlerFunc {
+	version := "unknown"
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		version = bi.Main.Version
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db := dbs.reader(r)
+
+		var ok bool
+		if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM file_info)").Scan(&ok); err != nil {
+			statusJSON(http.StatusServiceUnavailable, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, &Health{Status: "ok", Version: version})
+	})
+}
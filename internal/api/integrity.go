@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/render"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// Integrity returns the files on the server suspected of silent
+// corruption.
+func (c *Client) Integrity(opts hsdb.IntegrityOptions) ([]*hsdb.IntegrityEvent, error) {
+	params := url.Values{}
+	params.Set("host", strings.Join(opts.Hosts, ","))
+	params.Set("resolved", strconv.FormatBool(opts.Resolved))
+	params.Set("limit", strconv.Itoa(opts.Limit))
+
+	var events []*hsdb.IntegrityEvent
+	if err := c.get("/integrity", params, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func integrityHandler(dbs *databases, limit int) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, err := limitParam(r, limit)
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+		resolved, _ := strconv.ParseBool(r.URL.Query().Get("resolved"))
+
+		db := dbs.reader(r)
+
+		events, err := hsdb.IntegrityEvents(db, hsdb.IntegrityOptions{
+			Hosts:    listParam(r, "host"),
+			Resolved: resolved,
+			Limit:    limit,
+		})
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, events)
+	})
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/render"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// List returns the subdirectories and files of an indexed directory on the
+// server.
+func (c *Client) List(dir hsdb.TreeRef) (*hsdb.Listing, error) {
+	params := url.Values{}
+	params.Set("dir", dir.String())
+
+	var listing hsdb.Listing
+	if err := c.get("/ls", params, &listing); err != nil {
+		return nil, err
+	}
+
+	return &listing, nil
+}
+
+func lsHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dir, err := hsdb.ParseTreeRef(r.URL.Query().Get("dir"))
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		db := dbs.reader(r)
+
+		listing, err := hsdb.List(db, dir)
+		if errors.Is(err, hsdb.ErrDirNotFound) {
+			statusJSON(http.StatusNotFound, err, w, r)
+			return
+		}
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, listing)
+	})
+}
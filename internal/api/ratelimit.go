@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiterIdle is how long the limiter of a client is kept after its last
+// request.
+const limiterIdle = 10 * time.Minute
+
+type clientLimiter struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// rateLimit allows each client perSecond requests, with bursts of up to
+// burst requests. Clients are identified by their address, or by their API
+// token once authenticate has validated it. Requests over the limit get a
+// 429 response with a Retry-After header.
+func rateLimit(perSecond float64, burst int) func(http.Handler) http.Handler {
+	burst = max(burst, 1)
+
+	var mu sync.Mutex
+	clients := map[string]*clientLimiter{}
+	lastSweep := time.Now()
+
+	allow := func(key string) (bool, time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		if now.Sub(lastSweep) > limiterIdle {
+			for k, c := range clients {
+				if now.Sub(c.seen) > limiterIdle {
+					delete(clients, k)
+				}
+			}
+			lastSweep = now
+		}
+
+		c, ok := clients[key]
+		if !ok {
+			c = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(perSecond), burst)}
+			clients[key] = c
+		}
+		c.seen = now
+
+		res := c.limiter.ReserveN(now, 1)
+		if delay := res.DelayFrom(now); delay > 0 {
+			res.CancelAt(now)
+			return false, delay
+		}
+		return true, 0
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := clientAddr(r)
+			if name := tokenName(r.Context()); name != "" {
+				key = "token:" + name
+			}
+
+			if ok, delay := allow(key); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+				statusJSON(http.StatusTooManyRequests, errors.New("rate limit exceeded"), w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientAddr returns the IP address of the client sending r.
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// defaultSince is the period covered by /recent and /timeline when no
+// since parameter is given.
+const defaultSince = "7d"
+
+// Recent returns the files added, changed or removed on the server since
+// opts.Since.
+func (c *Client) Recent(opts hsdb.RecentOptions) ([]*hsdb.Change, error) {
+	params := recentParams(opts)
+	params.Set("limit", strconv.Itoa(opts.Limit))
+
+	var changes []*hsdb.Change
+	if err := c.get("/recent", params, &changes); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// Timeline returns per day and host change counts from the server.
+func (c *Client) Timeline(opts hsdb.RecentOptions) ([]*hsdb.TimelineDay, error) {
+	var days []*hsdb.TimelineDay
+	if err := c.get("/timeline", recentParams(opts), &days); err != nil {
+		return nil, err
+	}
+
+	return days, nil
+}
+
+func recentParams(opts hsdb.RecentOptions) url.Values {
+	params := url.Values{}
+	params.Set("since", opts.Since.Format(time.RFC3339))
+	params.Set("host", strings.Join(opts.Hosts, ","))
+	params.Set("by", opts.By)
+	return params
+}
+
+// recentOptions parses the since, host, by and limit parameters shared by
+// /recent and /timeline.
+func recentOptions(r *http.Request, defaultLimit int) (hsdb.RecentOptions, error) {
+	since := r.URL.Query().Get("since")
+	if since == "" {
+		since = defaultSince
+	}
+	t, err := hsdb.ParseSince(since, time.Now())
+	if err != nil {
+		return hsdb.RecentOptions{}, err
+	}
+
+	limit, err := limitParam(r, defaultLimit)
+	if err != nil {
+		return hsdb.RecentOptions{}, err
+	}
+
+	return hsdb.RecentOptions{
+		Since: t,
+		Hosts: listParam(r, "host"),
+		By:    r.URL.Query().Get("by"),
+		Limit: limit,
+	}, nil
+}
+
+func recentHandler(dbs *databases, limit int) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts, err := recentOptions(r, limit)
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		db := dbs.reader(r)
+
+		changes, err := hsdb.Recent(db, opts)
+		if errors.Is(err, hsdb.ErrInvalidBy) {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, changes)
+	})
+}
+
+func timelineHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts, err := recentOptions(r, 0)
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		db := dbs.reader(r)
+
+		days, err := hsdb.Timeline(db, opts)
+		if errors.Is(err, hsdb.ErrInvalidBy) {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, days)
+	})
+}
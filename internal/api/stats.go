@@ -0,0 +1,131 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/render"
+	"github.com/rubiojr/hashup/cmd/hs/types"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// Hosts returns the hosts indexed on the server.
+func (c *Client) Hosts() ([]*hsdb.HostStat, error) {
+	var hosts []*hsdb.HostStat
+	if err := c.get("/hosts", url.Values{}, &hosts); err != nil {
+		return nil, err
+	}
+
+	return hosts, nil
+}
+
+// ExtensionStats returns the file counts and sizes of the limit top
+// extensions on the server, optionally restricted to host. See
+// db.FileStats for the orderBy values.
+func (c *Client) ExtensionStats(orderBy string, descending bool, host string, limit int) (*hsdb.Stats, error) {
+	params := url.Values{}
+	params.Set("order_by", orderBy)
+	params.Set("descending", strconv.FormatBool(descending))
+	params.Set("host", host)
+	params.Set("limit", strconv.Itoa(limit))
+
+	var stats hsdb.Stats
+	if err := c.get("/stats/extensions", params, &stats); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// LargeFiles returns up to limit files bigger than threshold bytes on the
+// server, largest first.
+func (c *Client) LargeFiles(threshold int64, limit int) ([]*types.FileResult, error) {
+	params := url.Values{}
+	params.Set("threshold", strconv.FormatInt(threshold, 10))
+	params.Set("limit", strconv.Itoa(limit))
+
+	var files []*types.FileResult
+	if err := c.get("/files/large", params, &files); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+func hostsHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db := dbs.reader(r)
+
+		hosts, err := hsdb.Hosts(db)
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, hosts)
+	})
+}
+
+func extensionStatsHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, err := limitParam(r, 10)
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		descending := true
+		if v := r.URL.Query().Get("descending"); v != "" {
+			descending, err = strconv.ParseBool(v)
+			if err != nil {
+				statusJSON(http.StatusBadRequest, errInvalidParam("descending"), w, r)
+				return
+			}
+		}
+
+		db := dbs.reader(r)
+
+		host := r.URL.Query().Get("host")
+		stats, err := hsdb.FileStats(db, r.URL.Query().Get("order_by"), descending, host)
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+
+		render.JSON(w, r, stats.Summary(host, limit))
+	})
+}
+
+func largeFilesHandler(dbs *databases, defaultLimit int) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		threshold := int64(1000000000) // 1GB, like hs large-files
+		if v := r.URL.Query().Get("threshold"); v != "" {
+			var err error
+			threshold, err = strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				statusJSON(http.StatusBadRequest, errInvalidParam("threshold"), w, r)
+				return
+			}
+		}
+
+		limit, err := limitParam(r, defaultLimit)
+		if err != nil {
+			statusJSON(http.StatusBadRequest, err, w, r)
+			return
+		}
+
+		db := dbs.reader(r)
+
+		files, err := hsdb.LargeFiles(db, threshold, limit)
+		if err != nil {
+			statusJSON(http.StatusInternalServerError, err, w, r)
+			return
+		}
+		if files == nil {
+			files = []*types.FileResult{}
+		}
+
+		render.JSON(w, r, files)
+	})
+}
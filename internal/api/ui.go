@@ -0,0 +1,151 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/log"
+	"github.com/rubiojr/hashup/internal/templates"
+)
+
+// uiCookie keeps the API token of web search page sessions, browsers can't
+// send it in a header.
+const uiCookie = "hashup_token"
+
+// authenticateUI is authenticate for the web search page. Requests without
+// a valid token, in a header or the session cookie, get the login form.
+// htmx requests are redirected to it instead, htmx doesn't swap error
+// responses.
+func authenticateUI(dbs *databases) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := requestToken(r)
+			if c, err := r.Cookie(uiCookie); token == "" && err == nil {
+				token = c.Value
+			}
+
+			name, err := checkToken(dbs, r, token)
+			if err == nil {
+				next.ServeHTTP(w, r.WithContext(withTokenName(r.Context(), name)))
+				return
+			}
+			if !errors.Is(err, hsdb.ErrInvalidToken) {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if r.Header.Get("HX-Request") != "" {
+				w.Header().Set("HX-Redirect", "/")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			renderLogin(w, r, http.StatusUnauthorized, "")
+		})
+	}
+}
+
+// uiLoginHandler checks the token posted by the login form and keeps it in
+// a session cookie.
+func uiLoginHandler(dbs *databases) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.PostFormValue("token")
+		_, err := checkToken(dbs, r, token)
+		if errors.Is(err, hsdb.ErrInvalidToken) {
+			renderLogin(w, r, http.StatusUnauthorized, "Invalid API token")
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     uiCookie,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteStrictMode,
+		})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+}
+
+func renderLogin(w http.ResponseWriter, r *http.Request, code int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	templates.Login(message).Render(r.Context(), w)
+}
+
+func uiIndexHandler() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		templates.Index().Render(r.Context(), w)
+	})
+}
+
+// uiResultsHandler renders search result pages for the web search page.
+// Facets are computed for the query alone so that selecting one host or
+// extension keeps the others visible. Errors are rendered in place of the
+// results with a 200 status, htmx doesn't swap error responses.
+func uiResultsHandler(dbs *databases, limit int) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		params := r.URL.Query()
+		v := &templates.SearchView{
+			Query:      params.Get("q"),
+			Hosts:      params["host"],
+			Extensions: params["ext"],
+		}
+		if v.Query == "" {
+			templates.Facets(&templates.SearchView{Facets: &hsdb.Facets{}}).Render(r.Context(), w)
+			return
+		}
+
+		offset, err := intParam(r, "offset", 0)
+		if err != nil {
+			templates.SearchError(err).Render(r.Context(), w)
+			return
+		}
+		v.Offset = offset
+
+		db := dbs.reader(r)
+
+		cursor := params.Get("cursor")
+		v.Page, err = hsdb.SearchFiles(db, v.Query, hsdb.SearchOptions{
+			Extensions: v.Extensions,
+			Hosts:      v.Hosts,
+			Cursor:     cursor,
+			Count:      cursor == "",
+			Limit:      limit,
+		})
+		if err == nil && cursor == "" {
+			v.Facets, err = hsdb.SearchFacets(db, v.Query, hsdb.SearchOptions{})
+		}
+		if err != nil {
+			var qerr *hsdb.QueryError
+			if !errors.As(err, &qerr) && !errors.Is(err, hsdb.ErrInvalidCursor) {
+				log.Errorf("search failed: %v", err)
+			}
+			templates.SearchError(err).Render(r.Context(), w)
+			return
+		}
+
+		if v.Page.NextCursor != "" {
+			next := url.Values{
+				"q":      {v.Query},
+				"host":   v.Hosts,
+				"ext":    v.Extensions,
+				"cursor": {v.Page.NextCursor},
+				"offset": {strconv.Itoa(offset + len(v.Page.Results))},
+			}
+			v.NextURL = "/ui/results?" + next.Encode()
+		}
+
+		templates.SearchResults(v).Render(r.Context(), w)
+	})
+}
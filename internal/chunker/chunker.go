@@ -0,0 +1,197 @@
+// Package chunker splits files into content-defined chunks with FastCDC.
+//
+// Chunk boundaries depend on the content around them rather than on their
+// offset, so inserting or removing bytes in a file only changes the chunks
+// around the edit. Files sharing most of their chunks are near-duplicates,
+// and a copy can be rebuilt from the chunks a host already has.
+//
+// The average chunk size grows with the file so that manifests stay below
+// MaxChunks entries, chunks are only comparable between manifests with the
+// same average size.
+package chunker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/rubiojr/hashup/internal/types"
+)
+
+const (
+	// MinAvgSize is the smallest average chunk size.
+	MinAvgSize = 64 << 10
+	// MaxAvgSize is the largest average chunk size, files above
+	// MaxAvgSize*MaxChunks get longer manifests.
+	MaxAvgSize = 16 << 20
+	// MaxChunks is the number of chunks files are split into at most,
+	// keeping manifests small enough for a single message.
+	MaxChunks = 8192
+)
+
+// gear maps bytes to the random values rolled into the fingerprint. The
+// table is generated from a fixed seed: changing it changes every chunk
+// boundary and invalidates stored manifests.
+var gear [256]uint64
+
+func init() {
+	// splitmix64
+	seed := uint64(0x6861736875702121)
+	for i := range gear {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+}
+
+// AvgSize returns the average chunk size used for files of size bytes.
+func AvgSize(size int64) int {
+	avg := MinAvgSize
+	for avg < MaxAvgSize && (size+int64(avg)-1)/int64(avg) > MaxChunks {
+		avg <<= 1
+	}
+	return avg
+}
+
+// Chunker splits a stream into chunks.
+type Chunker struct {
+	r   io.Reader
+	min int
+	avg int
+	max int
+	// Boundaries are harder to find before avg bytes (maskS) and easier
+	// after (maskL), normalizing chunk sizes around avg.
+	maskS uint64
+	maskL uint64
+
+	buf   []byte
+	start int
+	end   int
+	eof   bool
+}
+
+// New returns a chunker splitting r into chunks of avg bytes on average,
+// between avg/4 and avg*4. avg must be a power of two.
+func New(r io.Reader, avg int) (*Chunker, error) {
+	if avg < 64 || avg&(avg-1) != 0 {
+		return nil, fmt.Errorf("invalid average chunk size %d", avg)
+	}
+	n := bits.TrailingZeros(uint(avg))
+	return &Chunker{
+		r:     r,
+		min:   avg / 4,
+		avg:   avg,
+		max:   avg * 4,
+		maskS: mask(n + 2),
+		maskL: mask(n - 2),
+		buf:   make([]byte, avg*4),
+	}, nil
+}
+
+// mask returns a mask of the n most significant bits, the ones depending
+// on the most bytes of the fingerprint window.
+func mask(n int) uint64 {
+	return ^uint64(0) << (64 - n)
+}
+
+// Next returns the next chunk, or io.EOF once the stream is consumed. The
+// chunk is only valid until the next call.
+func (c *Chunker) Next() ([]byte, error) {
+	if c.end-c.start < c.max && !c.eof {
+		if err := c.fill(); err != nil {
+			return nil, err
+		}
+	}
+	if c.start == c.end {
+		return nil, io.EOF
+	}
+
+	n := c.cut(c.buf[c.start:c.end])
+	chunk := c.buf[c.start : c.start+n]
+	c.start += n
+	return chunk, nil
+}
+
+func (c *Chunker) fill() error {
+	n := copy(c.buf, c.buf[c.start:c.end])
+	c.start, c.end = 0, n
+	for c.end < len(c.buf) {
+		n, err := c.r.Read(c.buf[c.end:])
+		c.end += n
+		if err == io.EOF {
+			c.eof = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cut returns the length of the chunk at the start of data.
+func (c *Chunker) cut(data []byte) int {
+	if len(data) <= c.min {
+		return len(data)
+	}
+	n := min(len(data), c.max)
+	normal := min(n, c.avg)
+
+	var fp uint64
+	i := c.min
+	for ; i < normal; i++ {
+		fp = (fp << 1) + gear[data[i]]
+		if fp&c.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fp = (fp << 1) + gear[data[i]]
+		if fp&c.maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
+
+// Manifest splits r, size bytes long, and returns the hashes of its chunks.
+func Manifest(r io.Reader, size int64) (*types.ChunkManifest, error) {
+	avg := AvgSize(size)
+	c, err := New(r, avg)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &types.ChunkManifest{AvgSize: avg}
+	for {
+		chunk, err := c.Next()
+		if errors.Is(err, io.EOF) {
+			return m, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.Hashes = append(m.Hashes, xxhash.Sum64(chunk))
+		m.Sizes = append(m.Sizes, uint32(len(chunk)))
+	}
+}
+
+// ManifestFile returns the chunk manifest of the file at path.
+func ManifestFile(path string) (*types.ChunkManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Manifest(f, info.Size())
+}
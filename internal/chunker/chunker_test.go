@@ -0,0 +1,81 @@
+package chunker
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomData(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+func TestChunker(t *testing.T) {
+	data := randomData(4 << 20)
+	c, err := New(bytes.NewReader(data), MinAvgSize)
+	require.NoError(t, err)
+
+	var total int
+	var chunks int
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, data[total:total+len(chunk)], chunk)
+		assert.LessOrEqual(t, len(chunk), MinAvgSize*4)
+		total += len(chunk)
+		chunks++
+		if total < len(data) {
+			assert.Greater(t, len(chunk), MinAvgSize/4)
+		}
+	}
+	assert.Equal(t, len(data), total)
+	assert.InDelta(t, len(data)/MinAvgSize, chunks, 20)
+
+	_, err = New(bytes.NewReader(data), 1000)
+	assert.Error(t, err)
+}
+
+func TestManifest(t *testing.T) {
+	data := randomData(4 << 20)
+	m, err := Manifest(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	assert.Equal(t, MinAvgSize, m.AvgSize)
+	require.Len(t, m.Sizes, len(m.Hashes))
+
+	again, err := Manifest(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	assert.Equal(t, m, again)
+
+	// Inserting bytes only changes the chunks around the edit
+	edited := append(append(append([]byte{}, data[:1<<20]...), "inserted"...), data[1<<20:]...)
+	e, err := Manifest(bytes.NewReader(edited), int64(len(edited)))
+	require.NoError(t, err)
+
+	known := map[uint64]bool{}
+	for _, h := range m.Hashes {
+		known[h] = true
+	}
+	var shared int
+	for _, h := range e.Hashes {
+		if known[h] {
+			shared++
+		}
+	}
+	assert.GreaterOrEqual(t, shared, len(m.Hashes)-2)
+}
+
+func TestAvgSize(t *testing.T) {
+	assert.Equal(t, MinAvgSize, AvgSize(0))
+	assert.Equal(t, MinAvgSize, AvgSize(MinAvgSize*MaxChunks))
+	assert.Equal(t, MinAvgSize*2, AvgSize(MinAvgSize*MaxChunks+1))
+	assert.Equal(t, MaxAvgSize, AvgSize(1<<50))
+}
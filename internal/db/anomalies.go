@@ -0,0 +1,241 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Anomaly kinds.
+const (
+	// AnomalyMassChange is raised when a host changes the content of many
+	// more indexed files than usual.
+	AnomalyMassChange = "mass_change"
+	// AnomalyNewExtensions is raised when many new files of a host have
+	// extensions the host never had before, like those renamed by
+	// ransomware.
+	AnomalyNewExtensions = "new_extensions"
+)
+
+// QuietAll is the host of quiet periods silencing every host.
+const QuietAll = "*"
+
+// ErrNotQuiet is returned when removing a quiet period that doesn't exist.
+var ErrNotQuiet = errors.New("host is not quiet")
+
+// AnomalyAlert is unusual activity of a host in a detection window.
+type AnomalyAlert struct {
+	ID          int64     `json:"id"`
+	Host        string    `json:"host"`
+	Kind        string    `json:"kind"`
+	WindowStart time.Time `json:"window_start"`
+	// Files is the number of changed files, or of files with new
+	// extensions, when the alert was raised.
+	Files int64 `json:"files"`
+	// Baseline is the usual number of changed files per window.
+	Baseline  float64 `json:"baseline"`
+	Threshold int64   `json:"threshold"`
+	// Extensions are the new extensions of new_extensions alerts.
+	Extensions []string `json:"extensions,omitempty"`
+	// Paths is a sample of the affected files.
+	Paths   []string  `json:"paths"`
+	Created time.Time `json:"created"`
+}
+
+// AnomalyOptions selects the alerts returned by AnomalyAlerts.
+type AnomalyOptions struct {
+	Hosts []string
+	Since time.Time
+	Limit int
+}
+
+// QuietPeriod silences the anomaly alerts of a host until a given time.
+type QuietPeriod struct {
+	Host    string    `json:"host"`
+	Until   time.Time `json:"until"`
+	Reason  string    `json:"reason,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// CreateAnomalyAlert stores a, setting its ID and creation time.
+func CreateAnomalyAlert(db *sql.DB, a *AnomalyAlert) error {
+	err := db.QueryRow(`
+		INSERT INTO anomaly_alerts (host, kind, window_start, files, baseline, threshold, extensions, paths)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id, created
+	`, a.Host, a.Kind, a.WindowStart.UTC().Format(dateLayout), a.Files, a.Baseline, a.Threshold,
+		strings.Join(a.Extensions, ","), strings.Join(a.Paths, "\n"),
+	).Scan(&a.ID, &a.Created)
+	if err != nil {
+		return fmt.Errorf("failed to save anomaly alert: %v", err)
+	}
+	return nil
+}
+
+// AnomalyAlerts returns the alerts matching opts, newest first.
+func AnomalyAlerts(db Querier, opts AnomalyOptions) ([]*AnomalyAlert, error) {
+	where := []string{"created >= ?"}
+	args := []any{opts.Since.UTC().Format(dateLayout)}
+	if hosts := nonEmpty(opts.Hosts); len(hosts) > 0 {
+		where = append(where, "host IN ("+placeholders(len(hosts))+")")
+		for _, h := range hosts {
+			args = append(args, h)
+		}
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := db.Query(`
+		SELECT id, host, kind, window_start, files, baseline, threshold, extensions, paths, created
+		FROM anomaly_alerts
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC
+		LIMIT `+fmt.Sprint(limit),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query anomaly alerts: %v", err)
+	}
+	defer rows.Close()
+
+	alerts := []*AnomalyAlert{}
+	for rows.Next() {
+		a := &AnomalyAlert{}
+		var exts, paths string
+		err := rows.Scan(&a.ID, &a.Host, &a.Kind, &a.WindowStart, &a.Files, &a.Baseline, &a.Threshold, &exts, &paths, &a.Created)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		a.Extensions = nonEmpty(strings.Split(exts, ","))
+		a.Paths = []string{}
+		if paths != "" {
+			a.Paths = strings.Split(paths, "\n")
+		}
+		alerts = append(alerts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return alerts, nil
+}
+
+// SetQuiet silences the anomaly alerts of q.Host, QuietAll for every host,
+// until q.Until, replacing its previous quiet period.
+func SetQuiet(db *sql.DB, q *QuietPeriod) error {
+	q.Host = strings.TrimSpace(q.Host)
+	if q.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+
+	err := db.QueryRow(`
+		INSERT INTO anomaly_quiet (host, until, reason) VALUES (?, ?, ?)
+		ON CONFLICT (host) DO UPDATE SET until = excluded.until, reason = excluded.reason, created = CURRENT_TIMESTAMP
+		RETURNING created
+	`, q.Host, q.Until.UTC().Format(dateLayout), q.Reason).Scan(&q.Created)
+	if err != nil {
+		return fmt.Errorf("failed to save quiet period: %v", err)
+	}
+	return nil
+}
+
+// DeleteQuiet ends the quiet period of host.
+func DeleteQuiet(db *sql.DB, host string) error {
+	res, err := db.Exec("DELETE FROM anomaly_quiet WHERE host = ?", host)
+	if err != nil {
+		return fmt.Errorf("failed to delete quiet period: %v", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrNotQuiet, host)
+	}
+	return nil
+}
+
+// QuietPeriods returns the quiet periods that have not ended, sorted by
+// host.
+func QuietPeriods(db Querier) ([]*QuietPeriod, error) {
+	rows, err := db.Query(`
+		SELECT host, until, reason, created FROM anomaly_quiet
+		WHERE until > ?
+		ORDER BY host
+	`, time.Now().UTC().Format(dateLayout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quiet periods: %v", err)
+	}
+	defer rows.Close()
+
+	periods := []*QuietPeriod{}
+	for rows.Next() {
+		q := &QuietPeriod{}
+		if err := rows.Scan(&q.Host, &q.Until, &q.Reason, &q.Created); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		periods = append(periods, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return periods, nil
+}
+
+// HostBaseline returns the usual number of changed files per window of host
+// and the number of windows it was computed from, zero for hosts without a
+// baseline yet.
+func HostBaseline(db Querier, host string) (float64, int, error) {
+	var baseline float64
+	var windows int
+	err := db.QueryRow("SELECT baseline, windows FROM anomaly_baselines WHERE host = ?", host).Scan(&baseline, &windows)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query baseline: %v", err)
+	}
+	return baseline, windows, nil
+}
+
+// SetHostBaseline saves the baseline of host, see HostBaseline.
+func SetHostBaseline(db *sql.DB, host string, baseline float64, windows int) error {
+	_, err := db.Exec(`
+		INSERT INTO anomaly_baselines (host, baseline, windows) VALUES (?, ?, ?)
+		ON CONFLICT (host) DO UPDATE SET baseline = excluded.baseline, windows = excluded.windows, updated = CURRENT_TIMESTAMP
+	`, host, baseline, windows)
+	if err != nil {
+		return fmt.Errorf("failed to save baseline: %v", err)
+	}
+	return nil
+}
+
+// HostExtensions returns the extensions of the files indexed on host
+// before the file_info row with id beforeID.
+func HostExtensions(db Querier, host string, beforeID int64) ([]string, error) {
+	rows, err := db.Query("SELECT DISTINCT extension FROM file_info WHERE host = ? AND id < ?", host, beforeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query extensions: %v", err)
+	}
+	defer rows.Close()
+
+	var exts []string
+	for rows.Next() {
+		var ext string
+		if err := rows.Scan(&ext); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		exts = append(exts, ext)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return exts, nil
+}
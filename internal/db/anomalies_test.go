@@ -0,0 +1,104 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnomalyAlerts(t *testing.T) {
+	db := testDB(t)
+
+	window := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	a := &AnomalyAlert{
+		Host: "nas", Kind: AnomalyMassChange, WindowStart: window, Files: 600, Baseline: 12.5, Threshold: 500,
+		Paths: []string{"/docs/a.doc", "/docs/b.doc"},
+	}
+	require.NoError(t, CreateAnomalyAlert(db, a))
+	assert.NotZero(t, a.ID)
+	assert.False(t, a.Created.IsZero())
+
+	require.NoError(t, CreateAnomalyAlert(db, &AnomalyAlert{
+		Host: "laptop", Kind: AnomalyNewExtensions, WindowStart: window, Files: 100, Threshold: 100,
+		Extensions: []string{"crypt", "locked"}, Paths: []string{"/home/me/a.locked"},
+	}))
+
+	alerts, err := AnomalyAlerts(db, AnomalyOptions{})
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	assert.Equal(t, "laptop", alerts[0].Host)
+	assert.Equal(t, []string{"crypt", "locked"}, alerts[0].Extensions)
+	assert.Equal(t, []string{"/docs/a.doc", "/docs/b.doc"}, alerts[1].Paths)
+	assert.Empty(t, alerts[1].Extensions)
+	assert.Equal(t, 12.5, alerts[1].Baseline)
+	assert.Equal(t, window, alerts[1].WindowStart)
+
+	alerts, err = AnomalyAlerts(db, AnomalyOptions{Hosts: []string{"nas"}})
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, a.ID, alerts[0].ID)
+
+	alerts, err = AnomalyAlerts(db, AnomalyOptions{Since: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+}
+
+func TestQuietPeriods(t *testing.T) {
+	db := testDB(t)
+
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	require.NoError(t, SetQuiet(db, &QuietPeriod{Host: "nas", Until: until, Reason: "restore"}))
+	require.NoError(t, SetQuiet(db, &QuietPeriod{Host: "laptop", Until: time.Now().Add(-time.Hour)}))
+	assert.Error(t, SetQuiet(db, &QuietPeriod{Host: " "}))
+
+	periods, err := QuietPeriods(db)
+	require.NoError(t, err)
+	require.Len(t, periods, 1)
+	assert.Equal(t, "nas", periods[0].Host)
+	assert.Equal(t, until, periods[0].Until)
+	assert.Equal(t, "restore", periods[0].Reason)
+
+	// Setting it again replaces the period
+	require.NoError(t, SetQuiet(db, &QuietPeriod{Host: "nas", Until: until.Add(time.Hour)}))
+	periods, err = QuietPeriods(db)
+	require.NoError(t, err)
+	require.Len(t, periods, 1)
+	assert.Equal(t, until.Add(time.Hour), periods[0].Until)
+	assert.Empty(t, periods[0].Reason)
+
+	require.NoError(t, DeleteQuiet(db, "nas"))
+	assert.ErrorIs(t, DeleteQuiet(db, "nas"), ErrNotQuiet)
+}
+
+func TestHostBaseline(t *testing.T) {
+	db := testDB(t)
+
+	baseline, windows, err := HostBaseline(db, "nas")
+	require.NoError(t, err)
+	assert.Zero(t, baseline)
+	assert.Zero(t, windows)
+
+	require.NoError(t, SetHostBaseline(db, "nas", 2, 1))
+	require.NoError(t, SetHostBaseline(db, "nas", 2.5, 2))
+	baseline, windows, err = HostBaseline(db, "nas")
+	require.NoError(t, err)
+	assert.Equal(t, 2.5, baseline)
+	assert.Equal(t, 2, windows)
+}
+
+func TestHostExtensions(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/docs/report.pdf", "nas", "pdf", 1, "00ab12cd34ef5678", "2024-03-01 10:00:00")
+	insertFile(t, db, "/docs/notes.txt", "nas", "txt", 2, "1111111111111111", "2024-03-01 10:00:00")
+	insertFile(t, db, "/photos/cat.jpg", "laptop", "jpg", 3, "ffee000011112222", "2024-03-01 10:00:00")
+
+	exts, err := HostExtensions(db, "nas", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pdf"}, exts)
+
+	exts, err = HostExtensions(db, "nas", 100)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"pdf", "txt"}, exts)
+}
@@ -0,0 +1,276 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	hstypes "github.com/rubiojr/hashup/cmd/hs/types"
+	"github.com/rubiojr/hashup/internal/types"
+)
+
+// ErrNoManifest is returned when no chunk manifest is stored for a content
+// hash, like for files scanned without chunking.
+var ErrNoManifest = errors.New("no chunk manifest")
+
+// commonChunkLimit is how many contents a chunk may be shared by and still
+// count towards similarity. More common chunks, like runs of zeros in disk
+// images, say little about two files and make comparisons quadratic.
+const commonChunkLimit = 50
+
+// SaveManifest stores the chunk manifest of the content hash, size bytes
+// long, unless one is already stored. It reports whether it was stored.
+func SaveManifest(db *sql.DB, hash string, size int64, m *types.ChunkManifest) (bool, error) {
+	if len(m.Hashes) != len(m.Sizes) {
+		return false, fmt.Errorf("invalid chunk manifest for %s: %d hashes, %d sizes", hash, len(m.Hashes), len(m.Sizes))
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		INSERT INTO chunk_manifests (file_hash, file_size, avg_size, chunks)
+		VALUES (?, ?, ?, ?) ON CONFLICT (file_hash) DO NOTHING`,
+		hash, size, m.AvgSize, len(m.Hashes),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to save chunk manifest: %v", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO file_chunks (file_hash, seq, chunk_hash, chunk_offset, chunk_size)
+		VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return false, fmt.Errorf("failed to prepare insert chunk statement: %v", err)
+	}
+	defer stmt.Close()
+
+	var offset int64
+	for i, h := range m.Hashes {
+		if _, err := stmt.Exec(hash, i, fmt.Sprintf("%016x", h), offset, m.Sizes[i]); err != nil {
+			return false, fmt.Errorf("failed to save chunk: %v", err)
+		}
+		offset += int64(m.Sizes[i])
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return true, nil
+}
+
+// Manifest returns the chunk manifest of the content hash.
+func Manifest(db Querier, hash string) (*types.ChunkManifest, error) {
+	m := &types.ChunkManifest{}
+	err := db.QueryRow("SELECT avg_size FROM chunk_manifests WHERE file_hash = ?", hash).Scan(&m.AvgSize)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrNoManifest, hash)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunk manifest: %v", err)
+	}
+
+	rows, err := db.Query("SELECT chunk_hash, chunk_size FROM file_chunks WHERE file_hash = ? ORDER BY seq", hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunks: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h string
+		var size uint32
+		if err := rows.Scan(&h, &size); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		v, err := strconv.ParseUint(h, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk hash %q: %v", h, err)
+		}
+		m.Hashes = append(m.Hashes, v)
+		m.Sizes = append(m.Sizes, size)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+	return m, nil
+}
+
+// ChunkSource is an indexed file of a host holding a chunk of another.
+type ChunkSource struct {
+	// Seq is the position of the chunk in the manifest.
+	Seq    int    `json:"seq"`
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"`
+}
+
+// ChunkSources returns, for the chunks of the content hash, the files of
+// host whose latest indexed version holds the same chunks, up to three per
+// chunk and latest files first. Files may have changed since they were
+// indexed, chunks read from them must be verified.
+func ChunkSources(db Querier, hash, host string) ([]*ChunkSource, error) {
+	rows, err := db.Query(`
+		SELECT t.seq, fi.file_path, s.chunk_offset
+		FROM file_chunks t
+		JOIN file_chunks s ON s.chunk_hash = t.chunk_hash AND s.chunk_size = t.chunk_size
+		JOIN file_info fi ON fi.file_hash = s.file_hash
+		WHERE t.file_hash = ? AND fi.host = ?`+latestFiles+`
+		ORDER BY t.seq, fi.id DESC`,
+		hash, host,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunk sources: %v", err)
+	}
+	defer rows.Close()
+
+	var sources []*ChunkSource
+	perSeq := map[int]int{}
+	for rows.Next() {
+		s := &ChunkSource{}
+		if err := rows.Scan(&s.Seq, &s.Path, &s.Offset); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if perSeq[s.Seq] >= 3 {
+			continue
+		}
+		perSeq[s.Seq]++
+		sources = append(sources, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+	return sources, nil
+}
+
+// NearDupeOptions restricts which files are compared when looking for
+// near-duplicates.
+type NearDupeOptions struct {
+	Hosts      []string
+	MinSize    int64
+	PathPrefix string
+	// Types are file categories (see ExpandTypes) or extensions.
+	Types []string
+	// MinSimilarity is the fraction of the larger content found in the
+	// other, 0.5 by default.
+	MinSimilarity float64
+	Limit         int
+}
+
+// NearDupe is a pair of different contents sharing chunks, like edited
+// videos or snapshots of a disk image.
+type NearDupe struct {
+	Hash      string `json:"hash"`
+	Size      int64  `json:"size"`
+	OtherHash string `json:"other_hash"`
+	OtherSize int64  `json:"other_size"`
+	// Shared is the size of the chunks found in both contents.
+	Shared     int64   `json:"shared"`
+	Similarity float64 `json:"similarity"`
+	// Files are the copies of both contents, see FileResult.FileHash.
+	Files []*hstypes.FileResult `json:"files"`
+}
+
+// NearDupes returns pairs of contents with chunk manifests sharing most of
+// their chunks, most shared bytes first. Only contents of the latest version
+// of some file are compared.
+func NearDupes(db Querier, opts NearDupeOptions) ([]*NearDupe, error) {
+	q := &Query{}
+	q.AddHosts(opts.Hosts...)
+	q.AddPathPrefix(opts.PathPrefix)
+	q.AddExtensions(ExpandTypes(opts.Types)...)
+	where, args := q.Where()
+
+	minSimilarity := opts.MinSimilarity
+	if minSimilarity <= 0 {
+		minSimilarity = 0.5
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	queryArgs := append([]any{opts.MinSize}, args...)
+	queryArgs = append(queryArgs, commonChunkLimit, minSimilarity, limit)
+	rows, err := db.Query(`
+		WITH chunks AS (
+			SELECT DISTINCT fc.file_hash, fc.chunk_hash, fc.chunk_size, cm.avg_size
+			FROM file_chunks fc
+			JOIN chunk_manifests cm ON cm.file_hash = fc.file_hash
+			WHERE cm.file_size >= ? AND EXISTS (
+				SELECT 1 FROM file_info fi WHERE fi.file_hash = fc.file_hash`+latestFiles+where+`
+			)
+		), shared AS (
+			SELECT chunk_hash FROM chunks
+			GROUP BY chunk_hash
+			HAVING COUNT(*) BETWEEN 2 AND ?
+		)
+		SELECT a.file_hash, ma.file_size, b.file_hash, mb.file_size, SUM(a.chunk_size) AS shared_size
+		FROM shared s
+		JOIN chunks a ON a.chunk_hash = s.chunk_hash
+		JOIN chunks b ON b.chunk_hash = s.chunk_hash AND b.chunk_size = a.chunk_size
+			AND b.avg_size = a.avg_size AND b.file_hash > a.file_hash
+		JOIN chunk_manifests ma ON ma.file_hash = a.file_hash
+		JOIN chunk_manifests mb ON mb.file_hash = b.file_hash
+		GROUP BY a.file_hash, b.file_hash
+		HAVING CAST(shared_size AS REAL) / MAX(ma.file_size, mb.file_size) >= ?
+		ORDER BY shared_size DESC, a.file_hash, b.file_hash
+		LIMIT ?`,
+		queryArgs...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query near-duplicates: %v", err)
+	}
+	defer rows.Close()
+
+	var dupes []*NearDupe
+	var hashes []any
+	seen := map[string]bool{}
+	for rows.Next() {
+		d := &NearDupe{}
+		if err := rows.Scan(&d.Hash, &d.Size, &d.OtherHash, &d.OtherSize, &d.Shared); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		d.Similarity = float64(d.Shared) / float64(max(d.Size, d.OtherSize))
+		dupes = append(dupes, d)
+		for _, h := range []string{d.Hash, d.OtherHash} {
+			if !seen[h] {
+				seen[h] = true
+				hashes = append(hashes, h)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	if len(dupes) == 0 {
+		return dupes, nil
+	}
+
+	files, err := queryResults(db, `
+		SELECT fi.file_path, fi.file_size, fi.modified_date, fi.host, fi.extension, fi.file_hash
+		FROM file_info fi
+		WHERE fi.file_hash IN (`+placeholders(len(hashes))+`)`+latestFiles+where+`
+		ORDER BY fi.host, fi.file_path`,
+		append(hashes, args...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := map[string][]*hstypes.FileResult{}
+	for _, f := range files {
+		byHash[f.FileHash] = append(byHash[f.FileHash], f)
+	}
+	for _, d := range dupes {
+		d.Files = append(append(d.Files, byHash[d.Hash]...), byHash[d.OtherHash]...)
+	}
+
+	return dupes, nil
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/rubiojr/hashup/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNearDupes(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/home/me/disk.img", "laptop", "img", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/nas/backup/disk.img", "nas", "img", 2, "ffee000011112222", "2024-01-01 00:00:00")
+	insertFile(t, db, "/nas/other.img", "nas", "img", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+
+	sizes := []uint32{100, 100, 100, 100}
+	manifests := map[string]*types.ChunkManifest{
+		"00ab12cd34ef5678": {AvgSize: 64, Hashes: []uint64{1, 2, 3, 4}, Sizes: sizes},
+		"ffee000011112222": {AvgSize: 64, Hashes: []uint64{1, 2, 3, 9}, Sizes: sizes},
+		"1234567890abcdef": {AvgSize: 64, Hashes: []uint64{7, 8}, Sizes: sizes[:2]},
+	}
+	for hash, m := range manifests {
+		saved, err := SaveManifest(db, hash, 400, m)
+		require.NoError(t, err)
+		assert.True(t, saved)
+	}
+
+	saved, err := SaveManifest(db, "00ab12cd34ef5678", 400, manifests["00ab12cd34ef5678"])
+	require.NoError(t, err)
+	assert.False(t, saved)
+
+	m, err := Manifest(db, "ffee000011112222")
+	require.NoError(t, err)
+	assert.Equal(t, manifests["ffee000011112222"], m)
+	_, err = Manifest(db, "0000000000000000")
+	assert.ErrorIs(t, err, ErrNoManifest)
+
+	dupes, err := NearDupes(db, NearDupeOptions{})
+	require.NoError(t, err)
+	require.Len(t, dupes, 1)
+	assert.Equal(t, "00ab12cd34ef5678", dupes[0].Hash)
+	assert.Equal(t, "ffee000011112222", dupes[0].OtherHash)
+	assert.Equal(t, int64(300), dupes[0].Shared)
+	assert.Equal(t, 0.75, dupes[0].Similarity)
+	require.Len(t, dupes[0].Files, 2)
+	assert.Equal(t, "/home/me/disk.img", dupes[0].Files[0].FilePath)
+	assert.Equal(t, "/nas/backup/disk.img", dupes[0].Files[1].FilePath)
+
+	t.Run("filters", func(t *testing.T) {
+		dupes, err := NearDupes(db, NearDupeOptions{MinSimilarity: 0.8})
+		require.NoError(t, err)
+		assert.Empty(t, dupes)
+
+		dupes, err = NearDupes(db, NearDupeOptions{Hosts: []string{"nas"}})
+		require.NoError(t, err)
+		assert.Empty(t, dupes)
+
+		dupes, err = NearDupes(db, NearDupeOptions{MinSize: 1000})
+		require.NoError(t, err)
+		assert.Empty(t, dupes)
+	})
+
+	t.Run("chunk sources", func(t *testing.T) {
+		sources, err := ChunkSources(db, "ffee000011112222", "laptop")
+		require.NoError(t, err)
+		assert.Equal(t, []*ChunkSource{
+			{Seq: 0, Path: "/home/me/disk.img", Offset: 0},
+			{Seq: 1, Path: "/home/me/disk.img", Offset: 100},
+			{Seq: 2, Path: "/home/me/disk.img", Offset: 200},
+		}, sources)
+
+		sources, err = ChunkSources(db, "ffee000011112222", "desktop")
+		require.NoError(t, err)
+		assert.Empty(t, sources)
+	})
+}
+
+func TestNearDupesLatestVersion(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/home/me/disk.img", "laptop", "img", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/home/me/disk.img", "laptop", "img", 2, "ffee000011112222", "2024-01-02 00:00:00")
+
+	sizes := []uint32{100, 100, 100, 100}
+	for hash, m := range map[string]*types.ChunkManifest{
+		"00ab12cd34ef5678": {AvgSize: 64, Hashes: []uint64{1, 2, 3, 4}, Sizes: sizes},
+		"ffee000011112222": {AvgSize: 64, Hashes: []uint64{1, 2, 3, 9}, Sizes: sizes},
+	} {
+		_, err := SaveManifest(db, hash, 400, m)
+		require.NoError(t, err)
+	}
+
+	// The old version of the image is not a near-duplicate of the new one
+	dupes, err := NearDupes(db, NearDupeOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, dupes)
+
+	// Only chunks still in the latest version can be read from the file
+	sources, err := ChunkSources(db, "00ab12cd34ef5678", "laptop")
+	require.NoError(t, err)
+	assert.Len(t, sources, 3)
+	for _, s := range sources {
+		assert.Equal(t, "/home/me/disk.img", s.Path)
+	}
+}
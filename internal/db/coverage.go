@@ -0,0 +1,150 @@
+package db
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// CoverageOptions configures a backup coverage report.
+type CoverageOptions struct {
+	// MinCopies is the number of distinct hosts a file must be on to be
+	// considered backed up. Defaults to 2.
+	MinCopies int
+	// RequiredHosts must all hold a copy for a file to be backed up.
+	RequiredHosts []string
+	// Hosts restricts the report to files on these hosts.
+	Hosts      []string
+	PathPrefix string
+	MinSize    int64
+	// Depth aggregates files by their first Depth path components instead
+	// of by parent directory.
+	Depth int
+	Limit int
+}
+
+// CoverageDir summarizes the files in a directory that are not backed up.
+type CoverageDir struct {
+	Host  string `json:"host"`
+	Dir   string `json:"dir"`
+	Files int64  `json:"files"`
+	Size  int64  `json:"size"`
+	// MinHosts is the fewest distinct hosts holding any of the files.
+	MinHosts int `json:"min_hosts"`
+}
+
+// CoverageReport lists the directories holding files with too few copies.
+type CoverageReport struct {
+	MinCopies     int            `json:"min_copies"`
+	RequiredHosts []string       `json:"required_hosts,omitempty"`
+	Dirs          []*CoverageDir `json:"dirs"`
+	TotalFiles    int64          `json:"total_files"`
+	TotalSize     int64          `json:"total_size"`
+}
+
+// Coverage reports files whose content is present on fewer than
+// opts.MinCopies hosts, or missing from any of opts.RequiredHosts,
+// aggregated by host and directory and sorted by size. Only the latest
+// version of every file counts, a host holds the content it last indexed.
+func Coverage(db Querier, opts CoverageOptions) (*CoverageReport, error) {
+	if opts.MinCopies <= 0 {
+		opts.MinCopies = 2
+	}
+	required := nonEmpty(opts.RequiredHosts)
+
+	q := &Query{}
+	q.AddHosts(opts.Hosts...)
+	q.AddPathPrefix(opts.PathPrefix)
+	q.AddMinSize(opts.MinSize)
+	where, wargs := q.Where()
+
+	var args []any
+	backups := "0"
+	if len(required) > 0 {
+		backups = fmt.Sprintf("COUNT(DISTINCT CASE WHEN host IN (%s) THEN host END)", placeholders(len(required)))
+		for _, h := range required {
+			args = append(args, h)
+		}
+	}
+	args = append(args, opts.MinCopies, len(required))
+	args = append(args, wargs...)
+
+	rows, err := db.Query(`
+		WITH hash_hosts AS (
+			SELECT file_hash, COUNT(DISTINCT host) AS hosts, `+backups+` AS backups
+			FROM file_info fi
+			WHERE 1 = 1`+latestFiles+`
+			GROUP BY file_hash
+		)
+		SELECT fi.host, fi.file_path, fi.file_size, hh.hosts
+		FROM file_info fi
+		JOIN hash_hosts hh ON hh.file_hash = fi.file_hash
+		WHERE (hh.hosts < ? OR hh.backups < ?)`+latestFiles+where,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query coverage: %v", err)
+	}
+	defer rows.Close()
+
+	report := &CoverageReport{
+		MinCopies:     opts.MinCopies,
+		RequiredHosts: required,
+		Dirs:          []*CoverageDir{},
+	}
+	dirs := map[string]*CoverageDir{}
+	for rows.Next() {
+		var host, filePath string
+		var size int64
+		var hosts int
+		if err := rows.Scan(&host, &filePath, &size, &hosts); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		dir := coverageDir(filePath, opts.Depth)
+		key := host + "\x00" + dir
+		d, ok := dirs[key]
+		if !ok {
+			d = &CoverageDir{Host: host, Dir: dir, MinHosts: hosts}
+			dirs[key] = d
+			report.Dirs = append(report.Dirs, d)
+		}
+		d.Files++
+		d.Size += size
+		d.MinHosts = min(d.MinHosts, hosts)
+		report.TotalFiles++
+		report.TotalSize += size
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	sort.SliceStable(report.Dirs, func(i, j int) bool {
+		return report.Dirs[i].Size > report.Dirs[j].Size
+	})
+	if opts.Limit > 0 && len(report.Dirs) > opts.Limit {
+		report.Dirs = report.Dirs[:opts.Limit]
+	}
+
+	return report, nil
+}
+
+// coverageDir returns the directory a file is reported under: its parent
+// directory, or its first depth path components when depth is set.
+func coverageDir(filePath string, depth int) string {
+	dir := path.Dir(filePath)
+	if depth <= 0 {
+		return dir
+	}
+
+	parts := strings.Split(strings.TrimPrefix(dir, "/"), "/")
+	if len(parts) <= depth {
+		return dir
+	}
+	prefix := strings.Join(parts[:depth], "/")
+	if strings.HasPrefix(dir, "/") {
+		prefix = "/" + prefix
+	}
+	return prefix
+}
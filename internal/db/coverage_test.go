@@ -0,0 +1,64 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverage(t *testing.T) {
+	db := testDB(t)
+	// Only on the laptop
+	insertFile(t, db, "/home/me/Photos/2021/a.jpg", "laptop", "jpg", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/home/me/Photos/2021/b.jpg", "laptop", "jpg", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+	// On the laptop and an external disk, but not the NAS
+	insertFile(t, db, "/home/me/docs/report.pdf", "laptop", "pdf", 2, "ffee000011112222", "2024-01-01 00:00:00")
+	insertFile(t, db, "/media/usb/report.pdf", "usb", "pdf", 2, "ffee000011112222", "2024-01-01 00:00:00")
+
+	report, err := Coverage(db, CoverageOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.MinCopies)
+	assert.Equal(t, int64(2), report.TotalFiles)
+	assert.Equal(t, int64(200), report.TotalSize)
+	require.Len(t, report.Dirs, 1)
+	assert.Equal(t, &CoverageDir{Host: "laptop", Dir: "/home/me/Photos/2021", Files: 2, Size: 200, MinHosts: 1}, report.Dirs[0])
+
+	t.Run("required hosts", func(t *testing.T) {
+		report, err := Coverage(db, CoverageOptions{RequiredHosts: []string{"nas"}, Depth: 2})
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), report.TotalFiles)
+		require.Len(t, report.Dirs, 2)
+		assert.Equal(t, "/home/me", report.Dirs[0].Dir)
+		assert.Equal(t, int64(3), report.Dirs[0].Files)
+		assert.Equal(t, 1, report.Dirs[0].MinHosts)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		report, err := Coverage(db, CoverageOptions{MinCopies: 3, Hosts: []string{"usb"}})
+		require.NoError(t, err)
+		require.Len(t, report.Dirs, 1)
+		assert.Equal(t, &CoverageDir{Host: "usb", Dir: "/media/usb", Files: 1, Size: 100, MinHosts: 2}, report.Dirs[0])
+
+		report, err = Coverage(db, CoverageOptions{PathPrefix: "/home/me/docs"})
+		require.NoError(t, err)
+		assert.Empty(t, report.Dirs)
+	})
+}
+
+func TestCoverageLatestVersion(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/home/me/report.pdf", "laptop", "pdf", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/nas/report.pdf", "nas", "pdf", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	// The NAS copy is overwritten, the laptop copy is no longer backed up
+	insertFile(t, db, "/nas/report.pdf", "nas", "pdf", 2, "ffee000011112222", "2024-01-02 00:00:00")
+
+	report, err := Coverage(db, CoverageOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), report.TotalFiles)
+	require.Len(t, report.Dirs, 2)
+	for _, d := range report.Dirs {
+		assert.Equal(t, int64(1), d.Files)
+		assert.Equal(t, 1, d.MinHosts)
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"regexp"
 
 	"github.com/rubiojr/hashup/cmd/hs/types"
 	"github.com/rubiojr/hashup/internal/log"
@@ -15,7 +15,9 @@ import (
 //go:embed hashup.sql
 var Schema string
 
-// Open a SQLite database with appropriate pragmas
+// Open a SQLite database with appropriate pragmas, creating the missing
+// tables. The full-text index is left alone, it is managed by the writers of
+// file_info with EnsureFTS.
 func OpenDatabase(dbPath string) (*sql.DB, error) {
 	log.Debugf("Opening database %s", dbPath)
 	pragmas := []string{
@@ -43,6 +45,14 @@ func OpenDatabase(dbPath string) (*sql.DB, error) {
 		return db, fmt.Errorf("failed to create tables: %v", err)
 	}
 
+	if err := EnsureDirs(db); err != nil {
+		return db, err
+	}
+
+	if err := BackfillIndexed(db); err != nil {
+		return db, err
+	}
+
 	return db, nil
 }
 
@@ -51,44 +61,61 @@ func CreateTables(db *sql.DB) error {
 	return err
 }
 
-func Search(db *sql.DB, query string, extensions []string, hosts []string, limit int) ([]*types.FileResult, error) {
-	query = strings.Replace(query, " ", "%", -1)
-	sqlQuery := `
-		SELECT file_path, file_size, modified_date, host, extension, file_hash
-		FROM file_info
-		WHERE (file_path LIKE ? OR file_hash LIKE ?)
-	`
-
-	var args []any
-	args = append(args, "%"+query+"%", "%"+query+"%")
-
-	if len(extensions) > 0 {
-		placeholders := make([]string, len(extensions))
-		for i, ext := range extensions {
-			placeholders[i] = "?"
-			args = append(args, strings.TrimSpace(ext))
-		}
-		sqlQuery += fmt.Sprintf(" AND extension IN (%s)", strings.Join(placeholders, ","))
+// schemaObjects matches the names of the tables and indexes in Schema.
+var schemaObjects = regexp.MustCompile(`CREATE (?:TABLE|INDEX) IF NOT EXISTS (\w+)`)
+
+// Outdated reports whether the database lacks tables or indexes of Schema,
+// or data OpenDatabase fills in. Readers opening databases read-only open
+// them once with OpenDatabase first then, so that queries don't fail on
+// databases created by older versions.
+func Outdated(db Querier) (bool, error) {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type IN ('table', 'index')")
+	if err != nil {
+		return false, fmt.Errorf("failed to query schema: %v", err)
 	}
+	defer rows.Close()
 
-	if len(hosts) > 0 {
-		placeholders := make([]string, len(hosts))
-		for i, host := range hosts {
-			placeholders[i] = "?"
-			args = append(args, strings.TrimSpace(host))
+	existing := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, fmt.Errorf("failed to scan row: %v", err)
 		}
-		sqlQuery += fmt.Sprintf(" AND host IN (%s)", strings.Join(placeholders, ","))
+		existing[name] = true
 	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("error iterating over rows: %v", err)
+	}
+	for _, m := range schemaObjects.FindAllStringSubmatch(Schema, -1) {
+		if !existing[m[1]] {
+			return true, nil
+		}
+	}
+
+	var backfill bool
+	err = db.QueryRow("SELECT EXISTS (SELECT 1 FROM file_info WHERE updated_date IS NULL)").Scan(&backfill)
+	if err != nil {
+		return false, fmt.Errorf("failed to query file_info: %v", err)
+	}
+	if backfill {
+		return true, nil
+	}
+	return MissingDirs(db)
+}
 
-	sqlQuery += `
-		ORDER BY modified_date DESC
-	`
+// latestFiles restricts file_info, aliased as fi, to the latest version of
+// every indexed file still on disk. Older rows are the history of changed
+// files.
+const latestFiles = " AND fi.id IN (SELECT MAX(id) FROM file_info GROUP BY host, file_path)" + notRemoved
 
-	sqlQuery += fmt.Sprintf("LIMIT %d", limit)
+// notRemoved leaves out the file_info rows, aliased as fi, of files
+// reported removed from disk.
+const notRemoved = " AND NOT EXISTS (SELECT 1 FROM file_removals r WHERE r.file_id = fi.id)"
 
+func queryResults(db Querier, sqlQuery string, args ...any) ([]*types.FileResult, error) {
 	rows, err := db.Query(sqlQuery, args...)
 	if err != nil {
-		return nil, fmt.Errorf("Database error: %v", err)
+		return nil, fmt.Errorf("Database error: %w", err)
 	}
 	defer rows.Close()
 
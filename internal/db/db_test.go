@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDB(t *testing.T) *sql.DB {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "hashup.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	_, err = EnsureFTS(db)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`
+		INSERT INTO file_hashes (id, file_hash) VALUES
+		(1, '00ab12cd34ef5678'),
+		(2, 'ffee000011112222'),
+		(3, '1234567890abcdef')
+	`)
+	require.NoError(t, err)
+
+	return db
+}
+
+func insertFile(t *testing.T, db *sql.DB, path, host, ext string, hashID int, hash, modified string) {
+	_, err := db.Exec(`
+		INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash)
+		VALUES (?, 100, ?, ?, ?, ?, ?)
+	`, path, modified, hashID, host, ext, hash)
+	require.NoError(t, err)
+}
+
+func paths(t *testing.T, db *sql.DB, query string, exts, hosts []string) []string {
+	results, err := Search(db, query, exts, hosts, 100)
+	require.NoError(t, err)
+	var p []string
+	for _, r := range results {
+		p = append(p, r.FilePath)
+	}
+	return p
+}
+
+func TestSearch(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/home/me/report/notes.txt", "laptop", "txt", 1, "00ab12cd34ef5678", "2024-01-03 00:00:00")
+	insertFile(t, db, "/home/me/docs/report.pdf", "laptop", "pdf", 2, "ffee000011112222", "2024-01-01 00:00:00")
+	insertFile(t, db, "/nas/backup/report.pdf", "nas", "pdf", 2, "ffee000011112222", "2024-01-02 00:00:00")
+	insertFile(t, db, "/nas/photos/img.jpg", "nas", "jpg", 3, "1234567890abcdef", "2024-01-04 00:00:00")
+
+	enabled, err := ftsEnabled(db)
+	require.NoError(t, err)
+
+	t.Run("all terms must match", func(t *testing.T) {
+		assert.ElementsMatch(t,
+			[]string{"/home/me/docs/report.pdf", "/nas/backup/report.pdf"},
+			paths(t, db, "report pdf", nil, nil))
+	})
+
+	t.Run("short terms", func(t *testing.T) {
+		assert.Equal(t, []string{"/nas/photos/img.jpg"}, paths(t, db, "ph jpg", nil, nil))
+		assert.Equal(t, []string{"/home/me/docs/report.pdf"}, paths(t, db, "me pdf", nil, nil))
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		assert.Equal(t, []string{"/nas/backup/report.pdf"}, paths(t, db, "report", []string{"pdf"}, []string{"nas"}))
+	})
+
+	t.Run("hash prefix", func(t *testing.T) {
+		assert.Equal(t, []string{"/home/me/report/notes.txt"}, paths(t, db, "00ab12", nil, nil))
+	})
+
+	t.Run("basename matches rank first", func(t *testing.T) {
+		if !enabled {
+			t.Skip("FTS5 not available, build with -tags sqlite_fts5")
+		}
+		p := paths(t, db, "report", nil, nil)
+		assert.Len(t, p, 3)
+		assert.Equal(t, "/home/me/report/notes.txt", p[2])
+	})
+}
+
+func TestOpenKeepsFTS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hashup.db")
+	db, err := OpenDatabase(path)
+	require.NoError(t, err)
+	// A trigger of the index, as left by an FTS5 enabled store
+	_, err = db.Exec(`CREATE TRIGGER IF NOT EXISTS file_info_fts_ai AFTER INSERT ON file_info BEGIN SELECT 1; END`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = OpenDatabase(path)
+	require.NoError(t, err)
+	defer db.Close()
+	enabled, err := ftsEnabled(db)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	pool, err := OpenReadOnly(path, 1)
+	require.NoError(t, err)
+	defer pool.Close()
+	enabled, err = ftsEnabled(pool.WithContext(context.Background()))
+	require.NoError(t, err)
+	assert.True(t, enabled)
+}
+
+func TestEnsureFTSBackfill(t *testing.T) {
+	db := testDB(t)
+	enabled, err := ftsEnabled(db)
+	require.NoError(t, err)
+	if !enabled {
+		t.Skip("FTS5 not available, build with -tags sqlite_fts5")
+	}
+
+	// Simulate a database created before the index existed
+	for _, trigger := range ftsTriggers {
+		_, err := db.Exec("DROP TRIGGER " + trigger)
+		require.NoError(t, err)
+	}
+	_, err = db.Exec("DROP TABLE file_info_fts")
+	require.NoError(t, err)
+	insertFile(t, db, "/srv/archive/taxes-2021.zip", "nas", "zip", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+
+	enabled, err = EnsureFTS(db)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	assert.Equal(t, []string{"/srv/archive/taxes-2021.zip"}, paths(t, db, "taxes", nil, nil))
+
+	_, err = db.Exec("DELETE FROM file_info WHERE host = 'nas'")
+	require.NoError(t, err)
+	assert.Empty(t, paths(t, db, "taxes", nil, nil))
+}
+
+func TestOutdated(t *testing.T) {
+	// A database created before the directories, removals and anomaly
+	// tables existed
+	dbPath := filepath.Join(t.TempDir(), "hashup.db")
+	schema, err := os.ReadFile("testdata/original.sql")
+	require.NoError(t, err)
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec(string(schema) + `
+		INSERT INTO file_hashes (id, file_hash) VALUES (1, '00ab12cd34ef5678');
+		INSERT INTO file_info (file_path, file_size, modified_date, hash_id, host, extension, file_hash)
+		VALUES ('/docs/report.pdf', 10, '2024-03-01 10:00:00', 1, 'nas', 'pdf', '00ab12cd34ef5678');
+	`)
+	require.NoError(t, err)
+	db.Close()
+
+	pool, err := OpenReadOnly(dbPath, 1)
+	require.NoError(t, err)
+	defer pool.Close()
+	ro := pool.WithContext(context.Background())
+	outdated, err := Outdated(ro)
+	require.NoError(t, err)
+	assert.True(t, outdated)
+
+	db, err = OpenDatabase(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	outdated, err = Outdated(ro)
+	require.NoError(t, err)
+	assert.False(t, outdated)
+
+	changes, err := Recent(ro, RecentOptions{})
+	require.NoError(t, err)
+	assert.Len(t, changes, 1)
+	l, err := List(ro, TreeRef{Host: "nas", Path: "/docs"})
+	require.NoError(t, err)
+	assert.Len(t, l.Files, 1)
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery is a webhook delivery log entry.
+type Delivery struct {
+	ID           int64     `json:"id"`
+	SearchName   string    `json:"search_name"`
+	WebhookURL   string    `json:"webhook_url"`
+	Event        string    `json:"event"`
+	FilePath     string    `json:"file_path,omitempty"`
+	Host         string    `json:"host,omitempty"`
+	Status       string    `json:"status"`
+	Attempts     int       `json:"attempts"`
+	ResponseCode int       `json:"response_code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Created      time.Time `json:"created"`
+	Updated      time.Time `json:"updated"`
+}
+
+// CreateDelivery logs a pending delivery, setting its ID.
+func CreateDelivery(db *sql.DB, d *Delivery) error {
+	d.Status = DeliveryPending
+	err := db.QueryRow(`
+		INSERT INTO webhook_deliveries (search_name, webhook_url, event, file_path, host, status)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, created, updated
+	`, d.SearchName, d.WebhookURL, d.Event, d.FilePath, d.Host, d.Status).Scan(&d.ID, &d.Created, &d.Updated)
+	if err != nil {
+		return fmt.Errorf("failed to log delivery: %v", err)
+	}
+	return nil
+}
+
+// UpdateDelivery records the outcome of the latest attempt of d.
+func UpdateDelivery(db *sql.DB, d *Delivery) error {
+	err := db.QueryRow(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, response_code = ?, error = ?, updated = CURRENT_TIMESTAMP
+		WHERE id = ?
+		RETURNING updated
+	`, d.Status, d.Attempts, d.ResponseCode, d.Error, d.ID).Scan(&d.Updated)
+	if err != nil {
+		return fmt.Errorf("failed to update delivery: %v", err)
+	}
+	return nil
+}
+
+// Deliveries returns the latest deliveries, newest first, optionally only
+// those of the saved search called search.
+func Deliveries(db Querier, search string, limit int) ([]*Delivery, error) {
+	where := ""
+	var args []any
+	if search != "" {
+		where = "WHERE search_name = ?"
+		args = append(args, search)
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := db.Query(`
+		SELECT id, search_name, webhook_url, event, file_path, host, status,
+			attempts, response_code, error, created, updated
+		FROM webhook_deliveries `+where+`
+		ORDER BY id DESC
+		LIMIT `+fmt.Sprint(limit), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*Delivery{}
+	for rows.Next() {
+		d := &Delivery{}
+		err := rows.Scan(&d.ID, &d.SearchName, &d.WebhookURL, &d.Event, &d.FilePath, &d.Host, &d.Status,
+			&d.Attempts, &d.ResponseCode, &d.Error, &d.Created, &d.Updated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,186 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TreeRef identifies an indexed directory tree on a host.
+type TreeRef struct {
+	Host string `json:"host"`
+	Path string `json:"path"`
+}
+
+// ParseTreeRef parses a host:/path reference.
+func ParseTreeRef(s string) (TreeRef, error) {
+	host, p, ok := strings.Cut(s, ":")
+	if !ok || host == "" || p == "" {
+		return TreeRef{}, fmt.Errorf("invalid tree %q, expected host:/path", s)
+	}
+	return TreeRef{Host: host, Path: p}, nil
+}
+
+func (t TreeRef) String() string {
+	return t.Host + ":" + t.Path
+}
+
+// prefix returns the tree path with a trailing slash, so /data does not
+// match /database.
+func (t TreeRef) prefix() string {
+	if strings.HasSuffix(t.Path, "/") {
+		return t.Path
+	}
+	return t.Path + "/"
+}
+
+// DiffEntry is a file that differs between two trees. Paths are relative to
+// the tree roots.
+type DiffEntry struct {
+	Path string `json:"path"`
+	// To is the new relative path of moved files.
+	To   string `json:"to,omitempty"`
+	Hash string `json:"hash"`
+	// ToHash is the hash in the second tree for changed files.
+	ToHash string `json:"to_hash,omitempty"`
+	Size   int64  `json:"size"`
+}
+
+// TreeDiff is the result of comparing two indexed trees.
+type TreeDiff struct {
+	From TreeRef `json:"from"`
+	To   TreeRef `json:"to"`
+	// Missing files are in From but not in To.
+	Missing []*DiffEntry `json:"missing"`
+	// Extra files are in To but not in From.
+	Extra []*DiffEntry `json:"extra"`
+	// Changed files are in both trees with different content.
+	Changed []*DiffEntry `json:"changed"`
+	// Moved files have the same content under a different path.
+	Moved []*DiffEntry `json:"moved"`
+	Same  int64        `json:"same"`
+}
+
+type treeFile struct {
+	hash string
+	size int64
+}
+
+// Diff compares two indexed trees by relative path and content hash.
+func Diff(db Querier, from, to TreeRef) (*TreeDiff, error) {
+	a, err := loadTree(db, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := loadTree(db, to)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &TreeDiff{
+		From:    from,
+		To:      to,
+		Missing: []*DiffEntry{},
+		Extra:   []*DiffEntry{},
+		Changed: []*DiffEntry{},
+		Moved:   []*DiffEntry{},
+	}
+
+	for rel, fa := range a {
+		fb, ok := b[rel]
+		switch {
+		case !ok:
+			d.Missing = append(d.Missing, &DiffEntry{Path: rel, Hash: fa.hash, Size: fa.size})
+		case fa.hash == fb.hash:
+			d.Same++
+		default:
+			d.Changed = append(d.Changed, &DiffEntry{Path: rel, Hash: fa.hash, ToHash: fb.hash, Size: fb.size})
+		}
+	}
+
+	for rel, fb := range b {
+		if _, ok := a[rel]; !ok {
+			d.Extra = append(d.Extra, &DiffEntry{Path: rel, Hash: fb.hash, Size: fb.size})
+		}
+	}
+
+	sortEntries(d.Missing)
+	sortEntries(d.Extra)
+	sortEntries(d.Changed)
+	detectMoves(d)
+
+	return d, nil
+}
+
+// detectMoves pairs missing and extra files with the same content. Empty
+// files all share a hash and are never considered moved.
+func detectMoves(d *TreeDiff) {
+	extraByHash := map[string][]int{}
+	for i, e := range d.Extra {
+		if e.Size > 0 {
+			extraByHash[e.Hash] = append(extraByHash[e.Hash], i)
+		}
+	}
+
+	movedExtra := map[int]bool{}
+	missing := []*DiffEntry{}
+	for _, m := range d.Missing {
+		candidates := extraByHash[m.Hash]
+		if m.Size == 0 || len(candidates) == 0 {
+			missing = append(missing, m)
+			continue
+		}
+		i := candidates[0]
+		extraByHash[m.Hash] = candidates[1:]
+		movedExtra[i] = true
+		d.Moved = append(d.Moved, &DiffEntry{Path: m.Path, To: d.Extra[i].Path, Hash: m.Hash, Size: m.Size})
+	}
+
+	extra := []*DiffEntry{}
+	for i, e := range d.Extra {
+		if !movedExtra[i] {
+			extra = append(extra, e)
+		}
+	}
+
+	d.Missing = missing
+	d.Extra = extra
+}
+
+// loadTree returns the latest indexed version of every file under t, keyed
+// by path relative to the tree root.
+func loadTree(db Querier, t TreeRef) (map[string]treeFile, error) {
+	// Paths under prefix sort between prefix and prefix with the trailing
+	// slash replaced by the next character, see List.
+	prefix := t.prefix()
+	rows, err := db.Query(`
+		SELECT fi.file_path, fi.file_hash, fi.file_size
+		FROM file_info fi
+		WHERE fi.host = ? AND fi.file_path >= ? AND fi.file_path < ?`+latestFiles,
+		t.Host, prefix, strings.TrimSuffix(prefix, "/")+"0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %v", t, err)
+	}
+	defer rows.Close()
+
+	files := map[string]treeFile{}
+	for rows.Next() {
+		var p string
+		var f treeFile
+		if err := rows.Scan(&p, &f.hash, &f.size); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		files[strings.TrimPrefix(p, prefix)] = f
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return files, nil
+}
+
+func sortEntries(entries []*DiffEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+}
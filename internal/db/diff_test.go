@@ -0,0 +1,57 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTreeRef(t *testing.T) {
+	ref, err := ParseTreeRef("nas:/volume1/photos")
+	require.NoError(t, err)
+	assert.Equal(t, TreeRef{Host: "nas", Path: "/volume1/photos"}, ref)
+
+	_, err = ParseTreeRef("/volume1/photos")
+	assert.Error(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	db := testDB(t)
+	_, err := db.Exec("INSERT INTO file_hashes (id, file_hash) VALUES (4, 'aaaa'), (5, 'bbbb')")
+	require.NoError(t, err)
+
+	insertFile(t, db, "/home/me/photos/same.jpg", "laptop", "jpg", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/home/me/photos/edited.jpg", "laptop", "jpg", 2, "ffee000011112222", "2024-01-01 00:00:00")
+	insertFile(t, db, "/home/me/photos/old/moved.jpg", "laptop", "jpg", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+	insertFile(t, db, "/home/me/photos/missing.jpg", "laptop", "jpg", 4, "aaaa", "2024-01-01 00:00:00")
+	insertFile(t, db, "/home/me/photos-other/ignored.jpg", "laptop", "jpg", 4, "aaaa", "2024-01-01 00:00:00")
+	// Paths are case sensitive
+	insertFile(t, db, "/HOME/ME/PHOTOS/ignored.jpg", "laptop", "jpg", 4, "aaaa", "2024-01-01 00:00:00")
+
+	insertFile(t, db, "/backup/photos/same.jpg", "nas", "jpg", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	// An older version indexed before the current one
+	insertFile(t, db, "/backup/photos/edited.jpg", "nas", "jpg", 2, "ffee000011112222", "2024-01-01 00:00:00")
+	insertFile(t, db, "/backup/photos/edited.jpg", "nas", "jpg", 5, "bbbb", "2024-01-02 00:00:00")
+	insertFile(t, db, "/backup/photos/new/moved.jpg", "nas", "jpg", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+	insertFile(t, db, "/backup/photos/extra.jpg", "nas", "jpg", 4, "aaaa", "2024-01-01 00:00:00")
+
+	d, err := Diff(db, TreeRef{Host: "laptop", Path: "/home/me/photos"}, TreeRef{Host: "nas", Path: "/backup/photos/"})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), d.Same)
+	require.Len(t, d.Changed, 1)
+	assert.Equal(t, &DiffEntry{Path: "edited.jpg", Hash: "ffee000011112222", ToHash: "bbbb", Size: 100}, d.Changed[0])
+	require.Len(t, d.Moved, 2)
+	assert.Equal(t, &DiffEntry{Path: "missing.jpg", To: "extra.jpg", Hash: "aaaa", Size: 100}, d.Moved[0])
+	assert.Equal(t, &DiffEntry{Path: "old/moved.jpg", To: "new/moved.jpg", Hash: "1234567890abcdef", Size: 100}, d.Moved[1])
+	assert.Empty(t, d.Missing)
+	assert.Empty(t, d.Extra)
+
+	d, err = Diff(db, TreeRef{Host: "nas", Path: "/backup/photos/new"}, TreeRef{Host: "laptop", Path: "/home/me/photos"})
+	require.NoError(t, err)
+	assert.Empty(t, d.Missing)
+	require.Len(t, d.Moved, 1)
+	assert.Equal(t, "old/moved.jpg", d.Moved[0].To)
+	assert.Len(t, d.Extra, 3)
+}
@@ -0,0 +1,243 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rubiojr/hashup/cmd/hs/types"
+	"github.com/rubiojr/hashup/internal/log"
+)
+
+// ErrDirNotFound is returned when listing a directory that is not indexed.
+var ErrDirNotFound = errors.New("directory not found")
+
+const upsertDirSQL = `
+	INSERT INTO directories (host, path, parent, files, size) VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT (host, path) DO UPDATE SET
+		files = files + excluded.files,
+		size = size + excluded.size
+`
+
+// DirEntry is an indexed directory with the number and total size of the
+// files below it.
+type DirEntry struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Files int64  `json:"files"`
+	Size  int64  `json:"size"`
+}
+
+// Listing holds the immediate children of an indexed directory.
+type Listing struct {
+	Host  string              `json:"host"`
+	Dir   *DirEntry           `json:"dir"`
+	Dirs  []*DirEntry         `json:"dirs"`
+	Files []*types.FileResult `json:"files"`
+}
+
+// DirUpdater keeps the directories table in sync as files are indexed.
+type DirUpdater struct {
+	stmt *sql.Stmt
+}
+
+// NewDirUpdater prepares the statements used to update directories.
+func NewDirUpdater(db *sql.DB) (*DirUpdater, error) {
+	stmt, err := db.Prepare(upsertDirSQL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare directory statement: %v", err)
+	}
+	return &DirUpdater{stmt: stmt}, nil
+}
+
+// Add adds files and size to every directory containing filePath on host,
+// creating missing directories. Indexing a new version of a file adds zero
+// files and the size difference, removing it subtracts one file and its
+// size.
+func (u *DirUpdater) Add(host, filePath string, files, size int64) error {
+	for _, dir := range parentDirs(filePath) {
+		if _, err := u.stmt.Exec(host, dir, dirParent(dir), files, size); err != nil {
+			return fmt.Errorf("failed to update directory %s: %v", dir, err)
+		}
+	}
+	return nil
+}
+
+func (u *DirUpdater) Close() error {
+	return u.stmt.Close()
+}
+
+// EnsureDirs builds the directories table for databases indexed before it
+// existed.
+func EnsureDirs(db *sql.DB) error {
+	missing, err := MissingDirs(db)
+	if err != nil || !missing {
+		return err
+	}
+	return RebuildDirs(db)
+}
+
+// MissingDirs reports whether the directories table needs to be built, see
+// EnsureDirs.
+func MissingDirs(db Querier) (bool, error) {
+	var dirs, files bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM directories), EXISTS (SELECT 1 FROM file_info)
+	`).Scan(&dirs, &files)
+	if err != nil {
+		return false, fmt.Errorf("failed to query directories: %v", err)
+	}
+
+	return !dirs && files, nil
+}
+
+// RebuildDirs repopulates the directories table from the latest version of
+// every indexed file still on disk.
+func RebuildDirs(db *sql.DB) error {
+	log.Debug("Rebuilding directory index")
+
+	rows, err := db.Query(`
+		SELECT fi.host, fi.file_path, COALESCE(fi.file_size, 0)
+		FROM file_info fi
+		WHERE 1 = 1` + latestFiles)
+	if err != nil {
+		return fmt.Errorf("failed to query files: %v", err)
+	}
+	defer rows.Close()
+
+	type dirKey struct{ host, path string }
+	dirs := map[dirKey]*DirEntry{}
+	for rows.Next() {
+		var host, filePath string
+		var size int64
+		if err := rows.Scan(&host, &filePath, &size); err != nil {
+			return fmt.Errorf("failed to scan row: %v", err)
+		}
+		for _, dir := range parentDirs(filePath) {
+			d, ok := dirs[dirKey{host, dir}]
+			if !ok {
+				d = &DirEntry{Path: dir}
+				dirs[dirKey{host, dir}] = d
+			}
+			d.Files++
+			d.Size += size
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM directories"); err != nil {
+		return fmt.Errorf("failed to clear directories: %v", err)
+	}
+	stmt, err := tx.Prepare(upsertDirSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare directory statement: %v", err)
+	}
+	defer stmt.Close()
+	for k, d := range dirs {
+		if _, err := stmt.Exec(k.host, k.path, dirParent(k.path), d.Files, d.Size); err != nil {
+			return fmt.Errorf("failed to insert directory %s: %v", k.path, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// List returns the subdirectories and the latest version of the files
+// directly inside dir.
+func List(db Querier, dir TreeRef) (*Listing, error) {
+	dirPath := path.Clean(dir.Path)
+	l := &Listing{
+		Host:  dir.Host,
+		Dir:   &DirEntry{Name: path.Base(dirPath), Path: dirPath},
+		Dirs:  []*DirEntry{},
+		Files: []*types.FileResult{},
+	}
+
+	err := db.QueryRow(
+		"SELECT files, size FROM directories WHERE host = ? AND path = ?",
+		dir.Host, dirPath,
+	).Scan(&l.Dir.Files, &l.Dir.Size)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s:%s", ErrDirNotFound, dir.Host, dirPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query directory: %v", err)
+	}
+
+	// Directories whose files were all removed are left out.
+	rows, err := db.Query(`
+		SELECT path, files, size FROM directories
+		WHERE host = ? AND parent = ? AND files > 0
+		ORDER BY path
+	`, dir.Host, dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query directories: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		d := &DirEntry{}
+		if err := rows.Scan(&d.Path, &d.Files, &d.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		d.Name = path.Base(d.Path)
+		l.Dirs = append(l.Dirs, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	// Paths under prefix sort between prefix and prefix with the trailing
+	// slash replaced by the next character, which keeps the query on the
+	// (host, file_path) index.
+	prefix := strings.TrimSuffix(dirPath, "/") + "/"
+	files, err := queryResults(db, `
+		SELECT fi.file_path, fi.file_size, fi.modified_date, fi.host, fi.extension, fi.file_hash
+		FROM file_info fi
+		WHERE fi.id IN (
+			SELECT MAX(id) FROM file_info
+			WHERE host = ? AND file_path >= ? AND file_path < ?
+				AND instr(substr(file_path, ?), '/') = 0
+			GROUP BY file_path
+		)`+notRemoved+`
+		ORDER BY fi.file_path
+	`, dir.Host, prefix, strings.TrimSuffix(prefix, "/")+"0", len(prefix)+1)
+	if err != nil {
+		return nil, err
+	}
+	l.Files = append(l.Files, files...)
+
+	return l, nil
+}
+
+// parentDirs returns the directories containing filePath, innermost first.
+func parentDirs(filePath string) []string {
+	var dirs []string
+	for d := path.Dir(filePath); d != "."; d = path.Dir(d) {
+		dirs = append(dirs, d)
+		if d == "/" {
+			break
+		}
+	}
+	return dirs
+}
+
+// dirParent returns the parent of dir, empty for root directories.
+func dirParent(dir string) string {
+	if dir == "/" {
+		return ""
+	}
+	if p := path.Dir(dir); p != "." {
+		return p
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParentDirs(t *testing.T) {
+	assert.Equal(t, []string{"/a/b", "/a", "/"}, parentDirs("/a/b/c.txt"))
+	assert.Equal(t, []string{"/"}, parentDirs("/c.txt"))
+	assert.Equal(t, []string{"a"}, parentDirs("a/c.txt"))
+	assert.Equal(t, "", dirParent("/"))
+	assert.Equal(t, "/", dirParent("/a"))
+	assert.Equal(t, "", dirParent("a"))
+}
+
+func TestList(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/srv/media/readme.txt", "nas", "txt", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/srv/media/movies/a.mkv", "nas", "mkv", 2, "ffee000011112222", "2024-01-01 00:00:00")
+	insertFile(t, db, "/srv/media/movies/hd/b.mkv", "nas", "mkv", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+	insertFile(t, db, "/srv/media-old/c.txt", "nas", "txt", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/srv/media/other.txt", "laptop", "txt", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	// A newer version of readme.txt replaces the first one
+	insertFile(t, db, "/srv/media/readme.txt", "nas", "txt", 3, "1234567890abcdef", "2024-01-02 00:00:00")
+	require.NoError(t, EnsureDirs(db))
+
+	l, err := List(db, TreeRef{Host: "nas", Path: "/srv/media/"})
+	require.NoError(t, err)
+	assert.Equal(t, &DirEntry{Name: "media", Path: "/srv/media", Files: 3, Size: 300}, l.Dir)
+	assert.Equal(t, []*DirEntry{{Name: "movies", Path: "/srv/media/movies", Files: 2, Size: 200}}, l.Dirs)
+	require.Len(t, l.Files, 1)
+	assert.Equal(t, "/srv/media/readme.txt", l.Files[0].FilePath)
+	assert.Equal(t, "1234567890abcdef", l.Files[0].FileHash)
+
+	l, err = List(db, TreeRef{Host: "nas", Path: "/"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), l.Dir.Files)
+	assert.Equal(t, []*DirEntry{{Name: "srv", Path: "/srv", Files: 4, Size: 400}}, l.Dirs)
+	assert.Empty(t, l.Files)
+
+	_, err = List(db, TreeRef{Host: "nas", Path: "/nope"})
+	assert.ErrorIs(t, err, ErrDirNotFound)
+}
+
+func TestDirUpdater(t *testing.T) {
+	db := testDB(t)
+	u, err := NewDirUpdater(db)
+	require.NoError(t, err)
+	defer u.Close()
+
+	require.NoError(t, u.Add("nas", "/a/b/c.txt", 1, 100))
+	require.NoError(t, u.Add("nas", "/a/d.txt", 1, 50))
+	require.NoError(t, u.Add("nas", "/a/b/c.txt", 0, -40))
+
+	var files, size int64
+	require.NoError(t, db.QueryRow("SELECT files, size FROM directories WHERE host = 'nas' AND path = '/a'").Scan(&files, &size))
+	assert.Equal(t, int64(2), files)
+	assert.Equal(t, int64(110), size)
+	require.NoError(t, db.QueryRow("SELECT files, size FROM directories WHERE host = 'nas' AND path = '/a/b'").Scan(&files, &size))
+	assert.Equal(t, int64(1), files)
+	assert.Equal(t, int64(60), size)
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DU sort keys.
+const (
+	DUSortSize  = "size"
+	DUSortFiles = "files"
+	DUSortName  = "name"
+)
+
+// DUOptions configures a disk usage report.
+type DUOptions struct {
+	// Depth is the number of directory levels below the tree root to
+	// report. Deeper files are accounted to their ancestor at Depth.
+	// Defaults to 1.
+	Depth int
+	// Sort orders the children of every directory by size (the default)
+	// or file count, largest first, or by name.
+	Sort string
+}
+
+// DUNode is a directory with the total size and file count of everything
+// indexed below it. Nested nodes can be fed directly to treemap charts.
+type DUNode struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Files    int64     `json:"files"`
+	Children []*DUNode `json:"children,omitempty"`
+
+	children map[string]*DUNode
+}
+
+// DU aggregates the latest indexed version of every file under tree by
+// directory, down to opts.Depth levels.
+func DU(db Querier, tree TreeRef, opts DUOptions) (*DUNode, error) {
+	if opts.Depth <= 0 {
+		opts.Depth = 1
+	}
+
+	var less func(a, b *DUNode) bool
+	switch opts.Sort {
+	case "", DUSortSize:
+		less = func(a, b *DUNode) bool { return a.Size > b.Size }
+	case DUSortFiles:
+		less = func(a, b *DUNode) bool { return a.Files > b.Files }
+	case DUSortName:
+		less = func(a, b *DUNode) bool { return a.Name < b.Name }
+	default:
+		return nil, fmt.Errorf("%w %q, expected one of %s, %s, %s", ErrInvalidSort, opts.Sort, DUSortSize, DUSortFiles, DUSortName)
+	}
+
+	// loadTree selects the files by path range, which is case sensitive
+	// and stays on the (host, file_path) index.
+	files, err := loadTree(db, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &DUNode{Name: tree.Path, Path: tree.Path}
+	for rel, f := range files {
+		root.Size += f.size
+		root.Files++
+
+		dirs := strings.Split(rel, "/")
+		dirs = dirs[:len(dirs)-1]
+		n := root
+		for _, dir := range dirs[:min(len(dirs), opts.Depth)] {
+			n = n.child(dir)
+			n.Size += f.size
+			n.Files++
+		}
+	}
+	root.sort(less)
+
+	return root, nil
+}
+
+func (n *DUNode) child(name string) *DUNode {
+	if c, ok := n.children[name]; ok {
+		return c
+	}
+	if n.children == nil {
+		n.children = map[string]*DUNode{}
+	}
+	c := &DUNode{Name: name, Path: path.Join(n.Path, name)}
+	n.children[name] = c
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// sort orders children recursively, breaking ties by name so output is
+// stable.
+func (n *DUNode) sort(less func(a, b *DUNode) bool) {
+	sort.Slice(n.Children, func(i, j int) bool {
+		a, b := n.Children[i], n.Children[j]
+		if less(a, b) != less(b, a) {
+			return less(a, b)
+		}
+		return a.Name < b.Name
+	})
+	for _, c := range n.Children {
+		c.sort(less)
+	}
+}
+
+// Walk calls fn for n and its descendants, parents before children, with
+// the depth relative to n.
+func (n *DUNode) Walk(fn func(n *DUNode, depth int)) {
+	n.walk(fn, 0)
+}
+
+func (n *DUNode) walk(fn func(n *DUNode, depth int), depth int) {
+	fn(n, depth)
+	for _, c := range n.Children {
+		c.walk(fn, depth+1)
+	}
+}
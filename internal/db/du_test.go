@@ -0,0 +1,44 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDU(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/srv/media/movies/a.mkv", "nas", "mkv", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/srv/media/movies/hd/b.mkv", "nas", "mkv", 2, "ffee000011112222", "2024-01-01 00:00:00")
+	insertFile(t, db, "/srv/media/music/c.mp3", "nas", "mp3", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+	insertFile(t, db, "/srv/media/readme.txt", "nas", "txt", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+	insertFile(t, db, "/srv/media/music/c.mp3", "laptop", "mp3", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+	// Paths are case sensitive
+	insertFile(t, db, "/SRV/MEDIA/readme.txt", "nas", "txt", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+	_, err := db.Exec("UPDATE file_info SET file_size = 1000 WHERE extension = 'mkv'")
+	require.NoError(t, err)
+
+	root, err := DU(db, TreeRef{Host: "nas", Path: "/srv/media"}, DUOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2200), root.Size)
+	assert.Equal(t, int64(4), root.Files)
+	require.Len(t, root.Children, 2)
+	assert.Equal(t, "/srv/media/movies", root.Children[0].Path)
+	assert.Equal(t, int64(2000), root.Children[0].Size)
+	assert.Empty(t, root.Children[0].Children)
+	assert.Equal(t, "music", root.Children[1].Name)
+
+	root, err = DU(db, TreeRef{Host: "nas", Path: "/"}, DUOptions{Depth: 4, Sort: DUSortName})
+	require.NoError(t, err)
+	var paths []string
+	root.Walk(func(n *DUNode, depth int) {
+		paths = append(paths, n.Path)
+	})
+	assert.Equal(t, []string{
+		"/", "/SRV", "/SRV/MEDIA", "/srv", "/srv/media", "/srv/media/movies", "/srv/media/movies/hd", "/srv/media/music",
+	}, paths)
+
+	_, err = DU(db, TreeRef{Host: "nas", Path: "/"}, DUOptions{Sort: "color"})
+	assert.ErrorIs(t, err, ErrInvalidSort)
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/rubiojr/hashup/cmd/hs/types"
+)
+
+// DupeOptions restricts which files are considered when looking for
+// duplicates.
+type DupeOptions struct {
+	Hosts      []string
+	MinSize    int64
+	PathPrefix string
+	// Types are file categories (see ExpandTypes) or extensions.
+	Types []string
+	Limit int
+}
+
+// DupeGroup is a set of indexed files sharing the same content.
+type DupeGroup struct {
+	Hash   string              `json:"hash"`
+	Size   int64               `json:"size"`
+	Copies int                 `json:"copies"`
+	Wasted int64               `json:"wasted"`
+	Hosts  []string            `json:"hosts"`
+	Files  []*types.FileResult `json:"files"`
+}
+
+// Dupes returns groups of files with identical content, sorted by the space
+// that would be reclaimed keeping a single copy. Only the latest version of
+// every file is considered.
+func Dupes(db Querier, opts DupeOptions) ([]*DupeGroup, error) {
+	q := &Query{}
+	q.AddHosts(opts.Hosts...)
+	q.AddMinSize(opts.MinSize)
+	q.AddPathPrefix(opts.PathPrefix)
+	q.AddExtensions(ExpandTypes(opts.Types)...)
+	where, args := q.Where()
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := db.Query(`
+		SELECT fi.file_hash, MAX(fi.file_size) AS size, COUNT(*) AS copies
+		FROM file_info fi
+		WHERE 1 = 1`+latestFiles+where+`
+		GROUP BY fi.file_hash
+		HAVING COUNT(*) > 1
+		ORDER BY size * (copies - 1) DESC, fi.file_hash
+		LIMIT `+fmt.Sprint(limit),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicates: %v", err)
+	}
+	defer rows.Close()
+
+	var groups []*DupeGroup
+	byHash := map[string]*DupeGroup{}
+	for rows.Next() {
+		g := &DupeGroup{}
+		if err := rows.Scan(&g.Hash, &g.Size, &g.Copies); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		g.Wasted = g.Size * int64(g.Copies-1)
+		groups = append(groups, g)
+		byHash[g.Hash] = g
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	if len(groups) == 0 {
+		return groups, nil
+	}
+
+	hashArgs := make([]any, 0, len(groups)+len(args))
+	for _, g := range groups {
+		hashArgs = append(hashArgs, g.Hash)
+	}
+	files, err := queryResults(db, `
+		SELECT fi.file_path, fi.file_size, fi.modified_date, fi.host, fi.extension, fi.file_hash
+		FROM file_info fi
+		WHERE fi.file_hash IN (`+placeholders(len(groups))+`)`+latestFiles+where+`
+		ORDER BY fi.host, fi.file_path`,
+		append(hashArgs, args...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		g := byHash[f.FileHash]
+		g.Files = append(g.Files, f)
+		if len(g.Hosts) == 0 || g.Hosts[len(g.Hosts)-1] != f.Host {
+			g.Hosts = append(g.Hosts, f.Host)
+		}
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDupes(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/home/me/a.txt", "laptop", "txt", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/home/me/b.txt", "laptop", "txt", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/home/me/movie.mkv", "laptop", "mkv", 2, "ffee000011112222", "2024-01-01 00:00:00")
+	insertFile(t, db, "/nas/movies/movie.mkv", "nas", "mkv", 2, "ffee000011112222", "2024-01-01 00:00:00")
+	insertFile(t, db, "/nas/backup/movie.mkv", "nas", "mkv", 2, "ffee000011112222", "2024-01-01 00:00:00")
+	insertFile(t, db, "/nas/unique.jpg", "nas", "jpg", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+	_, err := db.Exec("UPDATE file_info SET file_size = 5000 WHERE extension = 'mkv'")
+	require.NoError(t, err)
+
+	groups, err := Dupes(db, DupeOptions{})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	assert.Equal(t, "ffee000011112222", groups[0].Hash)
+	assert.Equal(t, 3, groups[0].Copies)
+	assert.Equal(t, int64(10000), groups[0].Wasted)
+	assert.Equal(t, []string{"laptop", "nas"}, groups[0].Hosts)
+	assert.Len(t, groups[0].Files, 3)
+
+	assert.Equal(t, "00ab12cd34ef5678", groups[1].Hash)
+	assert.Equal(t, int64(100), groups[1].Wasted)
+
+	t.Run("filters", func(t *testing.T) {
+		groups, err := Dupes(db, DupeOptions{Hosts: []string{"nas"}})
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		assert.Equal(t, 2, groups[0].Copies)
+		assert.Equal(t, []string{"nas"}, groups[0].Hosts)
+
+		groups, err = Dupes(db, DupeOptions{MinSize: 1000})
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+
+		groups, err = Dupes(db, DupeOptions{Types: []string{"document"}})
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		assert.Equal(t, "00ab12cd34ef5678", groups[0].Hash)
+
+		groups, err = Dupes(db, DupeOptions{PathPrefix: "/nas/"})
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		assert.Equal(t, []string{"nas"}, groups[0].Hosts)
+	})
+}
+
+func TestDupesLatestVersion(t *testing.T) {
+	db := testDB(t)
+	// A file edited and reverted has three versions, two with the same hash
+	insertFile(t, db, "/home/me/a.txt", "laptop", "txt", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/home/me/a.txt", "laptop", "txt", 2, "ffee000011112222", "2024-01-02 00:00:00")
+	insertFile(t, db, "/home/me/a.txt", "laptop", "txt", 1, "00ab12cd34ef5678", "2024-01-03 00:00:00")
+	// A copy of a content the other file no longer has
+	insertFile(t, db, "/home/me/b.txt", "laptop", "txt", 2, "ffee000011112222", "2024-01-02 00:00:00")
+
+	groups, err := Dupes(db, DupeOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+
+	insertFile(t, db, "/nas/a.txt", "nas", "txt", 1, "00ab12cd34ef5678", "2024-01-03 00:00:00")
+	groups, err = Dupes(db, DupeOptions{})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, 2, groups[0].Copies)
+	require.Len(t, groups[0].Files, 2)
+	assert.Equal(t, "/home/me/a.txt", groups[0].Files[0].FilePath)
+	assert.Equal(t, "/nas/a.txt", groups[0].Files[1].FilePath)
+}
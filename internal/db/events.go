@@ -0,0 +1,171 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Event types.
+const (
+	// EventFileIndexed is recorded the first time a path is indexed on a
+	// host.
+	EventFileIndexed = "file_indexed"
+	// EventFileUpdated is recorded when a new version of an indexed path
+	// is stored.
+	EventFileUpdated = "file_updated"
+	// EventFileRemoved is recorded when a scanner reports an indexed path
+	// removed from disk. The file is its latest version.
+	EventFileRemoved = "file_removed"
+	// EventHostSeen is recorded when the store receives the first file
+	// from a host since it started.
+	EventHostSeen = "host_seen"
+	// EventFileCorrupted is recorded when a file is suspected of silent
+	// corruption, see IntegrityEvent. The file hash is the unexpected one.
+	EventFileCorrupted = "file_corrupted"
+	// EventAnomalyDetected is recorded when a host changes unusually many
+	// files, see AnomalyAlert. It carries no file.
+	EventAnomalyDetected = "anomaly_detected"
+)
+
+// EventTypes lists the valid event types.
+var EventTypes = []string{EventFileIndexed, EventFileUpdated, EventFileRemoved, EventHostSeen, EventFileCorrupted, EventAnomalyDetected}
+
+// Event is a change recorded by the store.
+type Event struct {
+	ID       int64     `json:"id"`
+	Type     string    `json:"type"`
+	Host     string    `json:"host"`
+	FileID   int64     `json:"-"`
+	FilePath string    `json:"file_path,omitempty"`
+	FileHash string    `json:"file_hash,omitempty"`
+	FileSize int64     `json:"file_size,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// EventFilter selects the events returned by Events.
+type EventFilter struct {
+	// AfterID returns events newer than the one with this id
+	AfterID int64
+	Types   []string
+	Hosts   []string
+	// Query restricts file events to files matching a search query, see
+	// ParseQuery. Host events never match a query.
+	Query string
+	Limit int
+}
+
+// EventRecorder stores events as the store indexes files.
+type EventRecorder struct {
+	stmt *sql.Stmt
+}
+
+// NewEventRecorder prepares the statements used to record events.
+func NewEventRecorder(db *sql.DB) (*EventRecorder, error) {
+	stmt, err := db.Prepare(`
+		INSERT INTO events (type, host, file_id, file_path, file_hash, file_size)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare event statement: %v", err)
+	}
+	return &EventRecorder{stmt: stmt}, nil
+}
+
+// Record stores e. Its ID and Time are set by the database.
+func (r *EventRecorder) Record(e *Event) error {
+	var fileID any
+	if e.FileID != 0 {
+		fileID = e.FileID
+	}
+	_, err := r.stmt.Exec(e.Type, e.Host, fileID, e.FilePath, e.FileHash, e.FileSize)
+	if err != nil {
+		return fmt.Errorf("failed to record event: %v", err)
+	}
+	return nil
+}
+
+func (r *EventRecorder) Close() error {
+	return r.stmt.Close()
+}
+
+// PruneEvents deletes the events recorded before t.
+func PruneEvents(db *sql.DB, t time.Time) (int64, error) {
+	res, err := db.Exec("DELETE FROM events WHERE created < ?", t.UTC().Format(dateLayout))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune events: %v", err)
+	}
+	return res.RowsAffected()
+}
+
+// LastEventID returns the id of the latest event, zero if there are none.
+func LastEventID(db Querier) (int64, error) {
+	var id int64
+	if err := db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM events").Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to query events: %v", err)
+	}
+	return id, nil
+}
+
+// Events returns the events matching f, oldest first.
+func Events(db Querier, f EventFilter) ([]*Event, error) {
+	where := []string{"e.id > ?"}
+	args := []any{f.AfterID}
+
+	if types := nonEmpty(f.Types); len(types) > 0 {
+		where = append(where, "e.type IN ("+placeholders(len(types))+")")
+		for _, t := range types {
+			args = append(args, t)
+		}
+	}
+	if hosts := nonEmpty(f.Hosts); len(hosts) > 0 {
+		where = append(where, "e.host IN ("+placeholders(len(hosts))+")")
+		for _, h := range hosts {
+			args = append(args, h)
+		}
+	}
+
+	if f.Query != "" {
+		q, err := ParseQuery(f.Query)
+		if err != nil {
+			return nil, err
+		}
+		s := buildSearch(q, false)
+		where = append(where, "e.file_id IN (SELECT fi.id "+s.from+")")
+		args = append(args, s.args...)
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := db.Query(`
+		SELECT e.id, e.type, e.host, COALESCE(e.file_id, 0), e.file_path, e.file_hash, e.file_size, e.created
+		FROM events e
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY e.id
+		LIMIT `+fmt.Sprint(limit),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %v", err)
+	}
+	defer rows.Close()
+
+	events := []*Event{}
+	for rows.Next() {
+		e := &Event{}
+		err := rows.Scan(&e.ID, &e.Type, &e.Host, &e.FileID, &e.FilePath, &e.FileHash, &e.FileSize, &e.Time)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return events, nil
+}
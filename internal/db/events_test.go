@@ -0,0 +1,70 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/docs/report.pdf", "nas", "pdf", 1, "00ab12cd34ef5678", "2024-03-01 10:00:00")
+	insertFile(t, db, "/photos/cat.jpg", "laptop", "jpg", 2, "ffee000011112222", "2024-03-01 10:00:00")
+
+	r, err := NewEventRecorder(db)
+	require.NoError(t, err)
+	defer r.Close()
+
+	for _, e := range []*Event{
+		{Type: EventHostSeen, Host: "nas"},
+		{Type: EventFileIndexed, Host: "nas", FileID: 1, FilePath: "/docs/report.pdf", FileHash: "00ab12cd34ef5678", FileSize: 100},
+		{Type: EventHostSeen, Host: "laptop"},
+		{Type: EventFileUpdated, Host: "laptop", FileID: 2, FilePath: "/photos/cat.jpg", FileHash: "ffee000011112222", FileSize: 100},
+	} {
+		require.NoError(t, r.Record(e))
+	}
+
+	events, err := Events(db, EventFilter{})
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, int64(1), events[0].ID)
+	assert.Equal(t, EventHostSeen, events[0].Type)
+	assert.Equal(t, "/docs/report.pdf", events[1].FilePath)
+	assert.Equal(t, int64(1), events[1].FileID)
+	assert.WithinDuration(t, time.Now(), events[1].Time, time.Minute)
+
+	events, err = Events(db, EventFilter{AfterID: 2, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(3), events[0].ID)
+
+	events, err = Events(db, EventFilter{Types: []string{EventFileIndexed, EventFileUpdated}, Hosts: []string{"laptop"}})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "/photos/cat.jpg", events[0].FilePath)
+
+	events, err = Events(db, EventFilter{Query: "report ext:pdf"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(2), events[0].ID)
+
+	events, err = Events(db, EventFilter{Query: "ext:jpg -path:photos"})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	_, err = Events(db, EventFilter{Query: "size:huge"})
+	assert.Error(t, err)
+
+	id, err := LastEventID(db)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), id)
+
+	n, err := PruneEvents(db, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	events, err = Events(db, EventFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
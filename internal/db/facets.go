@@ -0,0 +1,124 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rubiojr/hashup/internal/log"
+)
+
+// facetLimit is the number of values returned per facet.
+const facetLimit = 20
+
+// FacetCount is the number of matching files with a given value.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facets summarizes the files matching a search by host, file type and
+// extension, most common values first.
+type Facets struct {
+	Hosts      []FacetCount `json:"hosts"`
+	Types      []FacetCount `json:"types"`
+	Extensions []FacetCount `json:"extensions"`
+}
+
+// SearchFacets parses query (see ParseQuery) and counts the matching files
+// by facet. Sort, Cursor and Limit in opts are ignored.
+func SearchFacets(db Querier, query string, opts SearchOptions) (*Facets, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	q.AddExtensions(opts.Extensions...)
+	q.AddHosts(opts.Hosts...)
+
+	enabled, err := ftsEnabled(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled {
+		f, err := facets(db, buildSearch(q, true))
+		if !isNoFTSModule(err) {
+			return f, err
+		}
+		log.Debug("FTS5 not available in this binary, falling back to LIKE search")
+	}
+
+	return facets(db, buildSearch(q, false))
+}
+
+func facets(db Querier, s *searchSQL) (*Facets, error) {
+	f := &Facets{}
+
+	var err error
+	f.Hosts, err = countBy(db, s, "fi.host", facetLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	exts, err := countBy(db, s, "LOWER(fi.extension)", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	types := map[string]int64{}
+	for _, e := range exts {
+		if t := TypeOf(e.Value); t != "" {
+			types[t] += e.Count
+		}
+	}
+	f.Types = []FacetCount{}
+	for t, n := range types {
+		f.Types = append(f.Types, FacetCount{Value: t, Count: n})
+	}
+	sortFacet(f.Types)
+
+	f.Extensions = exts[:min(len(exts), facetLimit)]
+
+	return f, nil
+}
+
+// countBy counts the files matched by s grouped by expr, returning at most
+// limit values when limit is positive.
+func countBy(db Querier, s *searchSQL, expr string, limit int) ([]FacetCount, error) {
+	sqlQuery := s.with + `SELECT ` + expr + `, COUNT(*) ` + s.from + `
+		GROUP BY 1
+		ORDER BY 2 DESC, 1`
+	if limit > 0 {
+		sqlQuery += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := db.Query(sqlQuery, s.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count facets: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []FacetCount{}
+	for rows.Next() {
+		var c FacetCount
+		if err := rows.Scan(&c.Value, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if c.Value != "" {
+			counts = append(counts, c)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return counts, nil
+}
+
+func sortFacet(counts []FacetCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rubiojr/hashup/cmd/hs/types"
+)
+
+// FileCopy is an indexed copy of a file with its tags.
+type FileCopy struct {
+	types.FileResult
+	Tags []string `json:"tags"`
+}
+
+// FileDetail describes indexed content by hash.
+type FileDetail struct {
+	Hash string `json:"hash"`
+	// Added is when the content was first indexed.
+	Added  time.Time   `json:"added"`
+	Copies []*FileCopy `json:"copies"`
+}
+
+// File returns the current copies of the content with the given hash on
+// every host. Paths whose latest version has different content, or that
+// were removed, are not copies anymore and are left out.
+func File(db Querier, hash string) (*FileDetail, error) {
+	d := &FileDetail{Hash: hash, Copies: []*FileCopy{}}
+	err := db.QueryRow("SELECT added FROM file_hashes WHERE file_hash = ?", hash).Scan(&d.Added)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrFileNotFound, hash)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query file hash: %v", err)
+	}
+
+	rows, err := db.Query(`
+		SELECT fi.file_path, fi.file_size, fi.modified_date, fi.host, fi.extension, fi.file_hash,
+			COALESCE((SELECT tags FROM file_tags WHERE file_id = fi.id LIMIT 1), '')
+		FROM file_info fi
+		WHERE fi.file_hash = ? AND NOT EXISTS (
+			SELECT 1 FROM file_info n
+			WHERE n.host = fi.host AND n.file_path = fi.file_path AND n.id > fi.id
+		)`+notRemoved+`
+		ORDER BY fi.host, fi.file_path
+	`, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query copies: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c := &FileCopy{}
+		var tags string
+		err := rows.Scan(&c.FilePath, &c.FileSize, &c.ModifiedDate, &c.Host, &c.Extension, &c.FileHash, &tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		c.Tags = splitTags(tags)
+		d.Copies = append(d.Copies, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return d, nil
+}
+
+// FileByID returns the file_info row with the given id.
+func FileByID(db Querier, id int64) (*types.FileResult, error) {
+	results, err := queryResults(db, `
+		SELECT file_path, file_size, modified_date, host, extension, file_hash
+		FROM file_info WHERE id = ?
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w: id %d", ErrFileNotFound, id)
+	}
+	return results[0], nil
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/a.txt", "nas", "txt", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/b.txt", "laptop", "txt", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	// /b.txt on the laptop changed, it's not a copy anymore
+	insertFile(t, db, "/b.txt", "laptop", "txt", 2, "ffee000011112222", "2024-01-02 00:00:00")
+
+	id, err := FileID(db, "nas", "/a.txt", "00ab12cd34ef5678")
+	require.NoError(t, err)
+	require.NoError(t, SetTags(db, id, []string{"work"}))
+
+	d, err := File(db, "00ab12cd34ef5678")
+	require.NoError(t, err)
+	require.Len(t, d.Copies, 1)
+	assert.Equal(t, "/a.txt", d.Copies[0].FilePath)
+	assert.Equal(t, []string{"work"}, d.Copies[0].Tags)
+	assert.False(t, d.Added.IsZero())
+
+	_, err = File(db, "nope")
+	assert.ErrorIs(t, err, ErrFileNotFound)
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"slices"
+	"strings"
+)
+
+// fileTypes maps broad file categories to the extensions they include.
+var fileTypes = map[string][]string{
+	"image":    {"jpg", "jpeg", "png", "gif", "bmp", "tif", "tiff", "webp", "heic", "heif", "raw", "cr2", "nef", "arw", "dng", "svg"},
+	"video":    {"mp4", "mkv", "mov", "avi", "wmv", "flv", "webm", "m4v", "mpg", "mpeg", "3gp", "mts"},
+	"audio":    {"mp3", "flac", "wav", "ogg", "m4a", "aac", "wma", "opus", "aiff"},
+	"document": {"pdf", "doc", "docx", "odt", "rtf", "txt", "md", "xls", "xlsx", "ods", "csv", "ppt", "pptx", "odp", "epub"},
+	"archive":  {"zip", "tar", "gz", "tgz", "bz2", "xz", "zst", "7z", "rar", "iso", "dmg"},
+}
+
+// ExpandTypes replaces file categories (image, video, audio, document,
+// archive) with their extensions. Other values are kept as extensions.
+func ExpandTypes(types []string) []string {
+	var exts []string
+	for _, t := range nonEmpty(types) {
+		t = strings.ToLower(strings.TrimPrefix(t, "."))
+		if e, ok := fileTypes[t]; ok {
+			exts = append(exts, e...)
+			continue
+		}
+		exts = append(exts, t)
+	}
+	return exts
+}
+
+// TypeOf returns the file category of an extension, or an empty string
+// when it has none.
+func TypeOf(ext string) string {
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	for t, exts := range fileTypes {
+		if slices.Contains(exts, ext) {
+			return t
+		}
+	}
+	return ""
+}
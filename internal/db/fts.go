@@ -0,0 +1,134 @@
+package db
+
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rubiojr/hashup/internal/log"
+)
+
+//go:embed fts.sql
+var FTSSchema string
+
+// ftsTriggers are the triggers keeping file_info_fts in sync with file_info.
+var ftsTriggers = []string{"file_info_fts_ai", "file_info_fts_ad", "file_info_fts_au"}
+
+// ftsState caches ftsEnabled per database, keyed by *sql.DB or *Pool.
+var ftsState sync.Map
+
+// EnsureFTS creates the trigram full-text index and its triggers, backfilling
+// it from file_info when the triggers were not present yet. Only the store
+// and commands writing to file_info call it, opening a database never
+// changes the index.
+//
+// FTS5 is only compiled into go-sqlite3 with the sqlite_fts5 build tag. When
+// the module is missing the triggers are dropped so writes keep working, and
+// searches fall back to LIKE queries. A later FTS5 enabled writer will
+// recreate the triggers and rebuild the index.
+func EnsureFTS(db *sql.DB) (bool, error) {
+	defer ftsState.Delete(db)
+
+	hadTriggers, err := queryFTSTriggers(db)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := db.Exec(FTSSchema); err != nil {
+		if !isNoFTSModule(err) {
+			return false, fmt.Errorf("failed to create full-text index: %v", err)
+		}
+		log.Debug("FTS5 not available, path search will use LIKE queries")
+		for _, t := range ftsTriggers {
+			if _, err := db.Exec("DROP TRIGGER IF EXISTS " + t); err != nil {
+				return false, fmt.Errorf("failed to drop trigger %s: %v", t, err)
+			}
+		}
+		return false, nil
+	}
+
+	if !hadTriggers {
+		if err := RebuildFTS(db); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// RebuildFTS repopulates the full-text index from file_info.
+func RebuildFTS(db *sql.DB) error {
+	log.Debug("Rebuilding full-text index")
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT INTO file_info_fts (file_info_fts) VALUES ('delete-all')"); err != nil {
+		return fmt.Errorf("failed to clear full-text index: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO file_info_fts (rowid, basename, file_path)
+		SELECT id, substr(file_path, length(rtrim(file_path, replace(file_path, '/', ''))) + 1), file_path
+		FROM file_info
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to populate full-text index: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// ftsEnabled reports whether the full-text index is being maintained. It
+// is checked once per database, readers pick up an index created later by
+// the store when they are restarted.
+func ftsEnabled(db Querier) (bool, error) {
+	var key any = db
+	if q, ok := db.(*ctxQuerier); ok {
+		key = q.pool
+	}
+	if enabled, ok := ftsState.Load(key); ok {
+		return enabled.(bool), nil
+	}
+
+	enabled, err := queryFTSTriggers(db)
+	if err != nil {
+		return false, err
+	}
+	ftsState.Store(key, enabled)
+	return enabled, nil
+}
+
+func queryFTSTriggers(db Querier) (bool, error) {
+	var count int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = ?",
+		ftsTriggers[0],
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to query schema: %v", err)
+	}
+	return count > 0, nil
+}
+
+func isNoFTSModule(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "no such module: fts5")
+}
+
+// ftsMatchExpr turns terms into an FTS5 expression requiring all of them. Terms shorter than a trigram cannot be matched by
+// the index and are returned separately so callers can filter them with LIKE.
+func ftsMatchExpr(query []string) (string, []string) {
+	var terms, short []string
+	for _, term := range query {
+		if len([]rune(term)) < 3 {
+			short = append(short, term)
+			continue
+		}
+		terms = append(terms, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+	return strings.Join(terms, " "), short
+}
@@ -0,0 +1,32 @@
+-- Trigram full-text index over file paths. Contentless: rows are joined back
+-- to file_info through rowid = file_info.id.
+CREATE VIRTUAL TABLE IF NOT EXISTS file_info_fts USING fts5 (
+    basename,
+    file_path,
+    content = '',
+    contentless_delete = 1,
+    tokenize = 'trigram'
+);
+
+CREATE TRIGGER IF NOT EXISTS file_info_fts_ai AFTER INSERT ON file_info BEGIN
+    INSERT INTO file_info_fts (rowid, basename, file_path)
+    VALUES (
+        new.id,
+        substr(new.file_path, length(rtrim(new.file_path, replace(new.file_path, '/', ''))) + 1),
+        new.file_path
+    );
+END;
+
+CREATE TRIGGER IF NOT EXISTS file_info_fts_ad AFTER DELETE ON file_info BEGIN
+    DELETE FROM file_info_fts WHERE rowid = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS file_info_fts_au AFTER UPDATE OF file_path ON file_info BEGIN
+    DELETE FROM file_info_fts WHERE rowid = old.id;
+    INSERT INTO file_info_fts (rowid, basename, file_path)
+    VALUES (
+        new.id,
+        substr(new.file_path, length(rtrim(new.file_path, replace(new.file_path, '/', ''))) + 1),
+        new.file_path
+    );
+END;
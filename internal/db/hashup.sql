@@ -30,6 +30,11 @@ CREATE INDEX IF NOT EXISTS idx_file_hash ON file_info (file_hash);
 
 CREATE INDEX IF NOT EXISTS idx_host ON file_info (host);
 
+-- Recent changes are selected by indexing or modification time.
+CREATE INDEX IF NOT EXISTS idx_updated_date ON file_info (updated_date);
+
+CREATE INDEX IF NOT EXISTS idx_modified_date ON file_info (modified_date);
+
 CREATE TABLE IF NOT EXISTS file_tags (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     file_id INTEGER NOT NULL,
@@ -43,3 +48,156 @@ CREATE TABLE IF NOT EXISTS file_notes (
     notes TEXT NOT NULL,
     FOREIGN KEY (file_id) REFERENCES file_info (id)
 );
+
+CREATE INDEX IF NOT EXISTS idx_host_path ON file_info (host, file_path);
+
+-- Directory tree of every host, with the number and total size of the
+-- latest version of the files below each directory. Maintained by the store.
+CREATE TABLE IF NOT EXISTS directories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    host TEXT NOT NULL,
+    path TEXT NOT NULL,
+    parent TEXT NOT NULL, -- empty for root directories
+    files INTEGER NOT NULL DEFAULT 0,
+    size INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (host, path)
+);
+
+CREATE INDEX IF NOT EXISTS idx_directories_parent ON directories (host, parent);
+
+-- API tokens, managed with `hashup api token`. Only a SHA-256 hash of each
+-- token is stored.
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_used DATETIME
+);
+
+-- Events recorded by the store as files are indexed, streamed by the API.
+CREATE TABLE IF NOT EXISTS events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    type TEXT NOT NULL,
+    host TEXT NOT NULL,
+    file_id INTEGER, -- NULL for host events
+    file_path TEXT NOT NULL DEFAULT '',
+    file_hash TEXT NOT NULL DEFAULT '',
+    file_size INTEGER NOT NULL DEFAULT 0,
+    created DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Searches checked by the store against newly indexed files. Matches are
+-- posted to webhook_url, signed with secret.
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    query TEXT NOT NULL,
+    webhook_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    created DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Webhook delivery log.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    search_name TEXT NOT NULL,
+    webhook_url TEXT NOT NULL,
+    event TEXT NOT NULL,
+    file_path TEXT NOT NULL DEFAULT '',
+    host TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL, -- pending, delivered or failed
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_code INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_search ON webhook_deliveries (search_name);
+
+-- Suspected silent corruption: a new version of a file with the size and
+-- modification time of the indexed one but different content. Recorded by
+-- the store, which keeps the previous version as the latest.
+CREATE TABLE IF NOT EXISTS file_integrity_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    host TEXT NOT NULL,
+    file_path TEXT NOT NULL,
+    file_id INTEGER NOT NULL, -- indexed version, with the expected content
+    file_size INTEGER NOT NULL,
+    modified_date DATETIME NOT NULL,
+    expected_hash TEXT NOT NULL,
+    actual_hash TEXT NOT NULL,
+    detected DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_seen DATETIME DEFAULT CURRENT_TIMESTAMP,
+    resolved DATETIME, -- set when the file is stored again without the mismatch
+    FOREIGN KEY (file_id) REFERENCES file_info (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_integrity_events_open ON file_integrity_events (host, file_path) WHERE resolved IS NULL;
+
+-- Unusual activity detected by the store, such as a host changing the
+-- content of many more files than usual.
+CREATE TABLE IF NOT EXISTS anomaly_alerts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    host TEXT NOT NULL,
+    kind TEXT NOT NULL, -- mass_change or new_extensions
+    window_start DATETIME NOT NULL,
+    files INTEGER NOT NULL, -- files counted when the alert was raised
+    baseline REAL NOT NULL DEFAULT 0,
+    threshold INTEGER NOT NULL,
+    extensions TEXT NOT NULL DEFAULT '', -- comma separated
+    paths TEXT NOT NULL DEFAULT '', -- sample of the affected paths, one per line
+    created DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_anomaly_alerts_host ON anomaly_alerts (host);
+
+-- Usual number of changed files per detection window of each host, kept
+-- across store restarts.
+CREATE TABLE IF NOT EXISTS anomaly_baselines (
+    host TEXT PRIMARY KEY,
+    baseline REAL NOT NULL,
+    windows INTEGER NOT NULL, -- windows the baseline was computed from
+    updated DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Hosts whose anomalies are not alerted on until the given time, for known
+-- bulk operations. Host * silences every host.
+CREATE TABLE IF NOT EXISTS anomaly_quiet (
+    host TEXT PRIMARY KEY,
+    until DATETIME NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Content-defined chunks of file contents, sent by scanners with chunking
+-- enabled. See the chunker package.
+CREATE TABLE IF NOT EXISTS chunk_manifests (
+    file_hash TEXT PRIMARY KEY,
+    file_size INTEGER NOT NULL,
+    avg_size INTEGER NOT NULL, -- chunks are only comparable with the same average size
+    chunks INTEGER NOT NULL,
+    created DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS file_chunks (
+    file_hash TEXT NOT NULL,
+    seq INTEGER NOT NULL,
+    chunk_hash TEXT NOT NULL, -- hex encoded xxHash64 of the chunk
+    chunk_offset INTEGER NOT NULL,
+    chunk_size INTEGER NOT NULL,
+    PRIMARY KEY (file_hash, seq)
+) WITHOUT ROWID;
+
+CREATE INDEX IF NOT EXISTS idx_file_chunks_chunk ON file_chunks (chunk_hash);
+
+-- Files removed from disk, reported by scanners when a file found by the
+-- previous scan of a directory is gone.
+CREATE TABLE IF NOT EXISTS file_removals (
+    file_id INTEGER PRIMARY KEY, -- latest version of the file when it was removed
+    removed DATETIME NOT NULL, -- UTC, like file_info.updated_date
+    FOREIGN KEY (file_id) REFERENCES file_info (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_removals_removed ON file_removals (removed);
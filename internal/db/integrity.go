@@ -0,0 +1,189 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/hashup/cmd/hs/types"
+)
+
+// IntegrityEvent is a file suspected of silent corruption: the scanner
+// found content different from the indexed one while the size and
+// modification time did not change.
+type IntegrityEvent struct {
+	ID       int64  `json:"id"`
+	Host     string `json:"host"`
+	FilePath string `json:"file_path"`
+	// FileID is the indexed version of the file, with the expected content.
+	FileID       int64     `json:"-"`
+	FileSize     int64     `json:"file_size"`
+	ModifiedDate time.Time `json:"modified_date"`
+	ExpectedHash string    `json:"expected_hash"`
+	ActualHash   string    `json:"actual_hash"`
+	Detected     time.Time `json:"detected"`
+	// LastSeen is the last time the mismatch was reported.
+	LastSeen time.Time  `json:"last_seen"`
+	Resolved *time.Time `json:"resolved,omitempty"`
+	// GoodCopies are the current copies of the expected content on any
+	// host.
+	GoodCopies []*types.FileResult `json:"good_copies"`
+}
+
+// IntegrityOptions selects the events returned by IntegrityEvents.
+type IntegrityOptions struct {
+	Hosts []string
+	// Resolved includes the events resolved since they were detected.
+	Resolved bool
+	Limit    int
+}
+
+// RecordIntegrityEvent records e, or refreshes the unresolved event of the
+// same file and content, returning whether it was new. ID, Detected and
+// LastSeen are set by the database.
+func RecordIntegrityEvent(db *sql.DB, e *IntegrityEvent) (bool, error) {
+	now := time.Now().UTC().Format(dateLayout)
+	err := db.QueryRow(`
+		UPDATE file_integrity_events SET last_seen = ?
+		WHERE host = ? AND file_path = ? AND actual_hash = ? AND resolved IS NULL
+		RETURNING id, detected, last_seen
+	`, now, e.Host, e.FilePath, e.ActualHash).Scan(&e.ID, &e.Detected, &e.LastSeen)
+	if err == nil {
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, fmt.Errorf("failed to update integrity event: %v", err)
+	}
+
+	err = db.QueryRow(`
+		INSERT INTO file_integrity_events (
+			host, file_path, file_id, file_size, modified_date, expected_hash, actual_hash
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id, detected, last_seen
+	`, e.Host, e.FilePath, e.FileID, e.FileSize, e.ModifiedDate.Format(dateLayout), e.ExpectedHash, e.ActualHash,
+	).Scan(&e.ID, &e.Detected, &e.LastSeen)
+	if err != nil {
+		return false, fmt.Errorf("failed to record integrity event: %v", err)
+	}
+	return true, nil
+}
+
+// ResolveIntegrityEvents resolves the unresolved events of the file at path
+// on host, returning how many there were.
+func ResolveIntegrityEvents(db *sql.DB, host, path string) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE file_integrity_events SET resolved = ?
+		WHERE host = ? AND file_path = ? AND resolved IS NULL
+	`, time.Now().UTC().Format(dateLayout), host, path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve integrity events: %v", err)
+	}
+	return res.RowsAffected()
+}
+
+// UnresolvedIntegrityFiles returns the files with unresolved events.
+func UnresolvedIntegrityFiles(db Querier) ([]TreeRef, error) {
+	rows, err := db.Query("SELECT DISTINCT host, file_path FROM file_integrity_events WHERE resolved IS NULL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query integrity events: %v", err)
+	}
+	defer rows.Close()
+
+	var files []TreeRef
+	for rows.Next() {
+		var f TreeRef
+		if err := rows.Scan(&f.Host, &f.Path); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		files = append(files, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return files, nil
+}
+
+// IntegrityEvents returns the events matching opts by host and path, with
+// the copies of the expected content found elsewhere.
+func IntegrityEvents(db Querier, opts IntegrityOptions) ([]*IntegrityEvent, error) {
+	where := []string{"1 = 1"}
+	var args []any
+	if !opts.Resolved {
+		where = append(where, "resolved IS NULL")
+	}
+	if hosts := nonEmpty(opts.Hosts); len(hosts) > 0 {
+		where = append(where, "host IN ("+placeholders(len(hosts))+")")
+		for _, h := range hosts {
+			args = append(args, h)
+		}
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := db.Query(`
+		SELECT id, host, file_path, file_id, file_size, modified_date, expected_hash, actual_hash,
+			detected, last_seen, resolved
+		FROM file_integrity_events
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY host, file_path, id
+		LIMIT `+fmt.Sprint(limit),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query integrity events: %v", err)
+	}
+	defer rows.Close()
+
+	events := []*IntegrityEvent{}
+	for rows.Next() {
+		e := &IntegrityEvent{}
+		var resolved sql.NullTime
+		err := rows.Scan(&e.ID, &e.Host, &e.FilePath, &e.FileID, &e.FileSize, &e.ModifiedDate,
+			&e.ExpectedHash, &e.ActualHash, &e.Detected, &e.LastSeen, &resolved)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if resolved.Valid {
+			e.Resolved = &resolved.Time
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+	rows.Close()
+
+	for _, e := range events {
+		if e.GoodCopies, err = goodCopies(db, e); err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
+
+// goodCopies returns the current copies of the expected content of e,
+// other than the corrupted file itself.
+func goodCopies(db Querier, e *IntegrityEvent) ([]*types.FileResult, error) {
+	copies, err := queryResults(db, `
+		SELECT fi.file_path, fi.file_size, fi.modified_date, fi.host, fi.extension, fi.file_hash
+		FROM file_info fi
+		WHERE fi.file_hash = ? AND NOT (fi.host = ? AND fi.file_path = ?) AND NOT EXISTS (
+			SELECT 1 FROM file_info n
+			WHERE n.host = fi.host AND n.file_path = fi.file_path AND n.id > fi.id
+		)
+		ORDER BY fi.host, fi.file_path
+	`, e.ExpectedHash, e.Host, e.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	if copies == nil {
+		copies = []*types.FileResult{}
+	}
+	return copies, nil
+}
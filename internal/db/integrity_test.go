@@ -0,0 +1,68 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrityEvents(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/docs/report.pdf", "nas", "pdf", 1, "00ab12cd34ef5678", "2024-03-01 10:00:00")
+	insertFile(t, db, "/backup/report.pdf", "laptop", "pdf", 1, "00ab12cd34ef5678", "2024-03-01 10:00:00")
+	insertFile(t, db, "/photos/cat.jpg", "laptop", "jpg", 2, "ffee000011112222", "2024-03-01 10:00:00")
+
+	modified := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	e := &IntegrityEvent{
+		Host: "nas", FilePath: "/docs/report.pdf", FileID: 1, FileSize: 100, ModifiedDate: modified,
+		ExpectedHash: "00ab12cd34ef5678", ActualHash: "1111111111111111",
+	}
+	isNew, err := RecordIntegrityEvent(db, e)
+	require.NoError(t, err)
+	assert.True(t, isNew)
+	id := e.ID
+
+	isNew, err = RecordIntegrityEvent(db, e)
+	require.NoError(t, err)
+	assert.False(t, isNew)
+	assert.Equal(t, id, e.ID)
+
+	other := &IntegrityEvent{
+		Host: "laptop", FilePath: "/photos/cat.jpg", FileID: 3, FileSize: 100, ModifiedDate: modified,
+		ExpectedHash: "ffee000011112222", ActualHash: "2222222222222222",
+	}
+	_, err = RecordIntegrityEvent(db, other)
+	require.NoError(t, err)
+
+	files, err := UnresolvedIntegrityFiles(db)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []TreeRef{{Host: "nas", Path: "/docs/report.pdf"}, {Host: "laptop", Path: "/photos/cat.jpg"}}, files)
+
+	events, err := IntegrityEvents(db, IntegrityOptions{Hosts: []string{"nas"}})
+	require.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "1111111111111111", events[0].ActualHash)
+		assert.True(t, modified.Equal(events[0].ModifiedDate))
+		assert.Nil(t, events[0].Resolved)
+		if assert.Len(t, events[0].GoodCopies, 1) {
+			assert.Equal(t, "/backup/report.pdf", events[0].GoodCopies[0].FilePath)
+		}
+	}
+
+	n, err := ResolveIntegrityEvents(db, "nas", "/docs/report.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	events, err = IntegrityEvents(db, IntegrityOptions{})
+	require.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "laptop", events[0].Host)
+		assert.Empty(t, events[0].GoodCopies)
+	}
+
+	events, err = IntegrityEvents(db, IntegrityOptions{Resolved: true})
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+}
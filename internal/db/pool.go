@@ -0,0 +1,175 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Querier runs queries. It is implemented by *sql.DB and by the context
+// bound connections returned by Pool.WithContext.
+type Querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// maxCachedStmts limits the prepared statements kept by a Pool. Queries
+// built from user filters vary, the least recently used statements are
+// closed.
+const maxCachedStmts = 256
+
+// Pool is a long-lived connection pool that caches prepared statements.
+type Pool struct {
+	db *sql.DB
+
+	mu       sync.Mutex
+	maxStmts int
+	stmts    map[string]*list.Element
+	// lru holds the cached statements, most recently used first
+	lru *list.List
+}
+
+// cachedStmt is a prepared statement in the Pool cache.
+type cachedStmt struct {
+	query string
+	stmt  *sql.Stmt
+	// refs counts the queries being started with the statement. Evicted
+	// statements are closed by the last one.
+	refs    int
+	evicted bool
+}
+
+// NewPool returns a pool using db.
+func NewPool(db *sql.DB) *Pool {
+	return &Pool{db: db, maxStmts: maxCachedStmts, stmts: map[string]*list.Element{}, lru: list.New()}
+}
+
+// OpenReadOnly opens a read-only pool on the existing database at dbPath.
+// Writes fail with "attempt to write a readonly database".
+func OpenReadOnly(dbPath string, maxConns int) (*Pool, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?mode=ro&_query_only=true&_busy_timeout=5000&_cache_size=-20000", dbPath)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(maxConns)
+	db.SetMaxIdleConns(maxConns)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+
+	return NewPool(db), nil
+}
+
+// DB returns the underlying database.
+func (p *Pool) DB() *sql.DB {
+	return p.db
+}
+
+// WithContext returns a Querier running queries with ctx, so that they are
+// interrupted when it is cancelled.
+func (p *Pool) WithContext(ctx context.Context) Querier {
+	return &ctxQuerier{pool: p, ctx: ctx}
+}
+
+// Close closes the prepared statements and the database.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	for p.lru.Len() > 0 {
+		p.evict(p.lru.Back())
+	}
+	p.mu.Unlock()
+
+	return p.db.Close()
+}
+
+// stmt returns the prepared statement for query and a function to call
+// once the query is started, which keeps the statement open until then.
+func (p *Pool) stmt(ctx context.Context, query string) (*sql.Stmt, func(), error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if el, ok := p.stmts[query]; ok {
+		p.lru.MoveToFront(el)
+		return p.acquire(el.Value.(*cachedStmt))
+	}
+
+	stmt, err := p.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := &cachedStmt{query: query, stmt: stmt}
+	p.stmts[query] = p.lru.PushFront(c)
+	if p.lru.Len() > p.maxStmts {
+		p.evict(p.lru.Back())
+	}
+	return p.acquire(c)
+}
+
+func (p *Pool) acquire(c *cachedStmt) (*sql.Stmt, func(), error) {
+	c.refs++
+	return c.stmt, func() { p.release(c) }, nil
+}
+
+func (p *Pool) release(c *cachedStmt) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c.refs--
+	if c.evicted && c.refs == 0 {
+		c.stmt.Close()
+	}
+}
+
+// evict removes the statement from the cache, closing it unless a query is
+// being started with it. Rows of running queries stay readable.
+func (p *Pool) evict(el *list.Element) {
+	c := p.lru.Remove(el).(*cachedStmt)
+	delete(p.stmts, c.query)
+	c.evicted = true
+	if c.refs == 0 {
+		c.stmt.Close()
+	}
+}
+
+type ctxQuerier struct {
+	pool *Pool
+	ctx  context.Context
+}
+
+func (q *ctxQuerier) Query(query string, args ...any) (*sql.Rows, error) {
+	stmt, release, err := q.pool.stmt(q.ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return stmt.QueryContext(q.ctx, args...)
+}
+
+func (q *ctxQuerier) QueryRow(query string, args ...any) *sql.Row {
+	stmt, release, err := q.pool.stmt(q.ctx, query)
+	if err != nil {
+		// Preparing errors are reported by Scan.
+		return q.pool.db.QueryRowContext(q.ctx, query, args...)
+	}
+	defer release()
+	return stmt.QueryRowContext(q.ctx, args...)
+}
+
+func (q *ctxQuerier) Exec(query string, args ...any) (sql.Result, error) {
+	stmt, release, err := q.pool.stmt(q.ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return stmt.ExecContext(q.ctx, args...)
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenReadOnly(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "hashup.db")
+	rw, err := OpenDatabase(dbPath)
+	require.NoError(t, err)
+	defer rw.Close()
+	_, err = rw.Exec("INSERT INTO file_hashes (id, file_hash) VALUES (1, '00ab12cd34ef5678')")
+	require.NoError(t, err)
+	insertFile(t, rw, "/docs/report.pdf", "nas", "pdf", 1, "00ab12cd34ef5678", "2024-03-01 10:00:00")
+
+	_, err = OpenReadOnly(filepath.Join(t.TempDir(), "missing.db"), 2)
+	assert.Error(t, err)
+
+	pool, err := OpenReadOnly(dbPath, 2)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	db := pool.WithContext(context.Background())
+	results, err := Search(db, "report", nil, nil, 10)
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	_, err = db.Exec("DELETE FROM file_info")
+	assert.ErrorContains(t, err, "readonly")
+
+	// Statements are prepared once and shared
+	for range 3 {
+		_, err = Hosts(db)
+		require.NoError(t, err)
+	}
+	n := len(pool.stmts)
+	_, err = Hosts(db)
+	require.NoError(t, err)
+	assert.Equal(t, n, len(pool.stmts))
+
+	// Cancelled contexts interrupt queries
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Hosts(pool.WithContext(ctx))
+	assert.ErrorContains(t, err, "context canceled")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	var count int64
+	err = pool.WithContext(ctx).QueryRow(`
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n)
+		SELECT COUNT(*) FROM n
+	`).Scan(&count)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestPoolEvictsStatements(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "hashup.db")
+	rw, err := OpenDatabase(dbPath)
+	require.NoError(t, err)
+	defer rw.Close()
+
+	pool, err := OpenReadOnly(dbPath, 2)
+	require.NoError(t, err)
+	defer pool.Close()
+	pool.maxStmts = 2
+	db := pool.WithContext(context.Background())
+
+	// Rows of evicted statements stay readable
+	rows, err := db.Query("SELECT 1 UNION ALL SELECT 2")
+	require.NoError(t, err)
+	first := pool.stmts["SELECT 1 UNION ALL SELECT 2"].Value.(*cachedStmt).stmt
+
+	for _, q := range []string{"SELECT 3", "SELECT 4", "SELECT 3"} {
+		var n int
+		require.NoError(t, db.QueryRow(q).Scan(&n))
+	}
+	assert.Len(t, pool.stmts, 2)
+	assert.NotContains(t, pool.stmts, "SELECT 1 UNION ALL SELECT 2")
+	assert.Equal(t, "SELECT 3", pool.lru.Front().Value.(*cachedStmt).query)
+
+	var values []int
+	for rows.Next() {
+		var v int
+		require.NoError(t, rows.Scan(&v))
+		values = append(values, v)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []int{1, 2}, values)
+
+	// Evicted statements are closed
+	_, err = first.Query()
+	assert.ErrorContains(t, err, "closed")
+
+	// and prepared again when needed
+	var n int
+	require.NoError(t, db.QueryRow("SELECT 1 UNION ALL SELECT 2").Scan(&n))
+	assert.Equal(t, 1, n)
+	assert.NotContains(t, pool.stmts, "SELECT 4")
+}
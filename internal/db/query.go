@@ -0,0 +1,494 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Query is a parsed search expression such as
+//
+//	report ext:pdf,docx host:nas size:>10MB modified:2023-01..2023-06 tag:tax -path:tmp
+//
+// Bare words match file paths, field:value pairs filter on file metadata
+// and a leading "-" negates a word or filter. Comma separated values match
+// any of them.
+type Query struct {
+	// Terms are the positive free text terms, matched against paths.
+	Terms []string
+	// Filters are field filters and negated terms.
+	Filters []Filter
+}
+
+// Filter restricts results by a single field.
+type Filter struct {
+	Field   string
+	Values  []string
+	Negated bool
+
+	sql  string
+	args []any
+}
+
+// QueryError reports a query syntax error and where it happened.
+type QueryError struct {
+	Query string
+	// Pos is the byte offset of the offending token in Query.
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query: %s (at column %d)", e.Msg, e.Pos+1)
+}
+
+// Caret returns the query with a second line pointing at the error.
+func (e *QueryError) Caret() string {
+	return e.Query + "\n" + strings.Repeat(" ", len([]rune(e.Query[:e.Pos]))) + "^"
+}
+
+type queryField func(f *Filter, value string) error
+
+var queryFields = map[string]queryField{
+	"ext":      extFilter,
+	"host":     hostFilter,
+	"size":     sizeFilter,
+	"modified": modifiedFilter,
+	"tag":      tagFilter,
+	"path":     pathFilter,
+	"hash":     hashFilter,
+	"type":     typeFilter,
+}
+
+type token struct {
+	text string
+	pos  int
+}
+
+// ParseQuery parses a search expression.
+func ParseQuery(input string) (*Query, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Query{}
+	for _, tok := range tokens {
+		text := tok.text
+		pos := tok.pos
+		negated := false
+		if strings.HasPrefix(text, "-") && len(text) > 1 {
+			negated = true
+			text = text[1:]
+			pos++
+		}
+
+		field, value, isFilter := splitField(text)
+		if !isFilter {
+			text = unquote(text)
+			if negated {
+				f := Filter{Field: "path", Negated: true}
+				if err := pathFilter(&f, text); err != nil {
+					return nil, &QueryError{Query: input, Pos: pos, Msg: err.Error()}
+				}
+				q.Filters = append(q.Filters, f)
+				continue
+			}
+			q.Terms = append(q.Terms, text)
+			continue
+		}
+
+		compile, ok := queryFields[field]
+		if !ok {
+			return nil, &QueryError{Query: input, Pos: pos, Msg: fmt.Sprintf("unknown field %q", field)}
+		}
+
+		valuePos := pos + len(field) + 1
+		if value == "" {
+			return nil, &QueryError{Query: input, Pos: valuePos, Msg: fmt.Sprintf("missing value for %s", field)}
+		}
+
+		f := Filter{Field: field, Negated: negated}
+		if err := compile(&f, unquote(value)); err != nil {
+			return nil, &QueryError{Query: input, Pos: valuePos, Msg: err.Error()}
+		}
+		q.Filters = append(q.Filters, f)
+	}
+
+	return q, nil
+}
+
+// AddExtensions filters the query by any of the given extensions.
+func (q *Query) AddExtensions(exts ...string) {
+	exts = nonEmpty(exts)
+	if len(exts) == 0 {
+		return
+	}
+	f := Filter{Field: "ext"}
+	extFilter(&f, strings.Join(exts, ","))
+	q.Filters = append(q.Filters, f)
+}
+
+// AddHosts filters the query by any of the given hosts.
+func (q *Query) AddHosts(hosts ...string) {
+	hosts = nonEmpty(hosts)
+	if len(hosts) == 0 {
+		return
+	}
+	f := Filter{Field: "host"}
+	hostFilter(&f, strings.Join(hosts, ","))
+	q.Filters = append(q.Filters, f)
+}
+
+// AddPathPrefix restricts the query to paths starting with prefix.
+func (q *Query) AddPathPrefix(prefix string) {
+	if prefix == "" {
+		return
+	}
+	q.Filters = append(q.Filters, Filter{
+		Field:  "path",
+		Values: []string{prefix},
+		sql:    `fi.file_path LIKE ? ESCAPE '\'`,
+		args:   []any{escapeLike(prefix) + "%"},
+	})
+}
+
+// AddMinSize restricts the query to files of at least size bytes.
+func (q *Query) AddMinSize(size int64) {
+	if size <= 0 {
+		return
+	}
+	q.Filters = append(q.Filters, Filter{
+		Field:  "size",
+		Values: []string{strconv.FormatInt(size, 10)},
+		sql:    "fi.file_size >= ?",
+		args:   []any{size},
+	})
+}
+
+// Where returns the filters as SQL conditions, each prefixed with AND, for a
+// query where file_info is aliased as fi.
+func (q *Query) Where() (string, []any) {
+	var sb strings.Builder
+	var args []any
+	for _, f := range q.Filters {
+		if f.Negated {
+			fmt.Fprintf(&sb, " AND NOT (%s)", f.sql)
+		} else {
+			fmt.Fprintf(&sb, " AND (%s)", f.sql)
+		}
+		args = append(args, f.args...)
+	}
+	return sb.String(), args
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	var sb strings.Builder
+	start := -1
+	quoteStart := -1
+
+	for i, r := range input {
+		switch {
+		case r == '"':
+			if start == -1 {
+				start = i
+			}
+			if quoteStart == -1 {
+				quoteStart = i
+			} else {
+				quoteStart = -1
+			}
+			sb.WriteRune(r)
+		case unicode.IsSpace(r) && quoteStart == -1:
+			if start != -1 {
+				tokens = append(tokens, token{text: sb.String(), pos: start})
+				sb.Reset()
+				start = -1
+			}
+		default:
+			if start == -1 {
+				start = i
+			}
+			sb.WriteRune(r)
+		}
+	}
+
+	if quoteStart != -1 {
+		return nil, &QueryError{Query: input, Pos: quoteStart, Msg: "unterminated quote"}
+	}
+	if start != -1 {
+		tokens = append(tokens, token{text: sb.String(), pos: start})
+	}
+
+	return tokens, nil
+}
+
+// splitField splits field:value tokens. Tokens whose prefix is not a plain
+// word, like "12:30" or quoted text, are not filters.
+func splitField(text string) (string, string, bool) {
+	i := strings.IndexByte(text, ':')
+	if i <= 0 {
+		return "", "", false
+	}
+	for _, r := range text[:i] {
+		if r < 'a' || r > 'z' {
+			return "", "", false
+		}
+	}
+	return text[:i], text[i+1:], true
+}
+
+func unquote(s string) string {
+	return strings.ReplaceAll(s, `"`, "")
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func splitValues(value string) ([]string, error) {
+	values := nonEmpty(strings.Split(value, ","))
+	if len(values) == 0 {
+		return nil, fmt.Errorf("empty value list")
+	}
+	return values, nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+func extFilter(f *Filter, value string) error {
+	values, err := splitValues(value)
+	if err != nil {
+		return err
+	}
+	for i, v := range values {
+		values[i] = strings.TrimPrefix(v, ".")
+		f.args = append(f.args, values[i])
+	}
+	f.Values = values
+	f.sql = fmt.Sprintf("fi.extension COLLATE NOCASE IN (%s)", placeholders(len(values)))
+	return nil
+}
+
+func typeFilter(f *Filter, value string) error {
+	values, err := splitValues(value)
+	if err != nil {
+		return err
+	}
+	if err := extFilter(f, strings.Join(ExpandTypes(values), ",")); err != nil {
+		return err
+	}
+	f.Values = values
+	return nil
+}
+
+func hostFilter(f *Filter, value string) error {
+	values, err := splitValues(value)
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		f.args = append(f.args, v)
+	}
+	f.Values = values
+	f.sql = fmt.Sprintf("fi.host IN (%s)", placeholders(len(values)))
+	return nil
+}
+
+func tagFilter(f *Filter, value string) error {
+	values, err := splitValues(value)
+	if err != nil {
+		return err
+	}
+	var conds []string
+	for _, v := range values {
+		conds = append(conds, "',' || ft.tags || ',' LIKE ?")
+		f.args = append(f.args, "%,"+v+",%")
+	}
+	f.Values = values
+	f.sql = fmt.Sprintf(
+		"EXISTS (SELECT 1 FROM file_tags ft WHERE ft.file_id = fi.id AND (%s))",
+		strings.Join(conds, " OR "),
+	)
+	return nil
+}
+
+func pathFilter(f *Filter, value string) error {
+	values, err := splitValues(value)
+	if err != nil {
+		return err
+	}
+	var conds []string
+	for _, v := range values {
+		conds = append(conds, `fi.file_path LIKE ? ESCAPE '\'`)
+		f.args = append(f.args, "%"+escapeLike(v)+"%")
+	}
+	f.Values = values
+	f.sql = strings.Join(conds, " OR ")
+	return nil
+}
+
+func hashFilter(f *Filter, value string) error {
+	values, err := splitValues(value)
+	if err != nil {
+		return err
+	}
+	var conds []string
+	for _, v := range values {
+		v = strings.ToLower(v)
+		if !isHex(v) {
+			return fmt.Errorf("invalid hash %q", v)
+		}
+		conds = append(conds, "fi.file_hash GLOB ?")
+		f.args = append(f.args, v+"*")
+	}
+	f.Values = values
+	f.sql = strings.Join(conds, " OR ")
+	return nil
+}
+
+func sizeFilter(f *Filter, value string) error {
+	f.Values = []string{value}
+	return rangeFilter(f, "fi.file_size", value, func(s string) (any, any, error) {
+		n, err := humanize.ParseBytes(s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid size %q", s)
+		}
+		// Sizes are exact, so the upper bound is exclusive one byte later
+		return int64(n), int64(n) + 1, nil
+	})
+}
+
+func modifiedFilter(f *Filter, value string) error {
+	f.Values = []string{value}
+	return rangeFilter(f, "fi.modified_date", value, func(s string) (any, any, error) {
+		from, to, err := parseDatePeriod(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		return from.Format(time.DateTime), to.Format(time.DateTime), nil
+	})
+}
+
+// rangeFilter compiles comparisons (>v, >=v, <v, <=v), ranges (a..b, a..,
+// ..b) and plain values against column. bounds returns the inclusive start
+// and exclusive end of the period a value stands for.
+func rangeFilter(f *Filter, column, value string, bounds func(string) (any, any, error)) error {
+	if from, to, ok := strings.Cut(value, ".."); ok {
+		var conds []string
+		if from != "" {
+			start, _, err := bounds(from)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, column+" >= ?")
+			f.args = append(f.args, start)
+		}
+		if to != "" {
+			_, end, err := bounds(to)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, column+" < ?")
+			f.args = append(f.args, end)
+		}
+		if len(conds) == 0 {
+			return fmt.Errorf("empty range")
+		}
+		f.sql = strings.Join(conds, " AND ")
+		return nil
+	}
+
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if !strings.HasPrefix(value, op) {
+			continue
+		}
+		start, end, err := bounds(value[len(op):])
+		if err != nil {
+			return err
+		}
+		switch op {
+		case ">=":
+			f.sql, f.args = column+" >= ?", []any{start}
+		case "<=":
+			f.sql, f.args = column+" < ?", []any{end}
+		case ">":
+			f.sql, f.args = column+" >= ?", []any{end}
+		case "<":
+			f.sql, f.args = column+" < ?", []any{start}
+		default:
+			f.sql, f.args = column+" >= ? AND "+column+" < ?", []any{start, end}
+		}
+		return nil
+	}
+
+	start, end, err := bounds(value)
+	if err != nil {
+		return err
+	}
+	f.sql, f.args = column+" >= ? AND "+column+" < ?", []any{start, end}
+	return nil
+}
+
+// parseDatePeriod parses a year, month, day or timestamp and returns the
+// period it covers.
+func parseDatePeriod(s string) (time.Time, time.Time, error) {
+	layouts := []struct {
+		layout string
+		next   func(time.Time) time.Time
+	}{
+		{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+		{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+		{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+		{"2006-01-02T15:04:05", func(t time.Time) time.Time { return t.Add(time.Second) }},
+	}
+	for _, l := range layouts {
+		if len(s) != len(l.layout) {
+			continue
+		}
+		t, err := time.Parse(l.layout, s)
+		if err == nil {
+			return t, l.next(t), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q, expected YYYY, YYYY-MM or YYYY-MM-DD", s)
+}
+
+// escapeLike escapes LIKE wildcards in s, for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// QuoteValue quotes a filter value when it contains whitespace.
+func QuoteValue(v string) string {
+	if strings.IndexFunc(v, unicode.IsSpace) == -1 {
+		return v
+	}
+	return strconv.Quote(strings.ReplaceAll(v, `"`, ""))
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery(`report "tax return" ext:pdf,.DOCX host:nas size:>10MB modified:2023-01..2023-06 tag:tax -path:tmp -draft`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"report", "tax return"}, q.Terms)
+	require.Len(t, q.Filters, 7)
+
+	assert.Equal(t, "ext", q.Filters[0].Field)
+	assert.Equal(t, []string{"pdf", "DOCX"}, q.Filters[0].Values)
+	assert.Equal(t, []any{"pdf", "DOCX"}, q.Filters[0].args)
+
+	assert.Equal(t, "fi.file_size >= ?", q.Filters[2].sql)
+	assert.Equal(t, []any{int64(10000001)}, q.Filters[2].args)
+
+	assert.Equal(t, "fi.modified_date >= ? AND fi.modified_date < ?", q.Filters[3].sql)
+	assert.Equal(t, []any{"2023-01-01 00:00:00", "2023-07-01 00:00:00"}, q.Filters[3].args)
+
+	assert.True(t, q.Filters[5].Negated)
+	assert.Equal(t, "path", q.Filters[5].Field)
+	assert.True(t, q.Filters[6].Negated)
+	assert.Equal(t, []string{"draft"}, q.Filters[6].Values)
+
+	where, args := q.Where()
+	assert.Contains(t, where, ` AND NOT (fi.file_path LIKE ? ESCAPE '\')`)
+	assert.Len(t, args, 9)
+}
+
+func TestParseQueryRanges(t *testing.T) {
+	tests := []struct {
+		query string
+		sql   string
+		args  []any
+	}{
+		{"size:<=1KB", "fi.file_size < ?", []any{int64(1001)}},
+		{"size:1MiB..", "fi.file_size >= ?", []any{int64(1048576)}},
+		{"modified:2024", "fi.modified_date >= ? AND fi.modified_date < ?", []any{"2024-01-01 00:00:00", "2025-01-01 00:00:00"}},
+		{"modified:<2024-03-02", "fi.modified_date < ?", []any{"2024-03-02 00:00:00"}},
+		{"modified:>2024-03", "fi.modified_date >= ?", []any{"2024-04-01 00:00:00"}},
+		{"modified:..2024-02", "fi.modified_date < ?", []any{"2024-03-01 00:00:00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			require.NoError(t, err)
+			require.Len(t, q.Filters, 1)
+			assert.Equal(t, tt.sql, q.Filters[0].sql)
+			assert.Equal(t, tt.args, q.Filters[0].args)
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{"foo colour:red", 4, `unknown field "colour"`},
+		{"foo ext:", 8, "missing value for ext"},
+		{"size:>lots", 5, `invalid size "lots"`},
+		{"-modified:2023-13", 10, `invalid date "2023-13", expected YYYY, YYYY-MM or YYYY-MM-DD`},
+		{`path:"unfinished`, 5, "unterminated quote"},
+		{"hash:xyz", 5, `invalid hash "xyz"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := ParseQuery(tt.query)
+			var qerr *QueryError
+			require.True(t, errors.As(err, &qerr), "expected a QueryError, got %v", err)
+			assert.Equal(t, tt.pos, qerr.Pos)
+			assert.Equal(t, tt.msg, qerr.Msg)
+		})
+	}
+}
+
+func TestParseQueryNotFilters(t *testing.T) {
+	q, err := ParseQuery(`12:30 "a:b"`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"12:30", "a:b"}, q.Terms)
+	assert.Empty(t, q.Filters)
+}
+
+func TestSearchQueryFilters(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/home/me/report/notes.txt", "laptop", "txt", 1, "00ab12cd34ef5678", "2023-01-03 00:00:00")
+	insertFile(t, db, "/home/me/docs/report.pdf", "laptop", "pdf", 2, "ffee000011112222", "2023-05-01 00:00:00")
+	insertFile(t, db, "/nas/tmp/report.PDF", "nas", "PDF", 2, "ffee000011112222", "2023-02-02 00:00:00")
+	insertFile(t, db, "/nas/photos/img.jpg", "nas", "jpg", 3, "1234567890abcdef", "2024-01-04 00:00:00")
+	_, err := db.Exec("INSERT INTO file_tags (file_id, tags) VALUES (2, 'finance,tax'), (3, 'taxonomy')")
+	require.NoError(t, err)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"report ext:pdf", []string{"/home/me/docs/report.pdf", "/nas/tmp/report.PDF"}},
+		{"report ext:pdf -path:tmp", []string{"/home/me/docs/report.pdf"}},
+		{"ext:pdf,jpg host:nas", []string{"/nas/photos/img.jpg", "/nas/tmp/report.PDF"}},
+		{"modified:2023-01..2023-02", []string{"/nas/tmp/report.PDF", "/home/me/report/notes.txt"}},
+		{"tag:tax", []string{"/home/me/docs/report.pdf"}},
+		{"report -tag:tax ext:pdf", []string{"/nas/tmp/report.PDF"}},
+		{"hash:1234", []string{"/nas/photos/img.jpg"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.expected, paths(t, db, tt.query, nil, nil))
+		})
+	}
+}
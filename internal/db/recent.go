@@ -0,0 +1,270 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Change timestamps used by Recent and Timeline.
+const (
+	// ByIndexed uses the time files were added to the index.
+	ByIndexed = "indexed"
+	// ByModified uses the file modification time reported by the scanner.
+	ByModified = "modified"
+)
+
+// Change statuses. Removals are reported by scanners for files found by
+// the previous scan of a directory and missing from the last one.
+const (
+	ChangeNew      = "new"
+	ChangeModified = "modified"
+	ChangeRemoved  = "removed"
+)
+
+var (
+	// ErrInvalidSince is returned by ParseSince for unknown ages and dates.
+	ErrInvalidSince = errors.New("invalid time")
+	// ErrInvalidBy is returned for unknown RecentOptions.By values.
+	ErrInvalidBy = errors.New("invalid change time")
+)
+
+// dateLayout is the layout of the date columns in file_info.
+const dateLayout = "2006-01-02 15:04:05"
+
+// RecentOptions selects the changes reported by Recent and Timeline.
+type RecentOptions struct {
+	Since time.Time
+	Hosts []string
+	// By is ByIndexed (the default) or ByModified.
+	By    string
+	Limit int
+}
+
+// Change is a file that was added, changed or removed. Removed files are
+// described by their last indexed version.
+type Change struct {
+	Status       string    `json:"status"`
+	FilePath     string    `json:"file_path"`
+	FileSize     int64     `json:"file_size"`
+	ModifiedDate time.Time `json:"modified_date"`
+	// IndexedDate is when the change was stored, for removed files when
+	// the removal was reported.
+	IndexedDate time.Time `json:"indexed_date"`
+	Host        string    `json:"host"`
+	FileHash    string    `json:"file_hash"`
+	// PrevHash and PrevSize describe the previous version of modified
+	// files.
+	PrevHash string `json:"prev_hash,omitempty"`
+	PrevSize int64  `json:"prev_size,omitempty"`
+}
+
+// TimelineDay aggregates the changes of a host on a single day.
+type TimelineDay struct {
+	Day      string `json:"day"`
+	Host     string `json:"host"`
+	New      int64  `json:"new"`
+	Modified int64  `json:"modified"`
+	Removed  int64  `json:"removed"`
+	// Files and Size count the new and modified files.
+	Files int64 `json:"files"`
+	Size  int64 `json:"size"`
+}
+
+// ParseSince parses a relative age such as 30m, 12h, 7d or 2w, or an
+// absolute YYYY-MM-DD or RFC 3339 time, returning the time it refers to.
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, now.Location()); err == nil {
+		return t, nil
+	}
+
+	// time.ParseDuration has no units longer than hours.
+	days := map[string]int{"d": 1, "w": 7}
+	if n := len(s) - 1; n > 0 && days[s[n:]] > 0 {
+		if v, err := strconv.Atoi(s[:n]); err == nil && v >= 0 {
+			return now.AddDate(0, 0, -v*days[s[n:]]), nil
+		}
+	} else if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w %q, expected an age like 7d, 12h or 2w, or a date", ErrInvalidSince, s)
+}
+
+// changesSQL returns a WITH clause defining the changes table, the files
+// indexed or modified and removed since opts.Since. Its rows hold the
+// change and indexing times, the status, the file_info id and the id of the
+// previous version of modified files.
+func changesSQL(opts RecentOptions) (string, []any, error) {
+	var timeExpr, removedExpr, since string
+	switch opts.By {
+	case "", ByIndexed:
+		// Indexing times are stored in UTC, modification times in the
+		// scanning host's local time.
+		timeExpr, since = "fi.updated_date", opts.Since.UTC().Format(dateLayout)
+		removedExpr = "r.removed"
+	case ByModified:
+		timeExpr, since = "fi.modified_date", opts.Since.Local().Format(dateLayout)
+		// Removed files are dated by the removal, there is no later
+		// modification time.
+		removedExpr = "datetime(r.removed, 'localtime')"
+	default:
+		return "", nil, fmt.Errorf("%w %q, expected %s or %s", ErrInvalidBy, opts.By, ByIndexed, ByModified)
+	}
+
+	q := &Query{}
+	q.AddHosts(opts.Hosts...)
+	where, args := q.Where()
+
+	with := `
+		WITH changed AS (
+			SELECT fi.id, fi.host, fi.file_path, ` + timeExpr + ` AS changed, fi.updated_date AS indexed
+			FROM file_info fi
+			WHERE ` + timeExpr + ` >= ?` + where + `
+		), versions AS (
+			SELECT id, LAG(id) OVER (PARTITION BY host, file_path ORDER BY id) AS prev_id
+			FROM file_info
+			WHERE (host, file_path) IN (SELECT host, file_path FROM changed)
+		), changes AS (
+			SELECT c.changed, c.indexed, CASE WHEN v.prev_id IS NULL THEN '` + ChangeNew + `' ELSE '` + ChangeModified + `' END AS status,
+				c.id AS file_id, v.prev_id
+			FROM changed c
+			JOIN versions v ON v.id = c.id
+			UNION ALL
+			SELECT ` + removedExpr + `, r.removed, '` + ChangeRemoved + `', r.file_id, NULL
+			FROM file_removals r
+			JOIN file_info fi ON fi.id = r.file_id
+			WHERE ` + removedExpr + ` >= ?` + where + `
+		)`
+
+	queryArgs := append([]any{since}, args...)
+	queryArgs = append(queryArgs, since)
+	return with, append(queryArgs, args...), nil
+}
+
+// BackfillIndexed sets the indexing time of the file_info rows stored before
+// it was recorded to the time their hash was first seen, so that changes
+// can be selected by the indexed updated_date column. Called by
+// OpenDatabase.
+func BackfillIndexed(db *sql.DB) error {
+	_, err := db.Exec(`
+		UPDATE file_info SET updated_date = (
+			SELECT fh.added FROM file_hashes fh WHERE fh.id = file_info.hash_id
+		)
+		WHERE updated_date IS NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to backfill indexing times: %v", err)
+	}
+	return nil
+}
+
+// RecordRemoval records that the file_info row id, the latest version of a
+// file, was removed from disk at t. It reports whether the removal was not
+// already recorded.
+func RecordRemoval(db *sql.DB, id int64, t time.Time) (bool, error) {
+	res, err := db.Exec(
+		"INSERT INTO file_removals (file_id, removed) VALUES (?, ?) ON CONFLICT (file_id) DO NOTHING",
+		id, t.UTC().Format(dateLayout),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record removal: %v", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Recent returns the files indexed or modified since opts.Since, and those
+// removed since then, newest first. A file is new the first time its path
+// is indexed on a host and modified when a version with different content
+// is indexed.
+func Recent(db Querier, opts RecentOptions) ([]*Change, error) {
+	with, args, err := changesSQL(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := db.Query(with+`
+		SELECT ch.status, fi.file_path, COALESCE(fi.file_size, 0), fi.modified_date,
+			CAST(ch.indexed AS TEXT), fi.host, fi.file_hash,
+			COALESCE(prev.file_hash, ''), COALESCE(prev.file_size, 0)
+		FROM changes ch
+		JOIN file_info fi ON fi.id = ch.file_id
+		LEFT JOIN file_info prev ON prev.id = ch.prev_id
+		ORDER BY ch.changed DESC, ch.file_id DESC, ch.status = '`+ChangeRemoved+`' DESC
+		LIMIT `+fmt.Sprint(limit),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent changes: %v", err)
+	}
+	defer rows.Close()
+
+	changes := []*Change{}
+	for rows.Next() {
+		c := &Change{}
+		var indexed string
+		err := rows.Scan(&c.Status, &c.FilePath, &c.FileSize, &c.ModifiedDate, &indexed,
+			&c.Host, &c.FileHash, &c.PrevHash, &c.PrevSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		c.IndexedDate, _ = time.Parse(dateLayout, indexed)
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return changes, nil
+}
+
+// Timeline returns per day and host counts of the files indexed or
+// modified, and removed, since opts.Since, oldest first.
+func Timeline(db Querier, opts RecentOptions) ([]*TimelineDay, error) {
+	with, args, err := changesSQL(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(with+`
+		SELECT date(ch.changed) AS day, fi.host,
+			SUM(ch.status = '`+ChangeNew+`'), SUM(ch.status = '`+ChangeModified+`'),
+			SUM(ch.status = '`+ChangeRemoved+`'), SUM(ch.status != '`+ChangeRemoved+`'),
+			COALESCE(SUM(CASE WHEN ch.status != '`+ChangeRemoved+`' THEN fi.file_size END), 0)
+		FROM changes ch
+		JOIN file_info fi ON fi.id = ch.file_id
+		GROUP BY day, fi.host
+		ORDER BY day, fi.host`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query timeline: %v", err)
+	}
+	defer rows.Close()
+
+	days := []*TimelineDay{}
+	for rows.Next() {
+		d := &TimelineDay{}
+		if err := rows.Scan(&d.Day, &d.Host, &d.New, &d.Modified, &d.Removed, &d.Files, &d.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		days = append(days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return days, nil
+}
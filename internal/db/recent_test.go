@@ -0,0 +1,167 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	for in, want := range map[string]time.Time{
+		"7d":                   time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC),
+		"2w":                   time.Date(2024, 2, 25, 12, 0, 0, 0, time.UTC),
+		"90m":                  time.Date(2024, 3, 10, 10, 30, 0, 0, time.UTC),
+		"2024-03-01":           time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"2024-03-01T08:00:00Z": time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC),
+	} {
+		got, err := ParseSince(in, now)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	for _, in := range []string{"", "d", "-1d", "yesterday", "-2h"} {
+		_, err := ParseSince(in, now)
+		assert.ErrorIs(t, err, ErrInvalidSince, in)
+	}
+}
+
+func recentDB(t *testing.T) *sql.DB {
+	db := testDB(t)
+	insertFile(t, db, "/old.txt", "nas", "txt", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/report.txt", "nas", "txt", 1, "00ab12cd34ef5678", "2024-03-01 10:00:00")
+	insertFile(t, db, "/new.txt", "nas", "txt", 2, "ffee000011112222", "2024-03-08 10:00:00")
+	insertFile(t, db, "/report.txt", "nas", "txt", 3, "1234567890abcdef", "2024-03-09 09:00:00")
+	insertFile(t, db, "/notes.txt", "laptop", "txt", 2, "ffee000011112222", "2024-03-09 11:00:00")
+	for id, indexed := range []string{
+		"2024-01-02 00:00:00",
+		"2024-03-01 11:00:00",
+		"2024-03-08 11:00:00",
+		"2024-03-09 10:00:00",
+		"2024-03-09 12:00:00",
+	} {
+		_, err := db.Exec("UPDATE file_info SET updated_date = ? WHERE id = ?", indexed, id+1)
+		require.NoError(t, err)
+	}
+	return db
+}
+
+func TestRecent(t *testing.T) {
+	db := recentDB(t)
+	since := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	changes, err := Recent(db, RecentOptions{Since: since})
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	assert.Equal(t, "/notes.txt", changes[0].FilePath)
+	assert.Equal(t, ChangeNew, changes[0].Status)
+	assert.Equal(t, time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC), changes[0].IndexedDate)
+	assert.Equal(t, "/report.txt", changes[1].FilePath)
+	assert.Equal(t, ChangeModified, changes[1].Status)
+	assert.Equal(t, "00ab12cd34ef5678", changes[1].PrevHash)
+	assert.Equal(t, int64(100), changes[1].PrevSize)
+	assert.Equal(t, "/new.txt", changes[2].FilePath)
+	assert.Equal(t, ChangeNew, changes[2].Status)
+
+	changes, err = Recent(db, RecentOptions{Since: since, Hosts: []string{"nas"}, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "/report.txt", changes[0].FilePath)
+
+	// Rows indexed before updated_date was stored use the hash time.
+	_, err = db.Exec("UPDATE file_info SET updated_date = NULL WHERE id = 1")
+	require.NoError(t, err)
+	require.NoError(t, BackfillIndexed(db))
+	changes, err = Recent(db, RecentOptions{Since: since})
+	require.NoError(t, err)
+	assert.Len(t, changes, 4)
+
+	_, err = Recent(db, RecentOptions{Since: since, By: "accessed"})
+	assert.ErrorIs(t, err, ErrInvalidBy)
+}
+
+func TestRecentByModified(t *testing.T) {
+	db := recentDB(t)
+	since := time.Date(2024, 3, 9, 0, 0, 0, 0, time.Local)
+
+	changes, err := Recent(db, RecentOptions{Since: since, By: ByModified})
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "/notes.txt", changes[0].FilePath)
+	assert.Equal(t, "/report.txt", changes[1].FilePath)
+}
+
+func TestRecentRemoved(t *testing.T) {
+	db := recentDB(t)
+	// /report.txt is removed after its second version was indexed
+	removed := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC)
+	recorded, err := RecordRemoval(db, 4, removed)
+	require.NoError(t, err)
+	assert.True(t, recorded)
+	recorded, err = RecordRemoval(db, 4, removed.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, recorded)
+
+	changes, err := Recent(db, RecentOptions{Since: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+	assert.Equal(t, &Change{
+		Status:       ChangeRemoved,
+		FilePath:     "/report.txt",
+		FileSize:     100,
+		ModifiedDate: time.Date(2024, 3, 9, 9, 0, 0, 0, time.UTC),
+		IndexedDate:  removed,
+		Host:         "nas",
+		FileHash:     "1234567890abcdef",
+	}, changes[0])
+	assert.Equal(t, "/report.txt", changes[2].FilePath)
+	assert.Equal(t, ChangeModified, changes[2].Status)
+
+	changes, err = Recent(db, RecentOptions{Since: removed.Add(time.Minute)})
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	changes, err = Recent(db, RecentOptions{Since: removed, Hosts: []string{"laptop"}})
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	changes, err = Recent(db, RecentOptions{Since: removed.Add(-time.Minute).Local(), By: ByModified})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, ChangeRemoved, changes[0].Status)
+
+	days, err := Timeline(db, RecentOptions{Since: time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	assert.Equal(t, []*TimelineDay{
+		{Day: "2024-03-09", Host: "laptop", New: 1, Files: 1, Size: 100},
+		{Day: "2024-03-09", Host: "nas", Modified: 1, Files: 1, Size: 100},
+		{Day: "2024-03-10", Host: "nas", Removed: 1},
+	}, days)
+}
+
+func TestTimeline(t *testing.T) {
+	db := recentDB(t)
+
+	days, err := Timeline(db, RecentOptions{Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	assert.Equal(t, []*TimelineDay{
+		{Day: "2024-03-01", Host: "nas", New: 1, Files: 1, Size: 100},
+		{Day: "2024-03-08", Host: "nas", New: 1, Files: 1, Size: 100},
+		{Day: "2024-03-09", Host: "laptop", New: 1, Files: 1, Size: 100},
+		{Day: "2024-03-09", Host: "nas", Modified: 1, Files: 1, Size: 100},
+	}, days)
+
+	days, err = Timeline(db, RecentOptions{
+		Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local),
+		Hosts: []string{"laptop"},
+		By:    ByModified,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*TimelineDay{
+		{Day: "2024-03-09", Host: "laptop", New: 1, Files: 1, Size: 100},
+	}, days)
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrSavedSearchNotFound is returned when a saved search doesn't exist.
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// SavedSearch is a query checked against newly indexed files, with the
+// webhook notified of matches.
+type SavedSearch struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Query      string    `json:"query"`
+	WebhookURL string    `json:"webhook_url"`
+	Secret     string    `json:"-"`
+	Created    time.Time `json:"created"`
+
+	search *searchSQL
+}
+
+// CreateSavedSearch stores s, generating its webhook secret when empty.
+func CreateSavedSearch(db *sql.DB, s *SavedSearch) error {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" {
+		return fmt.Errorf("saved search name is required")
+	}
+	if _, err := ParseQuery(s.Query); err != nil {
+		return err
+	}
+	u, err := url.Parse(s.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q", s.WebhookURL)
+	}
+
+	if s.Secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return fmt.Errorf("failed to generate secret: %v", err)
+		}
+		s.Secret = hex.EncodeToString(b)
+	}
+
+	err = db.QueryRow(`
+		INSERT INTO saved_searches (name, query, webhook_url, secret) VALUES (?, ?, ?, ?)
+		RETURNING id, created
+	`, s.Name, s.Query, s.WebhookURL, s.Secret).Scan(&s.ID, &s.Created)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("a saved search named %q already exists", s.Name)
+		}
+		return fmt.Errorf("failed to save search: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteSavedSearch deletes the saved search called name.
+func DeleteSavedSearch(db *sql.DB, name string) error {
+	res, err := db.Exec("DELETE FROM saved_searches WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search: %v", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrSavedSearchNotFound, name)
+	}
+	return nil
+}
+
+// GetSavedSearch returns the saved search called name.
+func GetSavedSearch(db Querier, name string) (*SavedSearch, error) {
+	searches, err := querySavedSearches(db, "WHERE name = ?", name)
+	if err != nil {
+		return nil, err
+	}
+	if len(searches) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSavedSearchNotFound, name)
+	}
+	return searches[0], nil
+}
+
+// SavedSearches returns the saved searches sorted by name.
+func SavedSearches(db Querier) ([]*SavedSearch, error) {
+	return querySavedSearches(db, "")
+}
+
+func querySavedSearches(db Querier, where string, args ...any) ([]*SavedSearch, error) {
+	rows, err := db.Query(`
+		SELECT id, name, query, webhook_url, secret, created
+		FROM saved_searches `+where+`
+		ORDER BY name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved searches: %v", err)
+	}
+	defer rows.Close()
+
+	searches := []*SavedSearch{}
+	for rows.Next() {
+		s := &SavedSearch{}
+		if err := rows.Scan(&s.ID, &s.Name, &s.Query, &s.WebhookURL, &s.Secret, &s.Created); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		searches = append(searches, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return searches, nil
+}
+
+// Matches reports whether the file_info row with the given id matches the
+// search query.
+func (s *SavedSearch) Matches(db Querier, fileID int64) (bool, error) {
+	if s.search == nil {
+		q, err := ParseQuery(s.Query)
+		if err != nil {
+			return false, err
+		}
+		s.search = buildSearch(q, false)
+	}
+
+	var match bool
+	args := append(append([]any{}, s.search.args...), fileID)
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 "+s.search.from+" AND fi.id = ?)", args...).Scan(&match)
+	if err != nil {
+		return false, fmt.Errorf("failed to match saved search %s: %v", s.Name, err)
+	}
+	return match, nil
+}
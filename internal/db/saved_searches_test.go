@@ -0,0 +1,91 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedSearches(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/home/me/passwords.kdbx", "laptop", "kdbx", 1, "00ab12cd34ef5678", "2024-03-01 10:00:00")
+	insertFile(t, db, "/data/movie.mkv", "nas", "mkv", 2, "ffee000011112222", "2024-03-01 10:00:00")
+	_, err := db.Exec("UPDATE file_info SET file_size = 60000000000 WHERE id = 2")
+	require.NoError(t, err)
+
+	s := &SavedSearch{Name: "keepass", Query: "ext:kdbx", WebhookURL: "http://localhost:9000/hook"}
+	require.NoError(t, CreateSavedSearch(db, s))
+	assert.NotZero(t, s.ID)
+	assert.Len(t, s.Secret, 64)
+
+	require.NoError(t, CreateSavedSearch(db, &SavedSearch{
+		Name: "huge", Query: "size:>50GB", WebhookURL: "https://example.com/hook", Secret: "s3cret",
+	}))
+
+	for _, bad := range []*SavedSearch{
+		{Name: "keepass", Query: "ext:kdbx", WebhookURL: "http://localhost:9000/hook"},
+		{Name: " ", Query: "ext:kdbx", WebhookURL: "http://localhost:9000/hook"},
+		{Name: "bad-query", Query: "size:huge", WebhookURL: "http://localhost:9000/hook"},
+		{Name: "bad-url", Query: "ext:kdbx", WebhookURL: "ftp://localhost/hook"},
+	} {
+		assert.Error(t, CreateSavedSearch(db, bad), bad.Name)
+	}
+
+	searches, err := SavedSearches(db)
+	require.NoError(t, err)
+	require.Len(t, searches, 2)
+	assert.Equal(t, "huge", searches[0].Name)
+	assert.Equal(t, "s3cret", searches[0].Secret)
+
+	for _, tc := range []struct {
+		search *SavedSearch
+		fileID int64
+		want   bool
+	}{
+		{searches[0], 1, false},
+		{searches[0], 2, true},
+		{searches[1], 1, true},
+		{searches[1], 2, false},
+		{&SavedSearch{Query: "passwords host:laptop"}, 1, true},
+		{&SavedSearch{Query: "passwords -host:laptop"}, 1, false},
+	} {
+		match, err := tc.search.Matches(db, tc.fileID)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, match, "%s %d", tc.search.Query, tc.fileID)
+	}
+
+	s, err = GetSavedSearch(db, "keepass")
+	require.NoError(t, err)
+	assert.Equal(t, "ext:kdbx", s.Query)
+
+	require.NoError(t, DeleteSavedSearch(db, "keepass"))
+	assert.ErrorIs(t, DeleteSavedSearch(db, "keepass"), ErrSavedSearchNotFound)
+	_, err = GetSavedSearch(db, "keepass")
+	assert.ErrorIs(t, err, ErrSavedSearchNotFound)
+}
+
+func TestDeliveries(t *testing.T) {
+	db := testDB(t)
+
+	d := &Delivery{SearchName: "keepass", WebhookURL: "http://localhost:9000/hook", Event: "search.matched", FilePath: "/a.kdbx", Host: "laptop"}
+	require.NoError(t, CreateDelivery(db, d))
+	assert.Equal(t, DeliveryPending, d.Status)
+	require.NoError(t, CreateDelivery(db, &Delivery{SearchName: "huge", WebhookURL: "http://localhost:9000/hook", Event: "ping"}))
+
+	d.Status, d.Attempts, d.ResponseCode, d.Error = DeliveryFailed, 3, 500, "server returned 500"
+	require.NoError(t, UpdateDelivery(db, d))
+
+	deliveries, err := Deliveries(db, "", 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, "huge", deliveries[0].SearchName)
+
+	deliveries, err = Deliveries(db, "keepass", 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, DeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Equal(t, 500, deliveries[0].ResponseCode)
+	assert.Equal(t, "laptop", deliveries[0].Host)
+}
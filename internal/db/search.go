@@ -0,0 +1,354 @@
+package db
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rubiojr/hashup/cmd/hs/types"
+	"github.com/rubiojr/hashup/internal/log"
+)
+
+// Sort keys accepted by SearchOptions.
+const (
+	SortRelevance = "relevance"
+	SortModified  = "modified"
+	SortSize      = "size"
+	SortPath      = "path"
+	SortHost      = "host"
+)
+
+// SortKeys lists the supported sort keys.
+var SortKeys = []string{SortRelevance, SortModified, SortSize, SortPath, SortHost}
+
+var (
+	// ErrInvalidSort is returned for unknown sort keys or orders.
+	ErrInvalidSort = errors.New("invalid sort")
+	// ErrInvalidCursor is returned for malformed cursors, or cursors that
+	// were issued for a different sort order.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+type sortKey struct {
+	expr string
+	desc bool
+}
+
+var sortKeys = map[string]sortKey{
+	SortRelevance: {"m.rank", false},
+	SortModified:  {"COALESCE(CAST(fi.modified_date AS TEXT), '')", true},
+	SortSize:      {"COALESCE(fi.file_size, 0)", true},
+	SortPath:      {"fi.file_path", false},
+	SortHost:      {"fi.host", false},
+}
+
+// SearchOptions configures a paginated search.
+type SearchOptions struct {
+	// Extensions and Hosts restrict results to any of the given values,
+	// in addition to the filters in the query.
+	Extensions []string
+	Hosts      []string
+	// Sort is one of SortKeys. Relevance, the default, orders by
+	// modification date when the full-text index is not available or
+	// the query has no search terms.
+	Sort string
+	// Order is asc or desc. Defaults to descending for modified and size
+	// and ascending otherwise.
+	Order string
+	// Cursor continues a previous search from SearchPage.NextCursor.
+	Cursor string
+	// Count requests the total number of matching files.
+	Count bool
+	Limit int
+}
+
+// SearchPage is a page of search results.
+type SearchPage struct {
+	Results []*types.FileResult `json:"results"`
+	// NextCursor fetches the following page. Empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total is the number of matching files, or -1 when not requested.
+	Total int64 `json:"total"`
+}
+
+// cursor is the position of the last result of a page. It is handed to
+// clients base64 encoded.
+type cursor struct {
+	Sort string `json:"s"`
+	Desc bool   `json:"d"`
+	Key  any    `json:"k"`
+	ID   int64  `json:"i"`
+}
+
+// Search parses query (see ParseQuery) and returns the first limit matching
+// files, additionally restricted to any of the given extensions and hosts.
+func Search(db Querier, query string, extensions []string, hosts []string, limit int) ([]*types.FileResult, error) {
+	page, err := SearchFiles(db, query, SearchOptions{Extensions: extensions, Hosts: hosts, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	return page.Results, nil
+}
+
+// SearchFiles parses query (see ParseQuery) and returns a page of matching
+// files.
+func SearchFiles(db Querier, query string, opts SearchOptions) (*SearchPage, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return SearchQuery(db, q, opts)
+}
+
+// SearchQuery returns a page of files whose path contains every term in q
+// and that pass all of its filters. A single term that looks like a hash
+// prefix also matches file hashes. When the full-text index is available
+// results are ranked by relevance, with basename matches ahead of directory
+// matches.
+func SearchQuery(db Querier, q *Query, opts SearchOptions) (*SearchPage, error) {
+	fq := *q
+	fq.Filters = slices.Clone(q.Filters)
+	fq.AddExtensions(opts.Extensions...)
+	fq.AddHosts(opts.Hosts...)
+
+	enabled, err := ftsEnabled(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled {
+		page, err := searchPage(db, &fq, opts, true)
+		if !isNoFTSModule(err) {
+			return page, err
+		}
+		log.Debug("FTS5 not available in this binary, falling back to LIKE search")
+	}
+
+	return searchPage(db, &fq, opts, false)
+}
+
+// searchSQL is a search split so it can be both counted and paginated:
+// with holds the optional common table expression and from everything
+// from the FROM clause up to the WHERE conditions.
+type searchSQL struct {
+	with   string
+	from   string
+	args   []any
+	ranked bool
+}
+
+func searchPage(db Querier, q *Query, opts SearchOptions, fts bool) (*SearchPage, error) {
+	s := buildSearch(q, fts)
+
+	sortName := opts.Sort
+	if sortName == "" {
+		sortName = SortRelevance
+	}
+	key, ok := sortKeys[sortName]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, expected one of %s", ErrInvalidSort, opts.Sort, strings.Join(SortKeys, ", "))
+	}
+	if sortName == SortRelevance && !s.ranked {
+		sortName = SortModified
+		key = sortKeys[SortModified]
+	}
+	switch strings.ToLower(opts.Order) {
+	case "":
+	case "asc":
+		key.desc = false
+	case "desc":
+		key.desc = true
+	default:
+		return nil, fmt.Errorf("%w order %q, expected asc or desc", ErrInvalidSort, opts.Order)
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	page := &SearchPage{Results: []*types.FileResult{}, Total: -1}
+	if opts.Count {
+		err := db.QueryRow(s.with+"SELECT COUNT(*) "+s.from, s.args...).Scan(&page.Total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count results: %w", err)
+		}
+	}
+
+	from, args := s.from, s.args
+	op, dir := ">", "ASC"
+	if key.desc {
+		op, dir = "<", "DESC"
+	}
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor, sortName)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != sortName || c.Desc != key.desc {
+			return nil, fmt.Errorf("%w: issued for a different sort order", ErrInvalidCursor)
+		}
+		from += fmt.Sprintf(" AND (%[1]s %[2]s ? OR (%[1]s = ? AND fi.id %[2]s ?))", key.expr, op)
+		args = append(slices.Clone(args), c.Key, c.Key, c.ID)
+	}
+
+	rows, err := db.Query(s.with+`
+		SELECT fi.file_path, fi.file_size, fi.modified_date, fi.host, fi.extension, fi.file_hash, fi.id, `+key.expr+`
+		`+from+`
+		ORDER BY `+key.expr+` `+dir+`, fi.id `+dir+`
+		LIMIT `+fmt.Sprint(limit+1),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Database error: %w", err)
+	}
+	defer rows.Close()
+
+	var last cursor
+	for rows.Next() {
+		var result types.FileResult
+		var id int64
+		var k any
+		err := rows.Scan(
+			&result.FilePath,
+			&result.FileSize,
+			&result.ModifiedDate,
+			&result.Host,
+			&result.Extension,
+			&result.FileHash,
+			&id,
+			&k,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Error scanning row: %v", err)
+		}
+		if len(page.Results) == limit {
+			page.NextCursor = encodeCursor(last)
+			break
+		}
+		page.Results = append(page.Results, &result)
+		last = cursor{Sort: sortName, Desc: key.desc, Key: k, ID: id}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error iterating over rows: %v", err)
+	}
+
+	return page, nil
+}
+
+func buildSearch(q *Query, fts bool) *searchSQL {
+	s := &searchSQL{}
+
+	var short []string
+	if fts {
+		var match string
+		match, short = ftsMatchExpr(q.Terms)
+		fts = match != ""
+		if fts {
+			s.args = append(s.args, match)
+		}
+	}
+
+	if fts {
+		s.ranked = true
+		s.with = `
+			WITH matches (id, rank) AS MATERIALIZED (
+				SELECT rowid, bm25(file_info_fts, 10.0, 1.0)
+				FROM file_info_fts
+				WHERE file_info_fts MATCH ?
+		`
+		if hash, ok := hashPrefix(q.Terms); ok {
+			s.with += `
+				UNION ALL
+				SELECT id, -1e9 FROM file_info WHERE file_hash GLOB ?
+			`
+			s.args = append(s.args, hash+"*")
+		}
+		s.with += `
+			)
+		`
+		s.from = `
+			FROM (SELECT id, MIN(rank) AS rank FROM matches GROUP BY id) m
+			JOIN file_info fi ON fi.id = m.id
+			WHERE 1 = 1
+		`
+		for _, term := range short {
+			s.from += " AND fi.file_path LIKE ?"
+			s.args = append(s.args, "%"+term+"%")
+		}
+	} else {
+		s.from = `
+			FROM file_info fi
+			WHERE 1 = 1
+		`
+		if hash, ok := hashPrefix(q.Terms); ok {
+			s.from += " AND (fi.file_path LIKE ? OR fi.file_hash GLOB ?)"
+			s.args = append(s.args, "%"+q.Terms[0]+"%", hash+"*")
+		} else {
+			for _, term := range q.Terms {
+				s.from += " AND fi.file_path LIKE ?"
+				s.args = append(s.args, "%"+term+"%")
+			}
+		}
+	}
+
+	where, wargs := q.Where()
+	s.from += where
+	s.args = append(s.args, wargs...)
+
+	return s
+}
+
+// hashPrefix returns the lowercased term when terms is a single word that
+// could be the start of a file hash.
+func hashPrefix(terms []string) (string, bool) {
+	if len(terms) != 1 || len(terms[0]) < 4 || len(terms[0]) > 16 {
+		return "", false
+	}
+	term := strings.ToLower(terms[0])
+	return term, isHex(term)
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor decodes s, converting the sort key back to the type the
+// database returns for sortName so comparisons match.
+func decodeCursor(s, sortName string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	switch k := c.Key.(type) {
+	case json.Number:
+		if sortName == SortRelevance {
+			c.Key, err = k.Float64()
+		} else {
+			c.Key, err = k.Int64()
+		}
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	case string:
+	default:
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
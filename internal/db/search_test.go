@@ -0,0 +1,126 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// allPages follows cursors until the last page, returning the paths seen.
+func allPages(t *testing.T, db *sql.DB, query string, opts SearchOptions) ([]string, int) {
+	t.Helper()
+	var p []string
+	pages := 0
+	for {
+		page, err := SearchFiles(db, query, opts)
+		require.NoError(t, err)
+		pages++
+		for _, r := range page.Results {
+			p = append(p, r.FilePath)
+		}
+		if page.NextCursor == "" {
+			return p, pages
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	db := testDB(t)
+	for i := 0; i < 7; i++ {
+		// Two files share each modification date to exercise tie breaking.
+		insertFile(t, db, fmt.Sprintf("/data/file-%d.txt", i), "nas", "txt", 1, "00ab12cd34ef5678",
+			fmt.Sprintf("2024-01-%02d 00:00:00", i/2+1))
+	}
+	_, err := db.Exec("UPDATE file_info SET file_size = id * 10")
+	require.NoError(t, err)
+
+	t.Run("pages cover every result once", func(t *testing.T) {
+		for _, sort := range SortKeys {
+			for _, order := range []string{"asc", "desc"} {
+				p, pages := allPages(t, db, "file", SearchOptions{Sort: sort, Order: order, Limit: 3})
+				assert.Len(t, p, 7, "%s %s", sort, order)
+				assert.ElementsMatch(t, p, uniq(p), "%s %s", sort, order)
+				assert.Equal(t, 3, pages, "%s %s", sort, order)
+			}
+		}
+	})
+
+	t.Run("sort by size", func(t *testing.T) {
+		p, _ := allPages(t, db, "file", SearchOptions{Sort: SortSize, Limit: 2})
+		assert.Equal(t, "/data/file-6.txt", p[0])
+		assert.Equal(t, "/data/file-0.txt", p[6])
+
+		p, _ = allPages(t, db, "file", SearchOptions{Sort: SortSize, Order: "asc", Limit: 2})
+		assert.Equal(t, "/data/file-0.txt", p[0])
+	})
+
+	t.Run("sort by path", func(t *testing.T) {
+		p, _ := allPages(t, db, "ext:txt", SearchOptions{Sort: SortPath, Limit: 4})
+		assert.Equal(t, []string{
+			"/data/file-0.txt", "/data/file-1.txt", "/data/file-2.txt", "/data/file-3.txt",
+			"/data/file-4.txt", "/data/file-5.txt", "/data/file-6.txt",
+		}, p)
+	})
+
+	t.Run("total", func(t *testing.T) {
+		page, err := SearchFiles(db, "file", SearchOptions{Count: true, Limit: 2, Hosts: []string{"nas"}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), page.Total)
+		assert.Len(t, page.Results, 2)
+
+		page, err = SearchFiles(db, "file", SearchOptions{Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, int64(-1), page.Total)
+	})
+
+	t.Run("invalid sort and cursor", func(t *testing.T) {
+		_, err := SearchFiles(db, "file", SearchOptions{Sort: "color"})
+		assert.ErrorIs(t, err, ErrInvalidSort)
+
+		_, err = SearchFiles(db, "file", SearchOptions{Order: "up"})
+		assert.ErrorIs(t, err, ErrInvalidSort)
+
+		_, err = SearchFiles(db, "file", SearchOptions{Cursor: "not a cursor"})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+
+		page, err := SearchFiles(db, "file", SearchOptions{Sort: SortSize, Limit: 2})
+		require.NoError(t, err)
+		_, err = SearchFiles(db, "file", SearchOptions{Sort: SortPath, Cursor: page.NextCursor})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
+
+func uniq(s []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func TestSearchFacets(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/home/me/report.pdf", "laptop", "pdf", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/home/me/report.PDF", "laptop", "PDF", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/nas/report.jpg", "nas", "jpg", 2, "ffee000011112222", "2024-01-01 00:00:00")
+	insertFile(t, db, "/nas/report.xyz", "nas", "xyz", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+	insertFile(t, db, "/nas/other.jpg", "nas", "jpg", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+
+	f, err := SearchFacets(db, "report", SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []FacetCount{{"laptop", 2}, {"nas", 2}}, f.Hosts)
+	assert.Equal(t, []FacetCount{{"pdf", 2}, {"jpg", 1}, {"xyz", 1}}, f.Extensions)
+	assert.Equal(t, []FacetCount{{"document", 2}, {"image", 1}}, f.Types)
+
+	f, err = SearchFacets(db, "report -host:laptop", SearchOptions{Extensions: []string{"jpg"}})
+	require.NoError(t, err)
+	assert.Equal(t, []FacetCount{{"nas", 1}}, f.Hosts)
+}
@@ -0,0 +1,212 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rubiojr/hashup/cmd/hs/types"
+)
+
+// HostStat is the number and total size of the files indexed on a host.
+type HostStat struct {
+	Host  string `json:"host"`
+	Files int64  `json:"files"`
+	Size  int64  `json:"size"`
+}
+
+// Hosts returns the indexed hosts with their file counts and sizes.
+func Hosts(db Querier) ([]*HostStat, error) {
+	query := `
+		SELECT host, COUNT(*) as count, COALESCE(SUM(file_size), 0) AS total_size
+		FROM file_info
+		GROUP BY host
+		ORDER BY host
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query database: %v", err)
+	}
+	defer rows.Close()
+
+	hosts := []*HostStat{}
+	for rows.Next() {
+		var h HostStat
+		err := rows.Scan(&h.Host, &h.Files, &h.Size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		hosts = append(hosts, &h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return hosts, nil
+}
+
+type ExtensionStat struct {
+	Extension string `json:"extension"`
+	Count     int64  `json:"count"`
+	Size      int64  `json:"size"`
+	SizeHuman string `json:"size_human"`
+}
+
+type ExtensionStats struct {
+	Stats      []*ExtensionStat `json:"stats"`
+	TotalCount int64            `json:"total_count"`
+	TotalSize  int64            `json:"total_size"`
+}
+
+// Stats is the summary of the top extensions returned by
+// ExtensionStats.Summary.
+type Stats struct {
+	Host           string           `json:"host,omitempty"`
+	Extensions     []*ExtensionStat `json:"extensions"`
+	TotalCount     int64            `json:"total_count"`
+	TotalSize      int64            `json:"total_size"`
+	Count          int64            `json:"count"`
+	Size           int64            `json:"size"`
+	TotalSizeHuman string           `json:"total_size_human"`
+	Limit          int              `json:"limit"`
+	OtherCount     int64            `json:"other_count,omitempty"`
+	OtherSize      int64            `json:"other_size,omitempty"`
+	OtherSizeHuman string           `json:"other_size_human,omitempty"`
+}
+
+// FileStats returns the number and size of the indexed files by extension,
+// optionally restricted to host. orderBy is one of file_size (or size),
+// count and extension.
+func FileStats(db Querier, orderBy string, descending bool, host string) (*ExtensionStats, error) {
+	validColumns := map[string]string{
+		"file_size": "total_size",
+		"size":      "total_size",
+		"count":     "count",
+		"extension": "extension",
+	}
+
+	column, ok := validColumns[orderBy]
+	if !ok {
+		column = "total_size"
+	}
+
+	sortOrder := "ASC"
+	if descending {
+		sortOrder = "DESC"
+	}
+
+	where := ""
+	var args []any
+	if host != "" {
+		where = "WHERE host = ?"
+		args = append(args, host)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT extension, COUNT(*) as count, COALESCE(SUM(file_size), 0) AS total_size
+		FROM file_info
+		%s
+		GROUP BY extension COLLATE NOCASE
+		ORDER BY %s %s
+	`, where, column, sortOrder)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query database: %v", err)
+	}
+	defer rows.Close()
+
+	all := &ExtensionStats{
+		TotalSize:  0,
+		TotalCount: 0,
+		Stats:      []*ExtensionStat{},
+	}
+
+	for rows.Next() {
+		var count, sum int64
+		var extension string
+
+		err := rows.Scan(&extension, &count, &sum)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		all.TotalCount += count
+		all.TotalSize += sum
+
+		if extension == "" || extension == "unknown" {
+			continue
+		}
+
+		all.Stats = append(all.Stats, &ExtensionStat{
+			Extension: strings.ToLower(extension),
+			Count:     count,
+			Size:      sum,
+			SizeHuman: humanize.Bytes(uint64(sum)),
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return all, nil
+}
+
+// Summary returns the first limit extensions, grouping the rest as other.
+func (s *ExtensionStats) Summary(host string, limit int) *Stats {
+	stats := s.Stats
+	count := len(s.Stats)
+
+	var otherCount, otherSize int64
+	if len(stats) > limit {
+		for i := limit; i < len(stats); i++ {
+			otherCount += stats[i].Count
+			otherSize += stats[i].Size
+		}
+		// Trim the stats list to the limit
+		stats = stats[:limit]
+	}
+
+	if count > limit {
+		count = limit
+	}
+
+	response := &Stats{
+		Host:           host,
+		Extensions:     stats,
+		Count:          int64(count),
+		Size:           s.TotalSize - otherSize,
+		TotalCount:     s.TotalCount,
+		TotalSize:      s.TotalSize,
+		TotalSizeHuman: humanize.Bytes(uint64(s.TotalSize)),
+		Limit:          limit,
+	}
+
+	// Include "Other" category in the response if there are items beyond the limit
+	if otherCount > 0 {
+		response.OtherCount = otherCount
+		response.OtherSize = otherSize
+		response.OtherSizeHuman = humanize.Bytes(uint64(otherSize))
+	}
+
+	return response
+}
+
+// LargeFiles returns the files bigger than threshold bytes, largest first.
+// A limit of zero or less returns all of them.
+func LargeFiles(db Querier, threshold int64, limit int) ([]*types.FileResult, error) {
+	query := `
+		SELECT file_path, file_size, modified_date, host, extension, file_hash
+		FROM file_info
+		WHERE file_size > ?
+		ORDER BY file_size DESC
+	`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	return queryResults(db, query, threshold)
+}
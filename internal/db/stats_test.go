@@ -0,0 +1,37 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStats(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/a.txt", "nas", "txt", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+	insertFile(t, db, "/b.TXT", "nas", "TXT", 2, "ffee000011112222", "2024-01-01 00:00:00")
+	insertFile(t, db, "/c.pdf", "nas", "pdf", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+	insertFile(t, db, "/d", "laptop", "", 3, "1234567890abcdef", "2024-01-01 00:00:00")
+
+	hosts, err := Hosts(db)
+	require.NoError(t, err)
+	assert.Equal(t, []*HostStat{{Host: "laptop", Files: 1, Size: 100}, {Host: "nas", Files: 3, Size: 300}}, hosts)
+
+	stats, err := FileStats(db, "count", true, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), stats.TotalCount)
+	require.Len(t, stats.Stats, 2)
+	assert.Equal(t, "txt", stats.Stats[0].Extension)
+	assert.Equal(t, int64(2), stats.Stats[0].Count)
+
+	summary := stats.Summary("", 1)
+	assert.Len(t, summary.Extensions, 1)
+	assert.Equal(t, int64(1), summary.OtherCount)
+	assert.Equal(t, int64(100), summary.OtherSize)
+
+	stats, err = FileStats(db, "extension", false, "laptop")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.TotalCount)
+	assert.Empty(t, stats.Stats)
+}
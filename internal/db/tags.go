@@ -0,0 +1,118 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrFileNotFound is returned when a file is not in the index.
+var ErrFileNotFound = errors.New("file not found")
+
+// FileID returns the id of the latest indexed version of path on host with
+// the given content hash.
+func FileID(db Querier, host, path, hash string) (int64, error) {
+	var id int64
+	err := db.QueryRow(`
+		SELECT id FROM file_info
+		WHERE host = ? AND file_path = ? AND file_hash = ?
+		ORDER BY id DESC LIMIT 1
+	`, host, path, hash).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: %s:%s", ErrFileNotFound, host, path)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query file: %v", err)
+	}
+	return id, nil
+}
+
+// FileTags returns the sorted tags of a file.
+func FileTags(db Querier, fileID int64) ([]string, error) {
+	var tags string
+	err := db.QueryRow("SELECT tags FROM file_tags WHERE file_id = ? LIMIT 1", fileID).Scan(&tags)
+	if err == sql.ErrNoRows {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %v", err)
+	}
+
+	return splitTags(tags), nil
+}
+
+// SetTags replaces the tags of a file. An empty list removes them.
+func SetTags(db *sql.DB, fileID int64, tags []string) error {
+	tags = splitTags(strings.Join(tags, ","))
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var tagID int64
+	err = tx.QueryRow("SELECT id FROM file_tags WHERE file_id = ? LIMIT 1", fileID).Scan(&tagID)
+	switch {
+	case err == sql.ErrNoRows:
+		if len(tags) > 0 {
+			_, err = tx.Exec("INSERT INTO file_tags (file_id, tags) VALUES (?, ?)", fileID, strings.Join(tags, ","))
+		} else {
+			err = nil
+		}
+	case err != nil:
+		return fmt.Errorf("failed to query existing tags: %v", err)
+	case len(tags) == 0:
+		_, err = tx.Exec("DELETE FROM file_tags WHERE id = ?", tagID)
+	default:
+		_, err = tx.Exec("UPDATE file_tags SET tags = ? WHERE id = ?", strings.Join(tags, ","), tagID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save tags: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// splitTags splits comma separated tags, dropping blanks and duplicates.
+func splitTags(s string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		if t != "" && !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// AllTags returns every tag in use, sorted.
+func AllTags(db Querier) ([]string, error) {
+	rows, err := db.Query("SELECT DISTINCT tags FROM file_tags")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query database: %v", err)
+	}
+	defer rows.Close()
+
+	var all []string
+	for rows.Next() {
+		var tags string
+		if err := rows.Scan(&tags); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		all = append(all, tags)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return splitTags(strings.Join(all, ",")), nil
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetTags(t *testing.T) {
+	db := testDB(t)
+	insertFile(t, db, "/home/me/taxes.pdf", "laptop", "pdf", 1, "00ab12cd34ef5678", "2024-01-01 00:00:00")
+
+	_, err := FileID(db, "nas", "/home/me/taxes.pdf", "00ab12cd34ef5678")
+	assert.ErrorIs(t, err, ErrFileNotFound)
+
+	id, err := FileID(db, "laptop", "/home/me/taxes.pdf", "00ab12cd34ef5678")
+	require.NoError(t, err)
+
+	tags, err := FileTags(db, id)
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+
+	require.NoError(t, SetTags(db, id, []string{"tax", " 2024", "tax,important"}))
+	tags, err = FileTags(db, id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024", "important", "tax"}, tags)
+	assert.Equal(t, []string{"/home/me/taxes.pdf"}, paths(t, db, "tag:important", nil, nil))
+
+	require.NoError(t, SetTags(db, id, nil))
+	tags, err = FileTags(db, id)
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+}
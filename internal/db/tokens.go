@@ -0,0 +1,131 @@
+package db
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// tokenPrefix makes API tokens easy to recognize, e.g. by secret scanners.
+const tokenPrefix = "hsk_"
+
+var (
+	// ErrInvalidToken is returned when checking unknown or revoked tokens.
+	ErrInvalidToken = errors.New("invalid API token")
+	// ErrTokenNotFound is returned when revoking a token that doesn't exist.
+	ErrTokenNotFound = errors.New("API token not found")
+)
+
+// APIToken describes an API token. The token itself is only known when it
+// is created.
+type APIToken struct {
+	Name     string     `json:"name"`
+	Created  time.Time  `json:"created"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+// CreateToken creates a new API token called name and returns it.
+func CreateToken(db *sql.DB, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("token name is required")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	_, err := db.Exec("INSERT INTO api_tokens (name, token_hash) VALUES (?, ?)", name, hashToken(token))
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return "", fmt.Errorf("a token named %q already exists", name)
+		}
+		return "", fmt.Errorf("failed to save token: %v", err)
+	}
+
+	return token, nil
+}
+
+// RevokeToken deletes the API token called name.
+func RevokeToken(db *sql.DB, name string) error {
+	res, err := db.Exec("DELETE FROM api_tokens WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete token: %v", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrTokenNotFound, name)
+	}
+	return nil
+}
+
+// Tokens returns the API tokens sorted by name.
+func Tokens(db *sql.DB) ([]*APIToken, error) {
+	rows, err := db.Query("SELECT name, created, last_used FROM api_tokens ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tokens: %v", err)
+	}
+	defer rows.Close()
+
+	tokens := []*APIToken{}
+	for rows.Next() {
+		t := &APIToken{}
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&t.Name, &t.Created, &lastUsed); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if lastUsed.Valid {
+			t.LastUsed = &lastUsed.Time
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return tokens, nil
+}
+
+// CheckToken returns the name of the API token, or ErrInvalidToken if it
+// doesn't exist. Checking only reads the database, see TouchToken.
+func CheckToken(db Querier, token string) (string, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return "", ErrInvalidToken
+	}
+
+	var name string
+	err := db.QueryRow("SELECT name FROM api_tokens WHERE token_hash = ?", hashToken(token)).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", ErrInvalidToken
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check token: %v", err)
+	}
+
+	return name, nil
+}
+
+// TouchToken records the use of the API token called name.
+func TouchToken(db *sql.DB, name string) error {
+	_, err := db.Exec("UPDATE api_tokens SET last_used = CURRENT_TIMESTAMP WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("failed to record token use: %v", err)
+	}
+	return nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	db := testDB(t)
+
+	token, err := CreateToken(db, "app")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, "hsk_"))
+
+	_, err = CreateToken(db, "app")
+	assert.ErrorContains(t, err, "already exists")
+	_, err = CreateToken(db, " ")
+	assert.Error(t, err)
+
+	tokens, err := Tokens(db)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, "app", tokens[0].Name)
+	assert.Nil(t, tokens[0].LastUsed)
+
+	name, err := CheckToken(db, token)
+	require.NoError(t, err)
+	assert.Equal(t, "app", name)
+	tokens, err = Tokens(db)
+	require.NoError(t, err)
+	assert.Nil(t, tokens[0].LastUsed)
+
+	require.NoError(t, TouchToken(db, name))
+	tokens, err = Tokens(db)
+	require.NoError(t, err)
+	assert.NotNil(t, tokens[0].LastUsed)
+
+	_, err = CheckToken(db, token+"x")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = CheckToken(db, "")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	require.NoError(t, RevokeToken(db, "app"))
+	_, err = CheckToken(db, token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.ErrorIs(t, RevokeToken(db, "app"), ErrTokenNotFound)
+}
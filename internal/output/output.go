@@ -0,0 +1,216 @@
+// Package output renders command results as human readable tables or in
+// machine readable formats.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Supported output formats.
+const (
+	Table    = "table"
+	JSON     = "json"
+	NDJSON   = "ndjson"
+	CSV      = "csv"
+	Null     = "null"
+	Template = "template"
+)
+
+// Formats lists the formats that can be selected by name.
+var Formats = []string{Table, JSON, NDJSON, CSV, Null}
+
+// Bytes is a size printed in human readable form in tables and as a plain
+// number everywhere else.
+type Bytes int64
+
+func (b Bytes) String() string {
+	return humanize.Bytes(uint64(b))
+}
+
+// Column describes a table or CSV column.
+type Column[T any] struct {
+	Header string
+	Value  func(T) any
+}
+
+// Spec describes how to render a list of rows.
+type Spec[T any] struct {
+	Columns []Column[T]
+	// Key returns the value printed by the null delimited format, usually
+	// a file path.
+	Key func(T) string
+	// Document, when set, is encoded instead of the rows in JSON output.
+	Document any
+	// Text, when set, replaces the generic table output.
+	Text func(io.Writer) error
+}
+
+// Printer writes rows in the selected format.
+type Printer struct {
+	w      io.Writer
+	format string
+	tmpl   *template.Template
+}
+
+// New returns a printer for format. A non-empty tmpl selects the Go
+// template format, executed once per row.
+func New(w io.Writer, format, tmpl string) (*Printer, error) {
+	p := &Printer{w: w, format: format}
+	if tmpl != "" {
+		t, err := template.New("format").Funcs(template.FuncMap{
+			"bytes": func(n int64) string { return humanize.Bytes(uint64(n)) },
+			"json": func(v any) (string, error) {
+				b, err := json.Marshal(v)
+				return string(b), err
+			},
+		}).Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format template: %v", err)
+		}
+		p.format = Template
+		p.tmpl = t
+		return p, nil
+	}
+
+	if p.format == "" {
+		p.format = Table
+	}
+	for _, f := range Formats {
+		if f == p.format {
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// Format returns the selected format.
+func (p *Printer) Format() string {
+	return p.format
+}
+
+// Write renders rows according to spec.
+func Write[T any](p *Printer, rows []T, spec Spec[T]) error {
+	switch p.format {
+	case JSON:
+		var v any = rows
+		if spec.Document != nil {
+			v = spec.Document
+		} else if rows == nil {
+			v = []T{}
+		}
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case NDJSON:
+		enc := json.NewEncoder(p.w)
+		for _, r := range rows {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case CSV:
+		return writeCSV(p.w, rows, spec.Columns)
+	case Null:
+		if spec.Key == nil {
+			return fmt.Errorf("null delimited output is not supported by this command")
+		}
+		for _, r := range rows {
+			if _, err := io.WriteString(p.w, spec.Key(r)+"\x00"); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Template:
+		for _, r := range rows {
+			if err := p.tmpl.Execute(p.w, r); err != nil {
+				return fmt.Errorf("failed to execute format template: %v", err)
+			}
+			if _, err := io.WriteString(p.w, "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		if spec.Text != nil {
+			return spec.Text(p.w)
+		}
+		return writeTable(p.w, rows, spec.Columns)
+	}
+}
+
+func writeTable[T any](w io.Writer, rows []T, columns []Column[T]) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = c.Header
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	values := make([]string, len(columns))
+	for _, r := range rows {
+		for i, c := range columns {
+			values[i] = tableValue(c.Value(r))
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func writeCSV[T any](w io.Writer, rows []T, columns []Column[T]) error {
+	cw := csv.NewWriter(w)
+
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = strings.ToLower(strings.ReplaceAll(c.Header, " ", "_"))
+	}
+	if err := cw.Write(headers); err != nil {
+		return err
+	}
+
+	values := make([]string, len(columns))
+	for _, r := range rows {
+		for i, c := range columns {
+			values[i] = rawValue(c.Value(r))
+		}
+		if err := cw.Write(values); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func tableValue(v any) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(time.DateTime)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func rawValue(v any) string {
+	switch v := v.(type) {
+	case Bytes:
+		return strconv.FormatInt(int64(v), 10)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
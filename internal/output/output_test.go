@@ -0,0 +1,92 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type row struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+var rows = []*row{
+	{Path: "/a b.txt", Size: 2048},
+	{Path: "/c,d.txt", Size: 1},
+}
+
+var spec = Spec[*row]{
+	Columns: []Column[*row]{
+		{Header: "SIZE", Value: func(r *row) any { return Bytes(r.Size) }},
+		{Header: "FILE PATH", Value: func(r *row) any { return r.Path }},
+	},
+	Key: func(r *row) string { return r.Path },
+}
+
+func render(t *testing.T, format, tmpl string, s Spec[*row]) string {
+	t.Helper()
+	var buf bytes.Buffer
+	p, err := New(&buf, format, tmpl)
+	require.NoError(t, err)
+	require.NoError(t, Write(p, rows, s))
+	return buf.String()
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		tmpl   string
+		want   string
+	}{
+		{"table", Table, "", "SIZE    FILE PATH\n2.0 kB  /a b.txt\n1 B     /c,d.txt\n"},
+		{"default", "", "", "SIZE    FILE PATH\n2.0 kB  /a b.txt\n1 B     /c,d.txt\n"},
+		{"json", JSON, "", "[\n  {\n    \"path\": \"/a b.txt\",\n    \"size\": 2048\n  },\n  {\n    \"path\": \"/c,d.txt\",\n    \"size\": 1\n  }\n]\n"},
+		{"ndjson", NDJSON, "", "{\"path\":\"/a b.txt\",\"size\":2048}\n{\"path\":\"/c,d.txt\",\"size\":1}\n"},
+		{"csv", CSV, "", "size,file_path\n2048,/a b.txt\n1,\"/c,d.txt\"\n"},
+		{"null", Null, "", "/a b.txt\x00/c,d.txt\x00"},
+		{"template", "", "{{.Path}} {{bytes .Size}}", "/a b.txt 2.0 kB\n/c,d.txt 1 B\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, render(t, tt.format, tt.tmpl, spec))
+		})
+	}
+}
+
+func TestWriteDocumentAndText(t *testing.T) {
+	s := spec
+	s.Document = map[string]int{"count": 2}
+	s.Text = func(w io.Writer) error {
+		_, err := io.WriteString(w, "2 files\n")
+		return err
+	}
+
+	assert.Equal(t, "{\n  \"count\": 2\n}\n", render(t, JSON, "", s))
+	assert.Equal(t, "2 files\n", render(t, Table, "", s))
+	assert.Equal(t, "{\"path\":\"/a b.txt\",\"size\":2048}\n{\"path\":\"/c,d.txt\",\"size\":1}\n", render(t, NDJSON, "", s))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(io.Discard, "yaml", "")
+	assert.ErrorContains(t, err, "unknown output format")
+
+	_, err = New(io.Discard, "", "{{.Path")
+	assert.ErrorContains(t, err, "invalid format template")
+
+	p, err := New(io.Discard, JSON, "{{.Path}}")
+	require.NoError(t, err)
+	assert.Equal(t, Template, p.Format())
+}
+
+func TestNullWithoutKey(t *testing.T) {
+	p, err := New(io.Discard, Null, "")
+	require.NoError(t, err)
+	err = Write(p, rows, Spec[*row]{Columns: spec.Columns})
+	assert.Error(t, err)
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/rubiojr/hashup/internal/errmsg"
 	"github.com/rubiojr/hashup/internal/log"
 	"github.com/rubiojr/hashup/internal/types"
-	"github.com/vmihailenco/msgpack/v5"
 )
 
 type Stats struct {
@@ -150,22 +149,14 @@ func (np *natsProcessor) Process(path string, msg types.ScannedFile) error {
 		}
 	}()
 
-	// Marshal the message using MessagePack
-	plainData, err := msgpack.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal file message: %v", err)
-	}
-
-	var publishData []byte
-	// Encrypt the data if encryption is enabled
+	// Encode the message, encrypting it if encryption is enabled
+	var machine crypto.Machine
 	if np.encrypt {
-		encryptedData, err := np.crypto.Encrypt(plainData)
-		if err != nil {
-			return fmt.Errorf("failed to encrypt message: %v", err)
-		}
-		publishData = encryptedData
-	} else {
-		publishData = plainData
+		machine = np.crypto
+	}
+	publishData, err := types.EncodeMessage(&msg, machine)
+	if err != nil {
+		return err
 	}
 
 	// Add a header to indicate if the message is encrypted
@@ -196,5 +187,7 @@ func (np *natsProcessor) Close() {
 	if np.nc != nil && !np.nc.IsClosed() {
 		np.nc.Close()
 	}
-	close(np.statsChan)
+	if np.statsChan != nil {
+		close(np.statsChan)
+	}
 }
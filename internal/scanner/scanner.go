@@ -7,8 +7,10 @@ import (
 	"path/filepath"
 	"regexp"
 	"slices"
+	"strings"
 
 	"github.com/rubiojr/hashup/internal/cache"
+	"github.com/rubiojr/hashup/internal/chunker"
 	"github.com/rubiojr/hashup/internal/log"
 	"github.com/rubiojr/hashup/internal/pool"
 	"github.com/rubiojr/hashup/internal/processors"
@@ -59,6 +61,8 @@ type DirectoryScanner struct {
 	pool         *pool.Pool
 	pCount       chan int64
 	cache        cache.Cache
+	chunkMinSize int64
+	listPath     string
 }
 
 // Options for configuring the NATS processor
@@ -74,7 +78,6 @@ func WithIgnoreList(ignoreList []string) Option {
 func WithScanningConcurrency(concurrency int) Option {
 	return func(s *DirectoryScanner) {
 		s.pool = pool.NewPool(concurrency)
-		s.pool.Start()
 	}
 }
 
@@ -90,14 +93,29 @@ func WithCache(cache cache.Cache) Option {
 	}
 }
 
+// WithChunking adds the chunk manifest of files of at least minSize bytes
+// to their messages.
+func WithChunking(minSize int64) Option {
+	return func(s *DirectoryScanner) {
+		s.chunkMinSize = max(minSize, 1)
+	}
+}
+
+// WithRemovals keeps the list of the files found by the scan at listPath
+// and reports the files listed by the previous scan and no longer found,
+// with Removed messages.
+func WithRemovals(listPath string) Option {
+	return func(s *DirectoryScanner) {
+		s.listPath = listPath
+	}
+}
+
 func NewDirectoryScanner(rootDir string, options ...Option) *DirectoryScanner {
 	scanner := &DirectoryScanner{
 		rootDir:      rootDir,
 		ignoreList:   []string{},
 		ignoreHidden: true,
 		pool:         pool.NewPool(5),
-		// TODO: context propagagion
-		cache: cache.NewFileCache(context.Background(), 100, config.DefaultCachePath()),
 	}
 
 	// apply options
@@ -105,6 +123,11 @@ func NewDirectoryScanner(rootDir string, options ...Option) *DirectoryScanner {
 		option(scanner)
 	}
 
+	if scanner.cache == nil {
+		// TODO: context propagagion
+		scanner.cache = cache.NewFileCache(context.Background(), 100, config.DefaultCachePath())
+	}
+
 	scanner.pool.Start()
 
 	return scanner
@@ -139,6 +162,7 @@ func (s *DirectoryScanner) ScanDirectory(ctx context.Context, processor processo
 	}
 
 	var count int64
+	seen := map[string]bool{}
 
 	err = filepath.Walk(s.rootDir, func(path string, info os.FileInfo, err error) error {
 		// Check if the context has been cancelled
@@ -205,6 +229,10 @@ func (s *DirectoryScanner) ScanDirectory(ctx context.Context, processor processo
 			}
 		}
 
+		if s.listPath != "" {
+			seen[path] = true
+		}
+
 		f := func() error {
 			// Calculate file hash
 			fileHash, err := util.ComputeFileHash(absPath)
@@ -237,6 +265,13 @@ func (s *DirectoryScanner) ScanDirectory(ctx context.Context, processor processo
 				Hostname:  hostname,
 			}
 
+			if s.chunkMinSize > 0 && info.Size() >= s.chunkMinSize {
+				msg.Chunks, err = chunker.ManifestFile(absPath)
+				if err != nil {
+					log.Errorf("failed chunking %q: %v", absPath, err)
+				}
+			}
+
 			log.Debugf("Processing file %s\n", absPath)
 			err = processor.Process(absPath, msg)
 			if err != nil {
@@ -250,5 +285,60 @@ func (s *DirectoryScanner) ScanDirectory(ctx context.Context, processor processo
 		return nil
 	})
 
+	// Files missed by an interrupted scan are not gone
+	if err == nil && s.listPath != "" {
+		s.reportRemoved(processor, hostname, seen)
+	}
+
 	return count, err
 }
+
+// reportRemoved sends a Removed message for every file listed by the
+// previous scan and not seen by this one, then saves the list for the next
+// scan. Files whose message could not be sent stay listed, to be reported
+// again.
+func (s *DirectoryScanner) reportRemoved(processor processors.Processor, hostname string, seen map[string]bool) {
+	data, err := os.ReadFile(s.listPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Errorf("Error reading the scanned files list: %v", err)
+	}
+
+	for _, path := range strings.Split(string(data), "\x00") {
+		if path == "" || seen[path] {
+			continue
+		}
+
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			absPath = path
+		}
+		msg := types.ScannedFile{Path: path, Hostname: hostname, Removed: true}
+		log.Debugf("Processing removed file %s\n", absPath)
+		if err := processor.Process(absPath, msg); err != nil {
+			log.Errorf("failed processing removed %q: %v", absPath, err)
+			seen[path] = true
+		}
+	}
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	if err := saveList(s.listPath, paths); err != nil {
+		log.Errorf("Error saving the scanned files list: %v", err)
+	}
+}
+
+// saveList atomically writes the NUL separated paths to listPath.
+func saveList(listPath string, paths []string) error {
+	if err := os.MkdirAll(filepath.Dir(listPath), 0755); err != nil {
+		return err
+	}
+
+	tmp := listPath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(paths, "\x00")), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, listPath)
+}
@@ -100,3 +100,46 @@ func TestScanDirectory(t *testing.T) {
 		assert.NotEmpty(t, file.Hash, "File hash should not be empty")
 	}
 }
+
+func TestScanRemovals(t *testing.T) {
+	dir := t.TempDir()
+	listPath := filepath.Join(t.TempDir(), "scans", "list")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0644))
+	}
+
+	scan := func() map[string]types.ScannedFile {
+		p := processors.NewChanProcessor()
+		files := map[string]types.ScannedFile{}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for f := range p.Ch {
+				files[f.Path] = f
+			}
+		}()
+
+		s := NewDirectoryScanner(dir, WithCache(&cache.NoopCache{}), WithRemovals(listPath))
+		_, err := s.ScanDirectory(context.Background(), p)
+		assert.NoError(t, err)
+		close(p.Ch)
+		<-done
+		return files
+	}
+
+	files := scan()
+	assert.Len(t, files, 2)
+
+	b := filepath.Join(dir, "b.txt")
+	assert.NoError(t, os.Remove(b))
+	files = scan()
+	assert.Len(t, files, 2)
+	assert.False(t, files[filepath.Join(dir, "a.txt")].Removed)
+	assert.True(t, files[b].Removed)
+	assert.Empty(t, files[b].Hash)
+
+	// Removals are reported once
+	files = scan()
+	assert.Len(t, files, 1)
+	assert.NotContains(t, files, b)
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rubiojr/hashup/cmd/hs/types"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/webhook"
+)
+
+// savedSearchesReload is how often Alerts picks up saved searches changed
+// with `hs watch`.
+var savedSearchesReload = 30 * time.Second
+
+// Alerts checks newly stored files against the saved searches, notifying
+// their webhooks of matches.
+type Alerts struct {
+	db         *sql.DB
+	dispatcher *webhook.Dispatcher
+
+	mu       sync.Mutex
+	searches []*hsdb.SavedSearch
+	loaded   time.Time
+}
+
+func NewAlerts(dbPath string, opts ...webhook.Option) (*Alerts, error) {
+	db, err := hsdb.OpenDatabase(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+
+	return &Alerts{db: db, dispatcher: webhook.NewDispatcher(db, opts...)}, nil
+}
+
+// Check notifies the webhooks of the saved searches matching the
+// file_info row with the given id.
+func (a *Alerts) Check(ctx context.Context, fileID int64) error {
+	searches, err := a.savedSearches()
+	if err != nil {
+		return err
+	}
+
+	var file *types.FileResult
+	for _, s := range searches {
+		match, err := s.Matches(a.db, fileID)
+		if err != nil {
+			return err
+		}
+		if !match {
+			continue
+		}
+
+		if file == nil {
+			if file, err = hsdb.FileByID(a.db, fileID); err != nil {
+				return err
+			}
+		}
+		a.dispatcher.Notify(ctx, s, file)
+	}
+
+	return nil
+}
+
+func (a *Alerts) savedSearches() ([]*hsdb.SavedSearch, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if time.Since(a.loaded) < savedSearchesReload {
+		return a.searches, nil
+	}
+
+	searches, err := hsdb.SavedSearches(a.db)
+	if err != nil {
+		return nil, err
+	}
+	a.searches, a.loaded = searches, time.Now()
+	return searches, nil
+}
+
+// Close delivers the queued notifications and closes the database.
+func (a *Alerts) Close() error {
+	a.dispatcher.Close()
+	return a.db.Close()
+}
@@ -0,0 +1,310 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/log"
+	"github.com/rubiojr/hashup/internal/types"
+	"github.com/rubiojr/hashup/internal/webhook"
+	"github.com/rubiojr/hashup/pkg/config"
+)
+
+// quietReload is how often the detector picks up quiet periods changed
+// with `hs alerts quiet`.
+var quietReload = 30 * time.Second
+
+const (
+	// baselineWeight is the weight of the last window in the baseline.
+	baselineWeight = 0.2
+	// maxAlertPaths is how many affected paths alerts keep.
+	maxAlertPaths = 20
+)
+
+// AnomalyDetector watches the files stored per host for mass changes:
+// many more new versions of indexed files in a window than the host
+// usually has, or many new files with extensions the host never had.
+//
+// Files are placed in windows by their modification time, not by when
+// they are received, so that a backlog stored at once after downtime is
+// not mistaken for a burst. Windows start with the first file of a host
+// modified after the previous one ended. The baseline of a host is a moving
+// average of the changes in its windows, saved in the database and left
+// unchanged by quiet and alerting windows.
+type AnomalyDetector struct {
+	db         *sql.DB
+	cfg        config.AnomalyConfig
+	window     time.Duration
+	events     *hsdb.EventRecorder
+	dispatcher *webhook.Dispatcher
+	now        func() time.Time
+
+	mu          sync.Mutex
+	hosts       map[string]*hostActivity
+	quiet       []*hsdb.QuietPeriod
+	quietLoaded time.Time
+}
+
+// hostActivity is the activity of a host in the current window.
+type hostActivity struct {
+	start    time.Time
+	baseline float64
+	// windows is the number of windows in the baseline
+	windows int
+	changes int64
+	paths   []string
+	// known are the extensions of the host before the current window
+	known map[string]bool
+	// checkExts is set when the host had files before the detector
+	// started, or once a window of a new host brings no new extensions
+	checkExts bool
+	newExts   map[string]bool
+	newFiles  int64
+	newPaths  []string
+	alerted   map[string]bool
+	quiet     bool
+}
+
+func NewAnomalyDetector(dbPath string, cfg config.AnomalyConfig, opts ...webhook.Option) (*AnomalyDetector, error) {
+	if cfg.Window <= 0 {
+		return nil, fmt.Errorf("anomaly window must be positive")
+	}
+
+	db, err := hsdb.OpenDatabase(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+
+	events, err := hsdb.NewEventRecorder(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &AnomalyDetector{
+		db:         db,
+		cfg:        cfg,
+		window:     time.Duration(cfg.Window) * time.Second,
+		events:     events,
+		dispatcher: webhook.NewDispatcher(db, opts...),
+		now:        time.Now,
+		hosts:      map[string]*hostActivity{},
+	}, nil
+}
+
+// Observe counts a stored file in the activity of its host, raising the
+// alerts of the window when a threshold is crossed.
+func (d *AnomalyDetector) Observe(ctx context.Context, file *types.ScannedFile, stored FileStored) error {
+	if !stored.FileInfo {
+		return nil
+	}
+
+	quiet, err := d.isQuiet(file.Hostname)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	h, err := d.activity(file.Hostname, stored.FileID, d.eventTime(file))
+	if err != nil {
+		d.mu.Unlock()
+		return err
+	}
+	h.quiet = h.quiet || quiet
+
+	var alerts []*hsdb.AnomalyAlert
+	if stored.Updated {
+		h.changes++
+		if len(h.paths) < maxAlertPaths {
+			h.paths = append(h.paths, file.Path)
+		}
+		if threshold := d.changeThreshold(h); h.changes >= threshold && !h.alerted[hsdb.AnomalyMassChange] {
+			h.alerted[hsdb.AnomalyMassChange] = true
+			alerts = append(alerts, &hsdb.AnomalyAlert{
+				Kind:      hsdb.AnomalyMassChange,
+				Files:     h.changes,
+				Threshold: threshold,
+				Paths:     slices.Clone(h.paths),
+			})
+		}
+	} else if !h.known[file.Extension] {
+		h.newExts[file.Extension] = true
+		if h.checkExts {
+			h.newFiles++
+			if len(h.newPaths) < maxAlertPaths {
+				h.newPaths = append(h.newPaths, file.Path)
+			}
+			threshold := int64(d.cfg.MinNewExtensions)
+			if h.newFiles >= threshold && !h.alerted[hsdb.AnomalyNewExtensions] {
+				h.alerted[hsdb.AnomalyNewExtensions] = true
+				alerts = append(alerts, &hsdb.AnomalyAlert{
+					Kind:       hsdb.AnomalyNewExtensions,
+					Files:      h.newFiles,
+					Threshold:  threshold,
+					Extensions: slices.Sorted(maps.Keys(h.newExts)),
+					Paths:      slices.Clone(h.newPaths),
+				})
+			}
+		}
+	}
+	for _, a := range alerts {
+		a.Host, a.WindowStart, a.Baseline = file.Hostname, h.start, h.baseline
+	}
+	quiet = h.quiet
+	d.mu.Unlock()
+
+	if quiet {
+		return nil
+	}
+	for _, a := range alerts {
+		if err := d.raise(ctx, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventTime returns when the change to file happened: its modification
+// time, or now for files without one or modified in the future.
+func (d *AnomalyDetector) eventTime(file *types.ScannedFile) time.Time {
+	now := d.now()
+	if file.ModTime.IsZero() || file.ModTime.After(now) {
+		return now
+	}
+	return file.ModTime
+}
+
+// activity returns the activity of host in the window of a file changed
+// at t, ending the previous window if t is past it. Files changed before
+// the current window are counted in it. fileID is the file being observed.
+func (d *AnomalyDetector) activity(host string, fileID int64, t time.Time) (*hostActivity, error) {
+	h := d.hosts[host]
+	if h == nil {
+		exts, err := hsdb.HostExtensions(d.db, host, fileID)
+		if err != nil {
+			return nil, err
+		}
+		baseline, windows, err := hsdb.HostBaseline(d.db, host)
+		if err != nil {
+			return nil, err
+		}
+		h = &hostActivity{
+			known:     map[string]bool{},
+			checkExts: len(exts) > 0,
+			baseline:  baseline,
+			windows:   windows,
+		}
+		for _, ext := range exts {
+			h.known[ext] = true
+		}
+		h.reset(t)
+		d.hosts[host] = h
+		return h, nil
+	}
+
+	if t.Sub(h.start) < d.window {
+		return h, nil
+	}
+
+	if !h.quiet && len(h.alerted) == 0 {
+		if h.windows == 0 {
+			h.baseline = float64(h.changes)
+		} else {
+			h.baseline = (1-baselineWeight)*h.baseline + baselineWeight*float64(h.changes)
+		}
+		h.windows++
+		if err := hsdb.SetHostBaseline(d.db, host, h.baseline, h.windows); err != nil {
+			return nil, err
+		}
+	}
+	if len(h.newExts) == 0 {
+		h.checkExts = true
+	}
+	for ext := range h.newExts {
+		h.known[ext] = true
+	}
+	h.reset(t)
+	return h, nil
+}
+
+func (h *hostActivity) reset(start time.Time) {
+	h.start = start
+	h.changes, h.paths = 0, nil
+	h.newFiles, h.newPaths, h.newExts = 0, nil, map[string]bool{}
+	h.alerted = map[string]bool{}
+	h.quiet = false
+}
+
+// changeThreshold returns the changes in a window that raise a mass change
+// alert.
+func (d *AnomalyDetector) changeThreshold(h *hostActivity) int64 {
+	threshold := int64(d.cfg.MinChanges)
+	if t := int64(d.cfg.Factor * h.baseline); t > threshold {
+		threshold = t
+	}
+	return max(threshold, 1)
+}
+
+// isQuiet reports whether the alerts of host are silenced.
+func (d *AnomalyDetector) isQuiet(host string) (bool, error) {
+	if slices.Contains(d.cfg.QuietHosts, host) {
+		return true, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if time.Since(d.quietLoaded) >= quietReload {
+		quiet, err := hsdb.QuietPeriods(d.db)
+		if err != nil {
+			return false, err
+		}
+		d.quiet, d.quietLoaded = quiet, time.Now()
+	}
+
+	now := d.now()
+	for _, q := range d.quiet {
+		if (q.Host == host || q.Host == hsdb.QuietAll) && q.Until.After(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// raise stores a, logs it and notifies the anomaly webhook.
+func (d *AnomalyDetector) raise(ctx context.Context, a *hsdb.AnomalyAlert) error {
+	if err := hsdb.CreateAnomalyAlert(d.db, a); err != nil {
+		return err
+	}
+
+	switch a.Kind {
+	case hsdb.AnomalyMassChange:
+		log.Errorf("[%s] %d files changed in %s, usually %.0f: possible mass change, see `hs alerts`\n",
+			a.Host, a.Files, d.window, a.Baseline)
+	case hsdb.AnomalyNewExtensions:
+		log.Errorf("[%s] %d new files with unfamiliar extensions %v in %s, see `hs alerts`\n",
+			a.Host, a.Files, a.Extensions, d.window)
+	}
+
+	if err := d.events.Record(&hsdb.Event{Type: hsdb.EventAnomalyDetected, Host: a.Host}); err != nil {
+		return err
+	}
+
+	if d.cfg.WebhookURL != "" {
+		d.dispatcher.NotifyAnomaly(ctx, d.cfg.WebhookURL, d.cfg.WebhookSecret, a)
+	}
+	return nil
+}
+
+// Close delivers the queued notifications and closes the database.
+func (d *AnomalyDetector) Close() error {
+	d.dispatcher.Close()
+	d.events.Close()
+	return d.db.Close()
+}
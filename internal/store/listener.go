@@ -9,7 +9,6 @@ import (
 	"github.com/rubiojr/hashup/internal/crypto"
 	"github.com/rubiojr/hashup/internal/log"
 	"github.com/rubiojr/hashup/internal/types"
-	"github.com/vmihailenco/msgpack/v5"
 )
 
 type Listener interface {
@@ -60,6 +59,20 @@ func WithClientKey(key string) NATSListenerOption {
 	}
 }
 
+// WithAlerts checks stored files against the saved searches.
+func WithAlerts(alerts *Alerts) NATSListenerOption {
+	return func(s *natsListener) {
+		s.alerts = alerts
+	}
+}
+
+// WithAnomalies watches stored files for mass changes per host.
+func WithAnomalies(d *AnomalyDetector) NATSListenerOption {
+	return func(s *natsListener) {
+		s.anomalies = d
+	}
+}
+
 func WithCACert(cert string) NATSListenerOption {
 	return func(s *natsListener) {
 		s.caCert = cert
@@ -74,6 +87,8 @@ type natsListener struct {
 	natsEncryptionKey string
 	stats             *ProcessStats
 	storage           Storage
+	alerts            *Alerts
+	anomalies         *AnomalyDetector
 	clientCert        string
 	clientKey         string
 	caCert            string
@@ -178,31 +193,15 @@ func (l *natsListener) Listen(ctx context.Context) error {
 			if l.stats != nil {
 				l.stats.IncrementReceived()
 			}
-			var plaintext []byte
 			// Check if the message is encrypted
-			isEncrypted := msg.Header.Get("Encrypted") == "true"
-
-			if isEncrypted {
-				// Decrypt the message
-				decrypted, err := cryptom.Decrypt(msg.Data)
-				if err != nil {
-					log.Errorf("Failed to decrypt message: %v\n", err)
-					if l.stats != nil {
-						l.stats.IncrementSkipped()
-					}
-					continue
-				}
-				plaintext = decrypted
-			} else {
-				// Message is not encrypted
-				plaintext = msg.Data
+			var machine crypto.Machine
+			if msg.Header.Get("Encrypted") == "true" {
+				machine = cryptom
 			}
 
-			var fileMsg *types.ScannedFile
-
-			// Unmarshal using MessagePack
-			if err := msgpack.Unmarshal(plaintext, &fileMsg); err != nil {
-				log.Errorf("Failed to unmarshal message: %v\n", err)
+			fileMsg, err := types.DecodeMessage(msg.Data, machine)
+			if err != nil {
+				log.Errorf("Failed to decode message: %v\n", err)
 				if l.stats != nil {
 					l.stats.IncrementSkipped()
 				}
@@ -225,10 +224,27 @@ func (l *natsListener) Listen(ctx context.Context) error {
 				if l.stats != nil {
 					l.stats.IncrementSkipped()
 				}
+			} else if wasWritten.Corrupted {
+				log.Errorf("[%s] possible corruption of %s: content changed without a new modification time\n",
+					fileMsg.Hostname, fileMsg.Path)
+			} else if wasWritten.Removed {
+				if l.stats != nil {
+					l.stats.IncrementWritten()
+				}
 			} else if wasWritten.Dirty() {
 				if l.stats != nil {
 					l.stats.IncrementWritten()
 				}
+				if l.alerts != nil && wasWritten.FileInfo {
+					if err := l.alerts.Check(ctx, wasWritten.FileID); err != nil {
+						log.Errorf("Failed to check saved searches: %v\n", err)
+					}
+				}
+				if l.anomalies != nil {
+					if err := l.anomalies.Observe(ctx, fileMsg, wasWritten); err != nil {
+						log.Errorf("Failed to check for anomalies: %v\n", err)
+					}
+				}
 			} else {
 				if l.stats != nil {
 					l.stats.IncrementAlreadyPresent()
@@ -5,6 +5,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rubiojr/hashup/internal/log"
 
 	hsdb "github.com/rubiojr/hashup/internal/db"
 	"github.com/rubiojr/hashup/internal/types"
@@ -13,6 +17,10 @@ import (
 var ErrFileInfoExists = errors.New("file info already exists")
 var ErrFileHashExists = errors.New("file hash already exists")
 
+// eventRetention is how long indexing events are kept for API clients
+// catching up with the live feed.
+const eventRetention = 7 * 24 * time.Hour
+
 type Storage interface {
 	Store(context.Context, *types.ScannedFile) (FileStored, error)
 }
@@ -24,8 +32,16 @@ type sqliteStorage struct {
 	dbPath         string
 	pInsertHash    *sql.Stmt
 	pInsertInfo    *sql.Stmt
-	pQueryFileInfo *sql.Stmt
 	pQueryFileHash *sql.Stmt
+	pQueryLatest   *sql.Stmt
+	dirs           *hsdb.DirUpdater
+	events         *hsdb.EventRecorder
+
+	mu        sync.Mutex
+	seenHosts map[string]bool
+	lastPrune time.Time
+	// unresolved are the files with unresolved integrity events
+	unresolved map[hsdb.TreeRef]bool
 }
 
 func NewSqliteStorage(dbPath string) (*sqliteStorage, error) {
@@ -33,10 +49,14 @@ func NewSqliteStorage(dbPath string) (*sqliteStorage, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
-
+	if _, err := hsdb.EnsureFTS(db); err != nil {
+		return nil, err
+	}
 	storage := &sqliteStorage{
-		db:     db,
-		dbPath: dbPath,
+		db:         db,
+		dbPath:     dbPath,
+		seenHosts:  map[string]bool{},
+		unresolved: map[hsdb.TreeRef]bool{},
 	}
 
 	storage.pInsertHash, err = db.Prepare("INSERT INTO file_hashes (file_hash) VALUES (?)")
@@ -46,21 +66,43 @@ func NewSqliteStorage(dbPath string) (*sqliteStorage, error) {
 
 	storage.pInsertInfo, err = db.Prepare(`
 		INSERT INTO file_info (
-            file_path, file_size, modified_date, hash_id,
+            file_path, file_size, modified_date, updated_date, hash_id,
             host, extension, file_hash
-        ) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare insert info statement: %v", err)
 	}
 
-	storage.pQueryFileInfo, err = db.Prepare("SELECT id FROM file_info WHERE file_path = ? AND host = ? AND file_hash = ?")
+	storage.pQueryFileHash, err = db.Prepare("SELECT id FROM file_hashes WHERE file_hash = ?")
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare query file info statement: %v", err)
+		return nil, fmt.Errorf("failed to prepare query file hash statement: %v", err)
 	}
 
-	storage.pQueryFileHash, err = db.Prepare("SELECT id FROM file_hashes WHERE file_hash = ?")
+	storage.pQueryLatest, err = db.Prepare(`
+		SELECT fi.id, COALESCE(fi.file_size, 0), COALESCE(CAST(fi.modified_date AS TEXT), ''), fi.file_hash,
+			r.file_id IS NOT NULL
+		FROM file_info fi LEFT JOIN file_removals r ON r.file_id = fi.id
+		WHERE fi.file_path = ? AND fi.host = ? ORDER BY fi.id DESC LIMIT 1`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare query file hash statement: %v", err)
+		return nil, fmt.Errorf("failed to prepare query latest file statement: %v", err)
+	}
+
+	storage.dirs, err = hsdb.NewDirUpdater(db)
+	if err != nil {
+		return nil, err
+	}
+
+	storage.events, err = hsdb.NewEventRecorder(db)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := hsdb.UnresolvedIntegrityFiles(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		storage.unresolved[f] = true
 	}
 
 	return storage, nil
@@ -72,6 +114,20 @@ func (s *sqliteStorage) Store(ctx context.Context, fileMsg *types.ScannedFile) (
 		FileInfo: false,
 	}
 
+	latest, err := s.latestFile(fileMsg)
+	if err != nil {
+		return recordStored, err
+	}
+
+	if fileMsg.Removed {
+		return s.removeFile(fileMsg, latest)
+	}
+
+	recordStored.Corrupted, err = s.checkIntegrity(fileMsg, latest)
+	if err != nil || recordStored.Corrupted {
+		return recordStored, err
+	}
+
 	hashID, err := s.saveFileHash(fileMsg.Hash)
 	recordStored.FileHash = err == nil
 
@@ -79,15 +135,34 @@ func (s *sqliteStorage) Store(ctx context.Context, fileMsg *types.ScannedFile) (
 		return recordStored, fmt.Errorf("failed to save hash to database: %w", err)
 	}
 
-	err = s.saveFileInfo(hashID, fileMsg)
+	recordStored.FileID, err = s.saveFileInfo(hashID, fileMsg, latest)
 	recordStored.FileInfo = err == nil
+	recordStored.Updated = recordStored.FileInfo && latest != nil
 	if err != nil && err != ErrFileInfoExists {
 		return recordStored, fmt.Errorf("failed to save file info to database: %w", err)
 	}
 
+	// Manifests are saved once per content, files indexed before the
+	// scanner chunked them get theirs when they are scanned again.
+	if fileMsg.Chunks != nil {
+		if _, err := hsdb.SaveManifest(s.db, fileMsg.Hash, fileMsg.Size, fileMsg.Chunks); err != nil {
+			return recordStored, err
+		}
+	}
+
 	return recordStored, nil
 }
 
+// Close releases the prepared statements and closes the database.
+func (s *sqliteStorage) Close() error {
+	for _, stmt := range []*sql.Stmt{s.pInsertHash, s.pInsertInfo, s.pQueryFileHash, s.pQueryLatest} {
+		stmt.Close()
+	}
+	s.dirs.Close()
+	s.events.Close()
+	return s.db.Close()
+}
+
 func (s *sqliteStorage) saveFileHash(hash string) (int64, error) {
 	// Check if hash already exists in file_hashes
 	hashID := int64(-1)
@@ -116,6 +191,94 @@ func (s *sqliteStorage) saveFileHash(hash string) (int64, error) {
 type FileStored struct {
 	FileHash bool
 	FileInfo bool
+	// FileID is the id of the file_info row when FileInfo is set
+	FileID int64
+	// Updated is set when the file_info row is a new version of an
+	// indexed path
+	Updated bool
+	// Corrupted is set when the file is suspected of silent corruption,
+	// see checkIntegrity. Nothing else is stored then.
+	Corrupted bool
+	// Removed is set when the removal of the file, FileID, is recorded.
+	Removed bool
+}
+
+// latestVersion is the indexed version of a file a new one is compared to.
+type latestVersion struct {
+	id      int64
+	size    int64
+	modTime string
+	hash    string
+	// removed is set when the version was reported removed from disk
+	removed bool
+}
+
+// latestFile returns the latest indexed version of the file, nil if the
+// path is not indexed on the host.
+func (s *sqliteStorage) latestFile(fileMsg *types.ScannedFile) (*latestVersion, error) {
+	l := &latestVersion{}
+	err := s.pQueryLatest.QueryRow(fileMsg.Path, fileMsg.Hostname).Scan(&l.id, &l.size, &l.modTime, &l.hash, &l.removed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest file info: %w", err)
+	}
+	return l, nil
+}
+
+// checkIntegrity reports whether the file has different content than its
+// latest version with the same size and modification time, which editing
+// it would have changed. Such files are recorded as integrity events
+// instead of new versions. Files stored without a mismatch resolve the
+// events of their path.
+func (s *sqliteStorage) checkIntegrity(fileMsg *types.ScannedFile, latest *latestVersion) (bool, error) {
+	ref := hsdb.TreeRef{Host: fileMsg.Hostname, Path: fileMsg.Path}
+	modTimeStr := fileMsg.ModTime.Format("2006-01-02 15:04:05")
+
+	if latest == nil || latest.removed || latest.hash == fileMsg.Hash || latest.size != fileMsg.Size || latest.modTime != modTimeStr {
+		s.mu.Lock()
+		unresolved := s.unresolved[ref]
+		delete(s.unresolved, ref)
+		s.mu.Unlock()
+
+		if unresolved {
+			if _, err := hsdb.ResolveIntegrityEvents(s.db, ref.Host, ref.Path); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	e := &hsdb.IntegrityEvent{
+		Host:         fileMsg.Hostname,
+		FilePath:     fileMsg.Path,
+		FileID:       latest.id,
+		FileSize:     fileMsg.Size,
+		ModifiedDate: fileMsg.ModTime,
+		ExpectedHash: latest.hash,
+		ActualHash:   fileMsg.Hash,
+	}
+	isNew, err := hsdb.RecordIntegrityEvent(s.db, e)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.unresolved[ref] = true
+	s.mu.Unlock()
+
+	if !isNew {
+		return true, nil
+	}
+	return true, s.recordEvents(&hsdb.Event{
+		Type:     hsdb.EventFileCorrupted,
+		Host:     fileMsg.Hostname,
+		FileID:   latest.id,
+		FilePath: fileMsg.Path,
+		FileHash: fileMsg.Hash,
+		FileSize: fileMsg.Size,
+	})
 }
 
 func (r FileStored) Dirty() bool {
@@ -130,36 +293,109 @@ func (r FileStored) Clean() bool {
 	return !r.FileHash && !r.FileInfo
 }
 
-func (s *sqliteStorage) saveFileInfo(hashID int64, fileMsg *types.ScannedFile) error {
-	// Check if file_info already exists
-	var fileID int64
-	row := s.pQueryFileInfo.QueryRow(
-		fileMsg.Path, fileMsg.Hostname, fileMsg.Hash,
+func (s *sqliteStorage) saveFileInfo(hashID int64, fileMsg *types.ScannedFile, latest *latestVersion) (int64, error) {
+	// The file is already stored when its latest version has the same
+	// content. Content seen in an older version, or before the file was
+	// removed, is a new version.
+	if latest != nil && !latest.removed && latest.hash == fileMsg.Hash {
+		return 0, ErrFileInfoExists
+	}
+
+	// Format mod time for SQL. The indexing time is stored in UTC like
+	// SQLite's CURRENT_TIMESTAMP.
+	modTimeStr := fileMsg.ModTime.Format("2006-01-02 15:04:05")
+	updatedStr := time.Now().UTC().Format("2006-01-02 15:04:05")
+
+	// A new version of an indexed file replaces the previous one in the
+	// directory totals.
+	files, size := int64(1), fileMsg.Size
+	if latest != nil && !latest.removed {
+		files, size = 0, fileMsg.Size-latest.size
+	}
+
+	result, err := s.pInsertInfo.Exec(
+		fileMsg.Path, fileMsg.Size, modTimeStr, updatedStr, hashID,
+		fileMsg.Hostname, fileMsg.Extension, fileMsg.Hash,
 	)
-	err := row.Scan(&fileID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert file info: %w", err)
+	}
+	fileID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
 
-	if err == nil {
-		return ErrFileInfoExists
+	if err := s.dirs.Add(fileMsg.Hostname, fileMsg.Path, files, size); err != nil {
+		return 0, err
 	}
 
-	// Format mod time for SQL
-	modTimeStr := fileMsg.ModTime.Format("2006-01-02 15:04:05")
+	eventType := hsdb.EventFileIndexed
+	if files == 0 {
+		eventType = hsdb.EventFileUpdated
+	}
+	return fileID, s.recordEvents(&hsdb.Event{
+		Type:     eventType,
+		Host:     fileMsg.Hostname,
+		FileID:   fileID,
+		FilePath: fileMsg.Path,
+		FileHash: fileMsg.Hash,
+		FileSize: fileMsg.Size,
+	})
+}
 
-	if err == sql.ErrNoRows {
-		// Insert file_info if it doesn't exist
-		result, err := s.pInsertInfo.Exec(
-			fileMsg.Path, fileMsg.Size, modTimeStr, hashID,
-			fileMsg.Hostname, fileMsg.Extension, fileMsg.Hash,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to insert file info: %w", err)
+// removeFile records the removal of the latest version of a file reported
+// removed by the scanner. Paths not indexed, or already removed, are
+// ignored.
+func (s *sqliteStorage) removeFile(fileMsg *types.ScannedFile, latest *latestVersion) (FileStored, error) {
+	recordStored := FileStored{}
+	if latest == nil {
+		return recordStored, nil
+	}
+
+	removed, err := hsdb.RecordRemoval(s.db, latest.id, time.Now())
+	if err != nil || !removed {
+		return recordStored, err
+	}
+	recordStored.FileID = latest.id
+	recordStored.Removed = true
+
+	if err := s.dirs.Add(fileMsg.Hostname, fileMsg.Path, -1, -latest.size); err != nil {
+		return recordStored, err
+	}
+
+	return recordStored, s.recordEvents(&hsdb.Event{
+		Type:     hsdb.EventFileRemoved,
+		Host:     fileMsg.Hostname,
+		FileID:   latest.id,
+		FilePath: fileMsg.Path,
+		FileHash: latest.hash,
+		FileSize: latest.size,
+	})
+}
+
+// recordEvents records e, preceded by a host_seen event for hosts not seen
+// since the store started, and prunes old events every hour.
+func (s *sqliteStorage) recordEvents(e *hsdb.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.seenHosts[e.Host] {
+		if err := s.events.Record(&hsdb.Event{Type: hsdb.EventHostSeen, Host: e.Host}); err != nil {
+			return err
 		}
-		fileID, err = result.LastInsertId()
-		if err != nil {
-			return fmt.Errorf("failed to get last insert ID: %w", err)
+		s.seenHosts[e.Host] = true
+	}
+
+	if err := s.events.Record(e); err != nil {
+		return err
+	}
+
+	if time.Since(s.lastPrune) > time.Hour {
+		s.lastPrune = time.Now()
+		if _, err := hsdb.PruneEvents(s.db, time.Now().Add(-eventRetention)); err != nil {
+			log.Errorf("failed to prune events: %v", err)
 		}
-		return nil
 	}
 
-	return fmt.Errorf("failed to query file info: %w", err)
+	return nil
 }
@@ -48,6 +48,9 @@ func BenchmarkSqliteStore(b *testing.B) {
 		// Modify the file hash for each iteration to simulate different files
 		// This ensures we don't just test the "file already exists" path
 		sampleFile.Hash = sampleFile.Hash[:15] + string([]byte{byte('0' + i%10)})
+		// Content changes without a new modification time are integrity
+		// events, not new versions.
+		sampleFile.ModTime = currentTime.Add(time.Duration(i%10) * time.Second)
 
 		_, err := storage.Store(ctx, sampleFile)
 		if err != nil {
@@ -2,13 +2,19 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"testing"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	hsdb "github.com/rubiojr/hashup/internal/db"
 	"github.com/rubiojr/hashup/internal/types"
+	"github.com/rubiojr/hashup/internal/webhook"
+	"github.com/rubiojr/hashup/pkg/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -165,4 +171,393 @@ func TestStore(t *testing.T) {
 		}
 
 	})
+	t.Run("Directory totals count the latest version of each file", func(t *testing.T) {
+		// file1.txt (2048 after its update), file2.txt (1024), file3.docx (4096)
+		var files, size int64
+		err := db.QueryRow("SELECT files, size FROM directories WHERE host = ? AND path = ?", "test-host", "/path/to").Scan(&files, &size)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), files)
+		assert.Equal(t, int64(2048+1024+4096), size)
+
+		err = db.QueryRow("SELECT files, size FROM directories WHERE host = ? AND path = ?", "test-host", "/").Scan(&files, &size)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), files)
+	})
+	t.Run("Indexed files are recorded as events", func(t *testing.T) {
+		events, err := hsdb.Events(db, hsdb.EventFilter{})
+		assert.NoError(t, err)
+		var got []string
+		for _, e := range events {
+			got = append(got, e.Type)
+		}
+		assert.Equal(t, []string{
+			hsdb.EventHostSeen,
+			hsdb.EventFileIndexed,
+			hsdb.EventFileUpdated,
+			hsdb.EventFileIndexed,
+			hsdb.EventFileIndexed,
+		}, got)
+		assert.Equal(t, "/path/to/file1.txt", events[2].FilePath)
+		assert.Equal(t, int64(2048), events[2].FileSize)
+	})
+}
+
+func TestAlerts(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "hashup.db")
+	s, err := NewSqliteStorage(dbPath)
+	assert.NoError(t, err)
+
+	received := make(chan *webhook.Payload, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := &webhook.Payload{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(p))
+		received <- p
+	}))
+	defer receiver.Close()
+
+	assert.NoError(t, hsdb.CreateSavedSearch(s.db, &hsdb.SavedSearch{
+		Name: "keepass", Query: "ext:kdbx", WebhookURL: receiver.URL,
+	}))
+
+	alerts, err := NewAlerts(dbPath)
+	assert.NoError(t, err)
+
+	for _, f := range []*types.ScannedFile{
+		{Path: "/home/me/notes.txt", Size: 10, Hash: "1111111111111111", Extension: "txt", Hostname: "laptop"},
+		{Path: "/home/me/passwords.kdbx", Size: 10, Hash: "2222222222222222", Extension: "kdbx", Hostname: "laptop"},
+	} {
+		stored, err := s.Store(ctx, f)
+		assert.NoError(t, err)
+		assert.NotZero(t, stored.FileID)
+		assert.NoError(t, alerts.Check(ctx, stored.FileID))
+	}
+	assert.NoError(t, alerts.Close())
+
+	close(received)
+	var paths []string
+	for p := range received {
+		assert.Equal(t, webhook.EventMatch, p.Event)
+		paths = append(paths, p.File.FilePath)
+	}
+	assert.Equal(t, []string{"/home/me/passwords.kdbx"}, paths)
+}
+
+func TestStoreIntegrity(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "hashup.db")
+	s, err := NewSqliteStorage(dbPath)
+	assert.NoError(t, err)
+
+	modTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	good := &types.ScannedFile{Path: "/photos/cat.jpg", Size: 1024, ModTime: modTime, Hash: "00000000000000aa", Extension: "jpg", Hostname: "nas"}
+	backup := &types.ScannedFile{Path: "/backup/cat.jpg", Size: 1024, ModTime: modTime, Hash: "00000000000000aa", Extension: "jpg", Hostname: "laptop"}
+	for _, f := range []*types.ScannedFile{good, backup} {
+		_, err := s.Store(ctx, f)
+		assert.NoError(t, err)
+	}
+
+	// Same size and modification time, different content.
+	rotten := *good
+	rotten.Hash = "00000000000000bb"
+	written, err := s.Store(ctx, &rotten)
+	assert.NoError(t, err)
+	assert.True(t, written.Corrupted)
+	assert.True(t, written.Clean())
+
+	// Reported again, the event is refreshed.
+	written, err = s.Store(ctx, &rotten)
+	assert.NoError(t, err)
+	assert.True(t, written.Corrupted)
+
+	var versions int
+	assert.NoError(t, s.db.QueryRow("SELECT COUNT(*) FROM file_info WHERE file_path = ?", good.Path).Scan(&versions))
+	assert.Equal(t, 1, versions)
+
+	events, err := hsdb.IntegrityEvents(s.db, hsdb.IntegrityOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "nas", events[0].Host)
+		assert.Equal(t, good.Hash, events[0].ExpectedHash)
+		assert.Equal(t, rotten.Hash, events[0].ActualHash)
+		if assert.Len(t, events[0].GoodCopies, 1) {
+			assert.Equal(t, "laptop", events[0].GoodCopies[0].Host)
+		}
+	}
+
+	feed, err := hsdb.Events(s.db, hsdb.EventFilter{Types: []string{hsdb.EventFileCorrupted}})
+	assert.NoError(t, err)
+	assert.Len(t, feed, 1)
+	assert.NoError(t, s.Close())
+
+	// Unresolved events survive restarts, storing the file without a
+	// mismatch resolves them.
+	s, err = NewSqliteStorage(dbPath)
+	assert.NoError(t, err)
+	defer s.Close()
+
+	written, err = s.Store(ctx, good)
+	assert.NoError(t, err)
+	assert.False(t, written.Corrupted)
+
+	events, err = hsdb.IntegrityEvents(s.db, hsdb.IntegrityOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	events, err = hsdb.IntegrityEvents(s.db, hsdb.IntegrityOptions{Resolved: true})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.NotNil(t, events[0].Resolved)
+	}
+
+	// Edits change the modification time and are ordinary updates.
+	edited := *good
+	edited.Hash = "00000000000000cc"
+	edited.ModTime = modTime.Add(time.Minute)
+	written, err = s.Store(ctx, &edited)
+	assert.NoError(t, err)
+	assert.False(t, written.Corrupted)
+	assert.True(t, written.FileInfo)
+}
+
+func TestStoreChunks(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSqliteStorage(filepath.Join(t.TempDir(), "hashup.db"))
+	assert.NoError(t, err)
+
+	f := &types.ScannedFile{Path: "/vm/disk.img", Size: 300, ModTime: time.Now(), Hash: "00000000000000aa", Extension: "img", Hostname: "nas"}
+	_, err = s.Store(ctx, f)
+	assert.NoError(t, err)
+	_, err = hsdb.Manifest(s.db, f.Hash)
+	assert.ErrorIs(t, err, hsdb.ErrNoManifest)
+
+	// Indexed before it was chunked
+	f.Chunks = &types.ChunkManifest{AvgSize: 64, Hashes: []uint64{1, 2}, Sizes: []uint32{100, 200}}
+	stored, err := s.Store(ctx, f)
+	assert.NoError(t, err)
+	assert.True(t, stored.Clean())
+
+	m, err := hsdb.Manifest(s.db, f.Hash)
+	assert.NoError(t, err)
+	assert.Equal(t, f.Chunks, m)
+}
+
+func TestStoreRemoved(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSqliteStorage(filepath.Join(t.TempDir(), "hashup.db"))
+	assert.NoError(t, err)
+
+	f := &types.ScannedFile{Path: "/data/a.txt", Size: 100, ModTime: time.Now(), Hash: "00000000000000aa", Extension: "txt", Hostname: "nas"}
+	first, err := s.Store(ctx, f)
+	assert.NoError(t, err)
+
+	removed := &types.ScannedFile{Path: f.Path, Hostname: f.Hostname, Removed: true}
+	stored, err := s.Store(ctx, removed)
+	assert.NoError(t, err)
+	assert.True(t, stored.Removed)
+	assert.Equal(t, first.FileID, stored.FileID)
+
+	stored, err = s.Store(ctx, removed)
+	assert.NoError(t, err)
+	assert.False(t, stored.Removed)
+
+	stored, err = s.Store(ctx, &types.ScannedFile{Path: "/data/b.txt", Hostname: "nas", Removed: true})
+	assert.NoError(t, err)
+	assert.False(t, stored.Removed)
+
+	changes, err := hsdb.Recent(s.db, hsdb.RecentOptions{Since: time.Now().Add(-time.Hour)})
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, hsdb.ChangeRemoved, changes[0].Status)
+		assert.Equal(t, f.Hash, changes[0].FileHash)
+	}
+
+	// Removed files leave the directory totals and the latest versions
+	l, err := hsdb.List(s.db, hsdb.TreeRef{Host: "nas", Path: "/"})
+	assert.NoError(t, err)
+	assert.Zero(t, l.Dir.Files)
+	assert.Empty(t, l.Dirs)
+	l, err = hsdb.List(s.db, hsdb.TreeRef{Host: "nas", Path: "/data"})
+	assert.NoError(t, err)
+	assert.Empty(t, l.Files)
+	detail, err := hsdb.File(s.db, f.Hash)
+	assert.NoError(t, err)
+	assert.Empty(t, detail.Copies)
+	assert.NoError(t, hsdb.RebuildDirs(s.db))
+	_, err = hsdb.List(s.db, hsdb.TreeRef{Host: "nas", Path: "/"})
+	assert.ErrorIs(t, err, hsdb.ErrDirNotFound)
+
+	// The same content found again is a new version
+	stored, err = s.Store(ctx, f)
+	assert.NoError(t, err)
+	assert.True(t, stored.FileInfo)
+	assert.True(t, stored.Updated)
+	l, err = hsdb.List(s.db, hsdb.TreeRef{Host: "nas", Path: "/data"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), l.Dir.Files)
+	assert.Equal(t, int64(100), l.Dir.Size)
+	assert.Len(t, l.Files, 1)
+
+	feed, err := hsdb.Events(s.db, hsdb.EventFilter{Types: []string{hsdb.EventFileRemoved}})
+	assert.NoError(t, err)
+	assert.Len(t, feed, 1)
+}
+
+func TestStoreReverted(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSqliteStorage(filepath.Join(t.TempDir(), "hashup.db"))
+	assert.NoError(t, err)
+
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	a := &types.ScannedFile{Path: "/data/a.txt", Size: 100, ModTime: modTime, Hash: "00000000000000aa", Extension: "txt", Hostname: "nas"}
+	b := &types.ScannedFile{Path: a.Path, Size: 200, ModTime: modTime.Add(time.Minute), Hash: "00000000000000bb", Extension: "txt", Hostname: "nas"}
+	reverted := *a
+	reverted.ModTime = modTime.Add(2 * time.Minute)
+
+	_, err = s.Store(ctx, a)
+	assert.NoError(t, err)
+	stored, err := s.Store(ctx, b)
+	assert.NoError(t, err)
+	assert.True(t, stored.Updated)
+
+	// Going back to earlier content is a new version
+	stored, err = s.Store(ctx, &reverted)
+	assert.NoError(t, err)
+	assert.True(t, stored.FileInfo)
+	assert.True(t, stored.Updated)
+
+	stored, err = s.Store(ctx, &reverted)
+	assert.NoError(t, err)
+	assert.True(t, stored.Clean())
+
+	l, err := hsdb.List(s.db, hsdb.TreeRef{Host: "nas", Path: "/data"})
+	assert.NoError(t, err)
+	if assert.Len(t, l.Files, 1) {
+		assert.Equal(t, a.Hash, l.Files[0].FileHash)
+	}
+	assert.Equal(t, int64(1), l.Dir.Files)
+	assert.Equal(t, int64(100), l.Dir.Size)
+}
+
+func TestAnomalyDetector(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "hashup.db")
+	s, err := NewSqliteStorage(dbPath)
+	assert.NoError(t, err)
+
+	d, err := NewAnomalyDetector(dbPath, config.AnomalyConfig{
+		Window: 300, MinChanges: 3, Factor: 2, MinNewExtensions: 3,
+	})
+	assert.NoError(t, err)
+	clock := time.Now().Truncate(time.Second)
+	d.now = func() time.Time { return clock }
+
+	// The host had files before the detector started.
+	modTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 6 {
+		_, err := s.Store(ctx, &types.ScannedFile{
+			Path: fmt.Sprintf("/srv/doc%d.doc", i), Size: 10, ModTime: modTime,
+			Hash: fmt.Sprintf("%016d", i), Extension: "doc", Hostname: "nas",
+		})
+		assert.NoError(t, err)
+	}
+
+	// Every version is modified a second after the previous one
+	version := 0
+	updateAt := func(d *AnomalyDetector, n int, at time.Time) {
+		for i := range n {
+			version++
+			f := &types.ScannedFile{
+				Path: fmt.Sprintf("/srv/doc%d.doc", i), Size: 10, ModTime: at.Add(time.Duration(version) * time.Second),
+				Hash: fmt.Sprintf("%016d", 1000+version), Extension: "doc", Hostname: "nas",
+			}
+			stored, err := s.Store(ctx, f)
+			assert.NoError(t, err)
+			assert.True(t, stored.Updated)
+			assert.NoError(t, d.Observe(ctx, f, stored))
+		}
+	}
+	update := func(n int) {
+		updateAt(d, n, clock)
+	}
+	alerts := func() []*hsdb.AnomalyAlert {
+		alerts, err := hsdb.AnomalyAlerts(s.db, hsdb.AnomalyOptions{})
+		assert.NoError(t, err)
+		return alerts
+	}
+
+	// Below the minimum
+	update(2)
+	assert.Empty(t, alerts())
+
+	// The baseline is now 2 changes, raising the threshold to 4
+	clock = clock.Add(301 * time.Second)
+	update(3)
+	assert.Empty(t, alerts())
+	update(3)
+	got := alerts()
+	assert.Len(t, got, 1)
+	assert.Equal(t, hsdb.AnomalyMassChange, got[0].Kind)
+	assert.Equal(t, "nas", got[0].Host)
+	assert.Equal(t, int64(4), got[0].Files)
+	assert.Equal(t, int64(4), got[0].Threshold)
+	assert.Equal(t, 2.0, got[0].Baseline)
+	assert.Len(t, got[0].Paths, 4)
+
+	// New files with unfamiliar extensions
+	for i := range 3 {
+		f := &types.ScannedFile{
+			Path: fmt.Sprintf("/srv/doc%d.doc.locked", i), Size: 10, ModTime: modTime,
+			Hash: fmt.Sprintf("%016d", 2000+i), Extension: "locked", Hostname: "nas",
+		}
+		stored, err := s.Store(ctx, f)
+		assert.NoError(t, err)
+		assert.NoError(t, d.Observe(ctx, f, stored))
+	}
+	got = alerts()
+	assert.Len(t, got, 2)
+	assert.Equal(t, hsdb.AnomalyNewExtensions, got[0].Kind)
+	assert.Equal(t, []string{"locked"}, got[0].Extensions)
+	assert.Equal(t, int64(3), got[0].Files)
+
+	events, err := hsdb.Events(s.db, hsdb.EventFilter{Types: []string{hsdb.EventAnomalyDetected}})
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	// Quiet hosts are not alerted on, and the alerting window was left
+	// out of the baseline
+	assert.NoError(t, hsdb.SetQuiet(s.db, &hsdb.QuietPeriod{Host: "nas", Until: clock.Add(time.Hour)}))
+	d.quietLoaded = time.Time{}
+	clock = clock.Add(301 * time.Second)
+	update(5)
+	assert.Len(t, alerts(), 2)
+
+	assert.NoError(t, hsdb.DeleteQuiet(s.db, "nas"))
+	d.quietLoaded = time.Time{}
+	clock = clock.Add(301 * time.Second)
+	update(4)
+	assert.Len(t, alerts(), 3)
+
+	// A backlog of changes made in earlier windows is received at once
+	clock = clock.Add(time.Hour)
+	for i := range 3 {
+		updateAt(d, 3, clock.Add(time.Duration(i-3)*10*time.Minute))
+	}
+	assert.Len(t, alerts(), 3)
+
+	// The baseline survives restarts
+	baseline, windows, err := hsdb.HostBaseline(s.db, "nas")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, windows)
+	assert.NoError(t, d.Close())
+
+	d, err = NewAnomalyDetector(dbPath, config.AnomalyConfig{
+		Window: 300, MinChanges: 3, Factor: 2, MinNewExtensions: 3,
+	})
+	assert.NoError(t, err)
+	defer d.Close()
+	d.now = func() time.Time { return clock }
+	update(int(2 * baseline))
+	got = alerts()
+	assert.Len(t, got, 4)
+	assert.Equal(t, baseline, got[0].Baseline)
 }
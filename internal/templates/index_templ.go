@@ -29,7 +29,20 @@ func Index() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<!doctype html><html lang=\"en\"><head><meta charset=\"UTF-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\"><title>HashUp Search</title><script src=\"https://unpkg.com/htmx.org@1.9.6\"></script><style>\n\t\t\tbody {\n\t\t\t\tfont-family: system-ui, -apple-system, sans-serif;\n\t\t\t\tmax-width: 600px;\n\t\t\t\tmargin: 0 auto;\n\t\t\t\tpadding: 2rem;\n\t\t\t\tbackground-color: #0f0f0f;\n\t\t\t\tcolor: #d96f28;\n\t\t\t}\n\t\t\t.search-title {\n\t\t\t    color: #d96f28;\n\t\t\t\tfont-weight: bold;\n\t\t\t\tfont-size: 2.5rem;\n\t\t\t}\n\t\t\t.search-container {\n\t\t\t\tmargin: 1rem 0;\n\t\t\t\ttext-align: center;\n\t\t\t}\n\t\t\t.search-input {\n\t\t\t\twidth: 100%;\n\t\t\t\t/* max-width: 600px; */\n\t\t\t\tpadding: 12px;\n\t\t\t\tfont-size: 16px;\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 24px;\n\t\t\t\toutline: none;\n\t\t\t}\n\t\t\t.search-input:focus {\n\t\t\t\tbox-shadow: 0 0 0 2px rgba(0, 0, 255, 0.2);\n\t\t\t}\n\t\t\t.results-container {\n\t\t\t\tmargin-top: 1px;\n\t\t\t\t/* max-width: 500px; */\n\t\t\t}\n\t\t\t.result {\n\t\t\t\tpadding-bottom: 20px;\n\t\t\t\tcolor: #848482;\n\t\t\t\t/*border-bottom: 1px solid #eee;*/\n\t\t\t}\n\t\t\t.result-title {\n\t\t\t\tfont-size: 1.4rem;\n\t\t\t\tpadding-bottom: 6px;\n                position: relative;\n                display: inline-block;\n                cursor: default;\n                color: #d9d9d8;\n\t\t\t}\n\t\t\t.result-url {\n\t\t\t\tfont-size: 0.85rem;\n\t\t\t\tcolor: #006621;\n\t\t\t\tmargin-bottom: 0.3rem;\n\t\t\t}\n\t\t\t.result-item {\n\t\t\t\tfont-size: 0.9rem;\n\t\t\t}\n\t\t\t.result-field {\n\t\t\t\tpadding-right: 10px;\n\t\t\t}\n\t\t\t.result-field-header {\n\t\t\t\tcolor: #d96f28;\n\t\t\t}\n\t\t\t.loading {\n\t\t\t\ttext-align: center;\n\t\t\t\tpadding: 1rem;\n\t\t\t\tcolor: #949aae;\n\t\t\t}\n\t\t\t.no-results {\n\t\t\t\ttext-align: center;\n\t\t\t\tpadding: 1rem;\n\t\t\t\tcolor: #666;\n\t\t\t}\n\t\t\t.tooltiptext .result-title {\n\t\t\t    font-size: 1.5rem;\n\t\t\t    padding-bottom: 24px;\n\t\t\t}\n\t\t\t.result-button {\n                color: #d96f28;\n                background-color: #0f0f0f;\n                border: 0px;\n                border-radius: 0.5em;\n                box-sizing: border-box;\n                font-family: \"Inter var\",ui-sans-serif,system-ui,-apple-system,system-ui,\"Segoe UI\",Roboto,\"Helvetica Neue\",Arial,\"Noto Sans\",sans-serif,\"Apple Color Emoji\",\"Segoe UI Emoji\",\"Segoe UI Symbol\",\"Noto Color Emoji\";\n                font-size: 1.3rem;\n                font-weight: 600;\n                line-height: 1.25rem;\n                text-align: center;\n                text-decoration: none #D1D5DB solid;\n                text-decoration-thickness: auto;\n                cursor: pointer;\n                user-select: none;\n                touch-action: manipulation;\n            }\n            .result-button:hover {\n                color: #21242c;\n            }\n            .result-button:focus {\n              outline: 2px solid transparent;\n              outline-offset: 2px;\n            }\n            .result-button:focus-visible {\n              box-shadow: none;\n            }\n            .tooltiptext {\n                padding: 40px;\n                border: 4px solid #d96f28;\n                background-color: #0f0f0f;\n                color: #fff;\n                text-align: center;\n                border-radius: 0.25em;\n                white-space: nowrap;\n                transition-property: visibility;\n                transition-delay: 0.5s;\n            }\n\t\t</style></head><body><div class=\"search-container\"><h1 class=\"search-title\">HashUp Search</h1><input type=\"text\" name=\"q\" class=\"search-input\" placeholder=\"Search for files...\" hx-get=\"/search\" hx-trigger=\"input changed delay:700ms, search\" hx-target=\"#search-results\" hx-indicator=\"#loading\"></div><div id=\"loading\" class=\"loading htmx-indicator\">Searching...</div><div id=\"search-results\" class=\"results-container\"></div></body></html>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<!doctype html><html lang=\"en\"><head><meta charset=\"UTF-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\"><title>HashUp Search</title><script src=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var2 string
+		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs("/ui/static/htmx.min.js?v=" + HTMXVersion)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `index.templ`, Line: 10, Col: 58}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\"></script><style>\n\t\t\tbody {\n\t\t\t\tfont-family: system-ui, -apple-system, sans-serif;\n\t\t\t\tmax-width: 600px;\n\t\t\t\tmargin: 0 auto;\n\t\t\t\tpadding: 2rem;\n\t\t\t\tbackground-color: #0f0f0f;\n\t\t\t\tcolor: #d96f28;\n\t\t\t}\n\t\t\t.search-title {\n\t\t\t    color: #d96f28;\n\t\t\t\tfont-weight: bold;\n\t\t\t\tfont-size: 2.5rem;\n\t\t\t}\n\t\t\t.search-container {\n\t\t\t\tmargin: 1rem 0;\n\t\t\t\ttext-align: center;\n\t\t\t}\n\t\t\t.search-input {\n\t\t\t\twidth: 100%;\n\t\t\t\t/* max-width: 600px; */\n\t\t\t\tpadding: 12px;\n\t\t\t\tfont-size: 16px;\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 24px;\n\t\t\t\toutline: none;\n\t\t\t}\n\t\t\t.search-input:focus {\n\t\t\t\tbox-shadow: 0 0 0 2px rgba(0, 0, 255, 0.2);\n\t\t\t}\n\t\t\t.results-container {\n\t\t\t\tmargin-top: 1px;\n\t\t\t\t/* max-width: 500px; */\n\t\t\t}\n\t\t\t.result {\n\t\t\t\tpadding-bottom: 20px;\n\t\t\t\tcolor: #848482;\n\t\t\t\t/*border-bottom: 1px solid #eee;*/\n\t\t\t}\n\t\t\t.result-title {\n\t\t\t\tfont-size: 1.4rem;\n\t\t\t\tpadding-bottom: 6px;\n                position: relative;\n                display: inline-block;\n                cursor: default;\n                color: #d9d9d8;\n\t\t\t}\n\t\t\t.result-url {\n\t\t\t\tfont-size: 0.85rem;\n\t\t\t\tcolor: #006621;\n\t\t\t\tmargin-bottom: 0.3rem;\n\t\t\t}\n\t\t\t.result-item {\n\t\t\t\tfont-size: 0.9rem;\n\t\t\t}\n\t\t\t.result-field {\n\t\t\t\tpadding-right: 10px;\n\t\t\t}\n\t\t\t.result-field-header {\n\t\t\t\tcolor: #d96f28;\n\t\t\t}\n\t\t\t.loading {\n\t\t\t\ttext-align: center;\n\t\t\t\tpadding: 1rem;\n\t\t\t\tcolor: #949aae;\n\t\t\t}\n\t\t\t.no-results {\n\t\t\t\ttext-align: center;\n\t\t\t\tpadding: 1rem;\n\t\t\t\tcolor: #666;\n\t\t\t}\n\t\t\t.tooltiptext .result-title {\n\t\t\t    font-size: 1.5rem;\n\t\t\t    padding-bottom: 24px;\n\t\t\t}\n\t\t\t.result-button {\n                color: #d96f28;\n                background-color: #0f0f0f;\n                border: 0px;\n                border-radius: 0.5em;\n                box-sizing: border-box;\n                font-family: \"Inter var\",ui-sans-serif,system-ui,-apple-system,system-ui,\"Segoe UI\",Roboto,\"Helvetica Neue\",Arial,\"Noto Sans\",sans-serif,\"Apple Color Emoji\",\"Segoe UI Emoji\",\"Segoe UI Symbol\",\"Noto Color Emoji\";\n                font-size: 1.3rem;\n                font-weight: 600;\n                line-height: 1.25rem;\n                text-align: center;\n                text-decoration: none #D1D5DB solid;\n                text-decoration-thickness: auto;\n                cursor: pointer;\n                user-select: none;\n                touch-action: manipulation;\n            }\n            .result-button:hover {\n                color: #21242c;\n            }\n            .result-button:focus {\n              outline: 2px solid transparent;\n              outline-offset: 2px;\n            }\n            .result-button:focus-visible {\n              box-shadow: none;\n            }\n            .tooltiptext {\n                padding: 40px;\n                border: 4px solid #d96f28;\n                background-color: #0f0f0f;\n                color: #fff;\n                text-align: center;\n                border-radius: 0.25em;\n                white-space: nowrap;\n                transition-property: visibility;\n                transition-delay: 0.5s;\n            }\n\t\t\t.facets {\n\t\t\t\tdisplay: flex;\n\t\t\t\tflex-wrap: wrap;\n\t\t\t\tgap: 1rem;\n\t\t\t\tmargin-bottom: 1rem;\n\t\t\t}\n\t\t\t.facet {\n\t\t\t\tborder: 1px solid #333;\n\t\t\t\tborder-radius: 0.5em;\n\t\t\t\tpadding: 0.5rem 0.8rem;\n\t\t\t\tcolor: #d96f28;\n\t\t\t}\n\t\t\t.facet-value {\n\t\t\t\tdisplay: block;\n\t\t\t\tcolor: #d9d9d8;\n\t\t\t\tfont-size: 0.9rem;\n\t\t\t\tcursor: pointer;\n\t\t\t}\n\t\t\t.facet-count, .result-count {\n\t\t\t\tcolor: #848482;\n\t\t\t}\n\t\t\t.result-count {\n\t\t\t\tpadding-bottom: 1rem;\n\t\t\t\tfont-size: 0.9rem;\n\t\t\t}\n\t\t\t.error {\n\t\t\t\tpadding: 1rem;\n\t\t\t\tcolor: #e05252;\n\t\t\t}\n\t\t\t.more-button {\n\t\t\t\tdisplay: block;\n\t\t\t\twidth: 100%;\n\t\t\t\tpadding: 10px;\n\t\t\t\tcolor: #d96f28;\n\t\t\t\tbackground-color: #0f0f0f;\n\t\t\t\tborder: 1px solid #d96f28;\n\t\t\t\tborder-radius: 24px;\n\t\t\t\tfont-size: 1rem;\n\t\t\t\tcursor: pointer;\n\t\t\t}\n\t\t</style></head><body><form class=\"search-container\" hx-get=\"/ui/results\" hx-trigger=\"input changed delay:700ms from:#q, search from:#q, change, submit\" hx-target=\"#search-results\" hx-indicator=\"#loading\"><h1 class=\"search-title\">HashUp Search</h1><input id=\"q\" type=\"search\" name=\"q\" class=\"search-input\" placeholder=\"Search for files...\" autofocus><div id=\"facets\" class=\"facets\"></div></form><div id=\"loading\" class=\"loading htmx-indicator\">Searching...</div><div id=\"search-results\" class=\"results-container\"></div></body></html>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
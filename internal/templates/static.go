@@ -0,0 +1,12 @@
+package templates
+
+import "embed"
+
+// Static holds the assets used by the pages, served under /ui/static/.
+// htmx is vendored so the search page works offline.
+//
+//go:embed static
+var Static embed.FS
+
+// HTMXVersion is the version of the vendored htmx.
+const HTMXVersion = "1.9.6"
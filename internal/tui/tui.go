@@ -0,0 +1,554 @@
+// Package tui implements the interactive terminal interface of hs.
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/dustin/go-humanize"
+	"github.com/muesli/termenv"
+	"github.com/rubiojr/hashup/cmd/hs/types"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// ErrReadOnly is returned by backends that cannot tag files.
+var ErrReadOnly = errors.New("tagging is not supported by this backend")
+
+// Backend runs the queries behind the interface, against a local database
+// or an API server.
+type Backend interface {
+	Search(query string, opts hsdb.SearchOptions) (*hsdb.SearchPage, error)
+	Facets(query string) (*hsdb.Facets, error)
+	Tags(f *types.FileResult) ([]string, error)
+	SetTags(f *types.FileResult, tags []string) error
+}
+
+const (
+	// searchDelay debounces searches while typing.
+	searchDelay = 150 * time.Millisecond
+	// resultLimit is the number of results loaded per search.
+	resultLimit = 500
+	facetWidth  = 28
+	detailLines = 8
+)
+
+type focus int
+
+const (
+	focusResults focus = iota
+	focusFacets
+	focusTags
+)
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	activeStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	dimStyle      = lipgloss.NewStyle().Faint(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+)
+
+type facetItem struct {
+	field string
+	hsdb.FacetCount
+}
+
+// filter returns the query filter selecting the facet value.
+func (f facetItem) filter() string {
+	return f.field + ":" + hsdb.QuoteValue(f.Value)
+}
+
+type debounceMsg struct{ seq int }
+
+type resultsMsg struct {
+	seq    int
+	page   *hsdb.SearchPage
+	facets *hsdb.Facets
+	err    error
+}
+
+type copiesMsg struct {
+	hash  string
+	files []*types.FileResult
+	err   error
+}
+
+type tagsMsg struct {
+	tags []string
+	err  error
+}
+
+type statusMsg struct {
+	text string
+	err  error
+}
+
+// Model is the bubbletea model of the interface.
+type Model struct {
+	backend Backend
+
+	input    textinput.Model
+	tagInput textinput.Model
+	focus    focus
+
+	// filters are facet filters appended to the typed query.
+	filters []string
+	seq     int
+
+	results []*types.FileResult
+	total   int64
+	cursor  int
+	offset  int
+
+	facets      []facetItem
+	facetCursor int
+
+	copies map[string][]*types.FileResult
+
+	status string
+	err    error
+
+	width, height int
+}
+
+// New returns a model searching with backend.
+func New(backend Backend) *Model {
+	input := textinput.New()
+	input.Prompt = "Search: "
+	input.Placeholder = "terms and filters, e.g. report ext:pdf size:>1MB"
+	input.Focus()
+
+	tagInput := textinput.New()
+	tagInput.Prompt = "Tags: "
+	tagInput.Placeholder = "comma separated, empty to remove"
+
+	return &Model{
+		backend:  backend,
+		input:    input,
+		tagInput: tagInput,
+		copies:   map[string][]*types.FileResult{},
+	}
+}
+
+// Run starts the interface in the alternate screen and blocks until the
+// user quits.
+func Run(backend Backend) error {
+	_, err := tea.NewProgram(New(backend), tea.WithAltScreen()).Run()
+	return err
+}
+
+func (m *Model) Init() tea.Cmd {
+	return tea.Batch(textinput.Blink, m.search())
+}
+
+// Query returns the typed query combined with the active facet filters.
+func (m *Model) Query() string {
+	return strings.TrimSpace(strings.Join(append([]string{m.input.Value()}, m.filters...), " "))
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.input.Width = max(msg.Width-len(m.input.Prompt)-1, 10)
+		m.scroll()
+		return m, nil
+
+	case debounceMsg:
+		if msg.seq != m.seq {
+			return m, nil
+		}
+		return m, m.search()
+
+	case resultsMsg:
+		if msg.seq != m.seq {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.err = nil
+		m.results = msg.page.Results
+		m.total = msg.page.Total
+		m.cursor, m.offset = 0, 0
+		m.setFacets(msg.facets)
+		return m, m.loadCopies()
+
+	case copiesMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.copies[msg.hash] = msg.files
+		return m, nil
+
+	case tagsMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.tagInput.SetValue(strings.Join(msg.tags, ","))
+		m.tagInput.CursorEnd()
+		m.focus = focusTags
+		m.input.Blur()
+		return m, m.tagInput.Focus()
+
+	case statusMsg:
+		m.status, m.err = msg.text, msg.err
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+
+	return m, nil
+}
+
+func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.Type == tea.KeyCtrlC {
+		return m, tea.Quit
+	}
+	m.status = ""
+
+	if m.focus == focusTags {
+		switch msg.Type {
+		case tea.KeyEsc:
+			m.endTagging()
+			return m, nil
+		case tea.KeyEnter:
+			f := m.selected()
+			tags := strings.Split(m.tagInput.Value(), ",")
+			m.endTagging()
+			return m, m.setTags(f, tags)
+		}
+		var cmd tea.Cmd
+		m.tagInput, cmd = m.tagInput.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		return m, tea.Quit
+	case tea.KeyTab, tea.KeyShiftTab:
+		if m.focus == focusResults && len(m.facets) > 0 {
+			m.focus = focusFacets
+		} else {
+			m.focus = focusResults
+		}
+		return m, nil
+	case tea.KeyUp, tea.KeyCtrlP:
+		return m, m.move(-1)
+	case tea.KeyDown, tea.KeyCtrlN:
+		return m, m.move(1)
+	case tea.KeyPgUp:
+		return m, m.move(-m.listHeight())
+	case tea.KeyPgDown:
+		return m, m.move(m.listHeight())
+	case tea.KeyEnter:
+		if m.focus == focusFacets && len(m.facets) > 0 {
+			m.toggleFilter(m.facets[m.facetCursor].filter())
+			return m, m.search()
+		}
+		return m, nil
+	case tea.KeyCtrlY:
+		if f := m.selected(); f != nil {
+			termenv.Copy(f.FilePath)
+			m.status, m.err = "Copied "+f.FilePath, nil
+		}
+		return m, nil
+	case tea.KeyCtrlT:
+		if f := m.selected(); f != nil {
+			return m, m.loadTags(f)
+		}
+		return m, nil
+	case tea.KeyCtrlX:
+		if len(m.filters) > 0 {
+			m.filters = nil
+			return m, m.search()
+		}
+		return m, nil
+	}
+
+	before := m.input.Value()
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	if m.input.Value() == before {
+		return m, cmd
+	}
+
+	m.seq++
+	seq := m.seq
+	return m, tea.Batch(cmd, tea.Tick(searchDelay, func(time.Time) tea.Msg {
+		return debounceMsg{seq: seq}
+	}))
+}
+
+func (m *Model) endTagging() {
+	m.focus = focusResults
+	m.tagInput.Blur()
+	m.input.Focus()
+}
+
+// move moves the cursor of the focused list by n rows.
+func (m *Model) move(n int) tea.Cmd {
+	if m.focus == focusFacets {
+		m.facetCursor = clamp(m.facetCursor+n, 0, len(m.facets)-1)
+		return nil
+	}
+
+	m.cursor = clamp(m.cursor+n, 0, len(m.results)-1)
+	m.scroll()
+	return m.loadCopies()
+}
+
+func (m *Model) scroll() {
+	h := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+h {
+		m.offset = m.cursor - h + 1
+	}
+}
+
+func (m *Model) toggleFilter(filter string) {
+	if i := slices.Index(m.filters, filter); i >= 0 {
+		m.filters = slices.Delete(m.filters, i, i+1)
+		return
+	}
+	m.filters = append(m.filters, filter)
+}
+
+func (m *Model) setFacets(f *hsdb.Facets) {
+	m.facets = nil
+	if f != nil {
+		for _, g := range []struct {
+			field  string
+			counts []hsdb.FacetCount
+		}{
+			{"host", f.Hosts},
+			{"type", f.Types},
+			{"ext", f.Extensions},
+		} {
+			for _, c := range g.counts {
+				m.facets = append(m.facets, facetItem{field: g.field, FacetCount: c})
+			}
+		}
+	}
+	m.facetCursor = clamp(m.facetCursor, 0, len(m.facets)-1)
+	if len(m.facets) == 0 {
+		m.focus = focusResults
+	}
+}
+
+func (m *Model) selected() *types.FileResult {
+	if m.cursor < 0 || m.cursor >= len(m.results) {
+		return nil
+	}
+	return m.results[m.cursor]
+}
+
+func (m *Model) search() tea.Cmd {
+	m.seq++
+	seq := m.seq
+	query := m.Query()
+	backend := m.backend
+	return func() tea.Msg {
+		page, err := backend.Search(query, hsdb.SearchOptions{Limit: resultLimit, Count: true})
+		if err != nil {
+			return resultsMsg{seq: seq, err: err}
+		}
+		facets, err := backend.Facets(query)
+		return resultsMsg{seq: seq, page: page, facets: facets, err: err}
+	}
+}
+
+// loadCopies fetches every indexed copy of the selected file.
+func (m *Model) loadCopies() tea.Cmd {
+	f := m.selected()
+	if f == nil {
+		return nil
+	}
+	if _, ok := m.copies[f.FileHash]; ok {
+		return nil
+	}
+
+	backend := m.backend
+	return func() tea.Msg {
+		page, err := backend.Search("hash:"+f.FileHash, hsdb.SearchOptions{Sort: hsdb.SortHost, Limit: resultLimit})
+		if err != nil {
+			return copiesMsg{hash: f.FileHash, err: err}
+		}
+		return copiesMsg{hash: f.FileHash, files: page.Results}
+	}
+}
+
+func (m *Model) loadTags(f *types.FileResult) tea.Cmd {
+	backend := m.backend
+	return func() tea.Msg {
+		tags, err := backend.Tags(f)
+		return tagsMsg{tags: tags, err: err}
+	}
+}
+
+func (m *Model) setTags(f *types.FileResult, tags []string) tea.Cmd {
+	if f == nil {
+		return nil
+	}
+	backend := m.backend
+	return func() tea.Msg {
+		if err := backend.SetTags(f, tags); err != nil {
+			return statusMsg{err: err}
+		}
+		return statusMsg{text: "Tagged " + f.FilePath}
+	}
+}
+
+// listHeight is the number of result rows that fit on screen.
+func (m *Model) listHeight() int {
+	// search line, results title, details title and pane, status line
+	return max(m.height-4-detailLines, 1)
+}
+
+func (m *Model) View() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	if m.focus == focusTags {
+		b.WriteString(m.tagInput.View())
+	} else {
+		b.WriteString(m.input.View())
+	}
+	b.WriteString("\n")
+
+	listWidth := m.width - facetWidth - 1
+	body := lipgloss.JoinHorizontal(lipgloss.Top,
+		m.viewResults(listWidth),
+		" ",
+		m.viewFacets(),
+	)
+	b.WriteString(body)
+	b.WriteString("\n")
+	b.WriteString(m.viewDetails())
+	b.WriteString("\n")
+	b.WriteString(m.viewStatus())
+
+	return b.String()
+}
+
+func (m *Model) viewResults(width int) string {
+	h := m.listHeight()
+	lines := make([]string, 0, h+1)
+
+	count := fmt.Sprintf("%d", len(m.results))
+	if m.total >= 0 {
+		count = fmt.Sprintf("%d of %d", len(m.results), m.total)
+	}
+	lines = append(lines, titleStyle.Render("Results ")+dimStyle.Render(count))
+
+	for i := m.offset; i < min(m.offset+h, len(m.results)); i++ {
+		f := m.results[i]
+		line := fmt.Sprintf("%9s  %-12s %s",
+			humanize.Bytes(uint64(f.FileSize)),
+			ansi.Truncate(f.Host, 12, "…"),
+			f.FilePath)
+		line = ansi.Truncate(line, width, "…")
+		if i == m.cursor {
+			line = selectedStyle.Render(pad(line, width))
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < h+1 {
+		lines = append(lines, "")
+	}
+
+	return lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+}
+
+func (m *Model) viewFacets() string {
+	h := m.listHeight()
+	lines := []string{titleStyle.Render("Facets")}
+
+	start := 0
+	if m.facetCursor >= h {
+		start = m.facetCursor - h + 1
+	}
+	for i := start; i < min(start+h, len(m.facets)); i++ {
+		f := m.facets[i]
+		mark := " "
+		if slices.Contains(m.filters, f.filter()) {
+			mark = "✓"
+		}
+		line := fmt.Sprintf("%s %s:%s %s", mark, f.field, f.Value, dimStyle.Render(fmt.Sprint(f.Count)))
+		line = ansi.Truncate(line, facetWidth, "…")
+		switch {
+		case m.focus == focusFacets && i == m.facetCursor:
+			line = selectedStyle.Render(pad(line, facetWidth))
+		case mark != " ":
+			line = activeStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	return lipgloss.NewStyle().Width(facetWidth).Render(strings.Join(lines, "\n"))
+}
+
+func (m *Model) viewDetails() string {
+	lines := []string{titleStyle.Render("Copies")}
+
+	f := m.selected()
+	if f != nil {
+		lines[0] += dimStyle.Render(fmt.Sprintf(" %s %s, modified %s",
+			f.FileHash, humanize.Bytes(uint64(f.FileSize)), f.ModifiedDate.Format(time.DateTime)))
+		for _, c := range m.copies[f.FileHash] {
+			if len(lines) == detailLines {
+				lines[len(lines)-1] = dimStyle.Render("  …")
+				break
+			}
+			lines = append(lines, ansi.Truncate(fmt.Sprintf("  %-12s %s", c.Host, c.FilePath), m.width, "…"))
+		}
+	}
+	for len(lines) < detailLines {
+		lines = append(lines, "")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (m *Model) viewStatus() string {
+	if m.err != nil {
+		return errorStyle.Render(ansi.Truncate(m.err.Error(), m.width, "…"))
+	}
+	if m.status != "" {
+		return ansi.Truncate(m.status, m.width, "…")
+	}
+
+	help := "↑/↓ move • tab facets • enter toggle facet • ctrl+x clear facets • ctrl+y copy path • ctrl+t tag • esc quit"
+	if m.focus == focusTags {
+		help = "enter save tags • esc cancel"
+	}
+	return dimStyle.Render(ansi.Truncate(help, m.width, "…"))
+}
+
+func pad(s string, width int) string {
+	if w := ansi.StringWidth(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
+
+func clamp(v, lo, hi int) int {
+	if hi < lo {
+		return lo
+	}
+	return min(max(v, lo), hi)
+}
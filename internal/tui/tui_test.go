@@ -0,0 +1,137 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rubiojr/hashup/cmd/hs/types"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+type fakeBackend struct {
+	queries []string
+	files   []*types.FileResult
+	tags    map[string][]string
+}
+
+func (b *fakeBackend) Search(query string, opts hsdb.SearchOptions) (*hsdb.SearchPage, error) {
+	b.queries = append(b.queries, query)
+	var results []*types.FileResult
+	for _, f := range b.files {
+		if strings.HasPrefix(query, "hash:") && "hash:"+f.FileHash != query {
+			continue
+		}
+		results = append(results, f)
+	}
+	return &hsdb.SearchPage{Results: results, Total: int64(len(results))}, nil
+}
+
+func (b *fakeBackend) Facets(query string) (*hsdb.Facets, error) {
+	return &hsdb.Facets{
+		Hosts: []hsdb.FacetCount{{Value: "nas", Count: 2}, {Value: "laptop", Count: 1}},
+		Types: []hsdb.FacetCount{{Value: "document", Count: 3}},
+	}, nil
+}
+
+func (b *fakeBackend) Tags(f *types.FileResult) ([]string, error) {
+	return b.tags[f.FilePath], nil
+}
+
+func (b *fakeBackend) SetTags(f *types.FileResult, tags []string) error {
+	b.tags[f.FilePath] = tags
+	return nil
+}
+
+// run feeds msg to the model and then every message produced by the
+// resulting commands.
+func run(m *Model, msg tea.Msg) {
+	queue := []tea.Msg{msg}
+	for len(queue) > 0 {
+		msg, queue = queue[0], queue[1:]
+		_, cmd := m.Update(msg)
+		queue = append(queue, exec(cmd)...)
+	}
+}
+
+func exec(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	switch msg := cmd().(type) {
+	case tea.BatchMsg:
+		var msgs []tea.Msg
+		for _, c := range msg {
+			msgs = append(msgs, exec(c)...)
+		}
+		return msgs
+	case nil:
+		return nil
+	default:
+		return []tea.Msg{msg}
+	}
+}
+
+func newTestModel() (*Model, *fakeBackend) {
+	b := &fakeBackend{
+		files: []*types.FileResult{
+			{FilePath: "/a/report.pdf", Host: "laptop", FileHash: "h1"},
+			{FilePath: "/b/report.pdf", Host: "nas", FileHash: "h1"},
+			{FilePath: "/b/notes.txt", Host: "nas", FileHash: "h2"},
+		},
+		tags: map[string][]string{},
+	}
+	m := New(b)
+	run(m, tea.WindowSizeMsg{Width: 100, Height: 30})
+	run(m, m.search()())
+	return m, b
+}
+
+func TestModelSearch(t *testing.T) {
+	m, b := newTestModel()
+	assert.Len(t, m.results, 3)
+	assert.Equal(t, int64(3), m.total)
+	assert.Len(t, m.copies["h1"], 2)
+	assert.Contains(t, m.View(), "/b/report.pdf")
+
+	// Typing schedules a debounced search, only the latest one runs.
+	searches := len(b.queries)
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	run(m, debounceMsg{seq: m.seq - 1})
+	assert.Len(t, b.queries, searches)
+	run(m, debounceMsg{seq: m.seq})
+	assert.Equal(t, []string{"re"}, b.queries[searches:])
+}
+
+func TestModelFacets(t *testing.T) {
+	m, _ := newTestModel()
+	require.Len(t, m.facets, 3)
+
+	run(m, tea.KeyMsg{Type: tea.KeyTab})
+	assert.Equal(t, focusFacets, m.focus)
+	run(m, tea.KeyMsg{Type: tea.KeyDown})
+	run(m, tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, "host:laptop", m.Query())
+
+	run(m, tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, "", m.Query())
+}
+
+func TestModelTag(t *testing.T) {
+	m, b := newTestModel()
+	b.tags["/a/report.pdf"] = []string{"work"}
+
+	run(m, tea.KeyMsg{Type: tea.KeyCtrlT})
+	assert.Equal(t, focusTags, m.focus)
+	assert.Equal(t, "work", m.tagInput.Value())
+
+	run(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(",tax")})
+	run(m, tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, focusResults, m.focus)
+	assert.Equal(t, []string{"work", "tax"}, b.tags["/a/report.pdf"])
+	assert.Equal(t, "Tagged /a/report.pdf", m.status)
+}
@@ -0,0 +1,44 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/rubiojr/hashup/internal/crypto"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// EncodeMessage encodes f as the MessagePack message published to the
+// store, encrypted with m unless it is nil.
+func EncodeMessage(f *ScannedFile, m crypto.Machine) ([]byte, error) {
+	data, err := msgpack.Marshal(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal file message: %v", err)
+	}
+	if m == nil {
+		return data, nil
+	}
+
+	encrypted, err := m.Encrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt message: %v", err)
+	}
+	return encrypted, nil
+}
+
+// DecodeMessage decodes a message encoded by EncodeMessage, decrypting it
+// with m unless it is nil.
+func DecodeMessage(data []byte, m crypto.Machine) (*ScannedFile, error) {
+	if m != nil {
+		decrypted, err := m.Decrypt(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt message: %v", err)
+		}
+		data = decrypted
+	}
+
+	var f *ScannedFile
+	if err := msgpack.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message: %v", err)
+	}
+	return f, nil
+}
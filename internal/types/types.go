@@ -10,4 +10,19 @@ type ScannedFile struct {
 	Hash      string    `msgpack:"hash"`
 	Extension string    `msgpack:"extension"`
 	Hostname  string    `msgpack:"hostname"`
+	// Chunks is set when the scanner chunks files, see the chunker package.
+	Chunks *ChunkManifest `msgpack:"chunks,omitempty"`
+	// Removed is set for files found by the previous scan of a directory
+	// and missing from the last one. Only Path and Hostname are set then.
+	Removed bool `msgpack:"removed,omitempty"`
+}
+
+// ChunkManifest lists the content-defined chunks of a file, in order. See
+// the chunker package.
+type ChunkManifest struct {
+	// AvgSize is the average chunk size the file was split with, chunks
+	// are only comparable between manifests with the same average size.
+	AvgSize int      `msgpack:"avg_size" json:"avg_size"`
+	Hashes  []uint64 `msgpack:"hashes" json:"hashes"`
+	Sizes   []uint32 `msgpack:"sizes" json:"sizes"`
 }
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeFileHash(t *testing.T) {
+	// Content whose hash starts with a zero, which must be kept.
+	var content string
+	for i := 0; ; i++ {
+		content = strconv.Itoa(i)
+		if xxhash.Sum64String(content) < 1<<60 {
+			break
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	hash, err := ComputeFileHash(path)
+	require.NoError(t, err)
+	assert.Len(t, hash, 16)
+	assert.Equal(t, "0", hash[:1])
+
+	_, err = ComputeFileHash(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
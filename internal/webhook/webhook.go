@@ -0,0 +1,331 @@
+// Package webhook delivers saved search matches and anomaly alerts to
+// webhook URLs.
+//
+// Requests are JSON encoded Payloads signed with the saved search secret,
+// or the anomaly webhook secret for alerts.
+// The X-Hashup-Signature header carries "sha256=" followed by the hex
+// encoded HMAC-SHA256 of the body, see Verify.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rubiojr/hashup/cmd/hs/types"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/rubiojr/hashup/internal/log"
+)
+
+// Delivery headers.
+const (
+	HeaderSignature = "X-Hashup-Signature"
+	HeaderEvent     = "X-Hashup-Event"
+	HeaderDelivery  = "X-Hashup-Delivery"
+)
+
+// Payload events.
+const (
+	// EventMatch is sent when an indexed file matches a saved search.
+	EventMatch = "search.matched"
+	// EventPing is sent by Dispatcher.Ping to test a webhook.
+	EventPing = "ping"
+	// EventAnomaly is sent when the store detects unusual activity on a
+	// host.
+	EventAnomaly = "anomaly.detected"
+)
+
+// AnomalySearch is the search name of anomaly alert payloads and
+// deliveries.
+const AnomalySearch = "anomalies"
+
+// Payload is the body of webhook requests.
+type Payload struct {
+	Event  string             `json:"event"`
+	Search string             `json:"search"`
+	Query  string             `json:"query"`
+	File   *types.FileResult  `json:"file,omitempty"`
+	Alert  *hsdb.AnomalyAlert `json:"alert,omitempty"`
+	Time   time.Time          `json:"time"`
+}
+
+// Sign returns the signature of body sent in the X-Hashup-Signature
+// header.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body, for webhook
+// receivers.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+type Option func(*Dispatcher)
+
+// WithAttempts sets how many times a delivery is attempted, 5 by default.
+func WithAttempts(n int) Option {
+	return func(d *Dispatcher) {
+		d.attempts = n
+	}
+}
+
+// WithBackoff sets the wait before the first retry, doubled after every
+// failed attempt. One second by default.
+func WithBackoff(backoff time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.backoff = backoff
+	}
+}
+
+// WithWorkers sets how many deliveries are sent at once, 4 by default.
+func WithWorkers(n int) Option {
+	return func(d *Dispatcher) {
+		d.workers = n
+	}
+}
+
+// WithQueueSize sets how many deliveries can wait for a worker, 1000 by
+// default. Notifications are dropped when the queue is full.
+func WithQueueSize(n int) Option {
+	return func(d *Dispatcher) {
+		d.queueSize = n
+	}
+}
+
+// WithHTTPClient sets the client used to send requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// Dispatcher sends webhook requests, retrying failures and logging every
+// delivery to the database. Notifications are queued and delivered by a
+// fixed number of workers.
+type Dispatcher struct {
+	db        *sql.DB
+	client    *http.Client
+	attempts  int
+	backoff   time.Duration
+	workers   int
+	queueSize int
+
+	queue chan *job
+	// pending counts the queued and running deliveries
+	pending sync.WaitGroup
+	workWG  sync.WaitGroup
+	dropped atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+	// stop is closed by Close, deliveries are not retried after it
+	stop chan struct{}
+}
+
+// job is a queued delivery.
+type job struct {
+	ctx     context.Context
+	s       *hsdb.SavedSearch
+	p       *Payload
+	subject string
+}
+
+func NewDispatcher(db *sql.DB, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		db:        db,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		attempts:  5,
+		backoff:   time.Second,
+		workers:   4,
+		queueSize: 1000,
+		stop:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	d.queue = make(chan *job, max(d.queueSize, 0))
+	for range max(d.workers, 1) {
+		d.workWG.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// Notify queues the delivery of a match of file to the webhook of s.
+func (d *Dispatcher) Notify(ctx context.Context, s *hsdb.SavedSearch, file *types.FileResult) {
+	p := &Payload{Event: EventMatch, Search: s.Name, Query: s.Query, File: file, Time: time.Now().UTC()}
+	d.enqueue(ctx, s, p, "saved search "+s.Name)
+}
+
+// NotifyAnomaly queues the delivery of alert a to the webhook at url,
+// signed with secret.
+func (d *Dispatcher) NotifyAnomaly(ctx context.Context, url, secret string, a *hsdb.AnomalyAlert) {
+	s := &hsdb.SavedSearch{Name: AnomalySearch, WebhookURL: url, Secret: secret}
+	p := &Payload{Event: EventAnomaly, Search: s.Name, Alert: a, Time: time.Now().UTC()}
+	d.enqueue(ctx, s, p, fmt.Sprintf("%s alert of %s", a.Kind, a.Host))
+}
+
+// enqueue queues a delivery, dropping it when the queue is full or the
+// dispatcher closed. Queued deliveries outlive the cancellation of ctx, so
+// that Close can drain them.
+func (d *Dispatcher) enqueue(ctx context.Context, s *hsdb.SavedSearch, p *Payload, subject string) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if !d.closed {
+		d.pending.Add(1)
+		select {
+		case d.queue <- &job{ctx: context.WithoutCancel(ctx), s: s, p: p, subject: subject}:
+			return
+		default:
+			d.pending.Done()
+		}
+	}
+
+	n := d.dropped.Add(1)
+	log.Errorf("dropped webhook delivery for %s, %d dropped so far: queue full or closed", subject, n)
+}
+
+func (d *Dispatcher) work() {
+	defer d.workWG.Done()
+	for j := range d.queue {
+		if _, err := d.Deliver(j.ctx, j.s, j.p); err != nil {
+			log.Errorf("failed to deliver webhook for %s: %v", j.subject, err)
+		}
+		d.pending.Done()
+	}
+}
+
+// Dropped returns how many notifications were dropped because the queue
+// was full or the dispatcher closed.
+func (d *Dispatcher) Dropped() int64 {
+	return d.dropped.Load()
+}
+
+// Ping sends a ping event to the webhook of s, waiting for the delivery.
+func (d *Dispatcher) Ping(ctx context.Context, s *hsdb.SavedSearch) (*hsdb.Delivery, error) {
+	return d.Deliver(ctx, s, &Payload{Event: EventPing, Search: s.Name, Query: s.Query, Time: time.Now().UTC()})
+}
+
+// Wait waits for the deliveries queued by Notify and NotifyAnomaly.
+func (d *Dispatcher) Wait() {
+	d.pending.Wait()
+}
+
+// Close stops accepting notifications and waits for the queued ones to be
+// delivered. Failed deliveries are not retried once closing.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	close(d.stop)
+	close(d.queue)
+	d.mu.Unlock()
+
+	d.workWG.Wait()
+}
+
+// Deliver posts p to the webhook of s, retrying with exponential backoff
+// on network errors, 429 and 5xx responses until the dispatcher is closed. The returned delivery is
+// logged whether it succeeded or not, err is only set when logging fails.
+func (d *Dispatcher) Deliver(ctx context.Context, s *hsdb.SavedSearch, p *Payload) (*hsdb.Delivery, error) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %v", err)
+	}
+
+	delivery := &hsdb.Delivery{SearchName: s.Name, WebhookURL: s.WebhookURL, Event: p.Event}
+	if p.File != nil {
+		delivery.FilePath = p.File.FilePath
+		delivery.Host = p.File.Host
+	}
+	if p.Alert != nil {
+		delivery.Host = p.Alert.Host
+	}
+	if err := hsdb.CreateDelivery(d.db, delivery); err != nil {
+		return nil, err
+	}
+
+	backoff := d.backoff
+	for {
+		delivery.Attempts++
+		code, err := d.post(ctx, s, delivery.ID, p.Event, body)
+		delivery.ResponseCode = code
+		delivery.Error = ""
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+
+		retry := err != nil && (code == 0 || code == http.StatusTooManyRequests || code >= 500)
+		switch {
+		case err == nil:
+			delivery.Status = hsdb.DeliveryDelivered
+		case retry && delivery.Attempts < d.attempts:
+			delivery.Status = hsdb.DeliveryPending
+		default:
+			delivery.Status = hsdb.DeliveryFailed
+		}
+		if err := hsdb.UpdateDelivery(d.db, delivery); err != nil {
+			return delivery, err
+		}
+		if delivery.Status != hsdb.DeliveryPending {
+			return delivery, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			delivery.Status = hsdb.DeliveryFailed
+			delivery.Error = fmt.Sprintf("%s, not retried: %v", delivery.Error, ctx.Err())
+			return delivery, hsdb.UpdateDelivery(d.db, delivery)
+		case <-d.stop:
+			delivery.Status = hsdb.DeliveryFailed
+			delivery.Error = fmt.Sprintf("%s, not retried: dispatcher closed", delivery.Error)
+			return delivery, hsdb.UpdateDelivery(d.db, delivery)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends a signed request, returning the response status code.
+func (d *Dispatcher) post(ctx context.Context, s *hsdb.SavedSearch, id int64, event string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "hashup-webhook")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(id, 10))
+	req.Header.Set(HeaderSignature, Sign(s.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
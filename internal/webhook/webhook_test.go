@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/rubiojr/hashup/cmd/hs/types"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"ping"}`)
+	sig := Sign("s3cret", body)
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", sig)
+	assert.True(t, Verify("s3cret", body, sig))
+	assert.False(t, Verify("other", body, sig))
+	assert.False(t, Verify("s3cret", []byte(`{"event":"pong"}`), sig))
+}
+
+func TestDispatcher(t *testing.T) {
+	db, err := hsdb.OpenDatabase(filepath.Join(t.TempDir(), "hashup.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The receiver fails the first request of every delivery.
+	var requests atomic.Int32
+	var got []*Payload
+	seen := map[string]bool{}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if !Verify("s3cret", body, r.Header.Get(HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		id := r.Header.Get(HeaderDelivery)
+		if !seen[id] {
+			seen[id] = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		p := &Payload{}
+		assert.NoError(t, json.Unmarshal(body, p))
+		assert.Equal(t, p.Event, r.Header.Get(HeaderEvent))
+		got = append(got, p)
+	}))
+	defer receiver.Close()
+
+	d := NewDispatcher(db, WithBackoff(time.Millisecond), WithAttempts(3))
+	s := &hsdb.SavedSearch{Name: "keepass", Query: "ext:kdbx", WebhookURL: receiver.URL, Secret: "s3cret"}
+
+	d.Notify(context.Background(), s, &types.FileResult{FilePath: "/home/me/passwords.kdbx", Host: "laptop"})
+	d.Wait()
+	require.Len(t, got, 1)
+	assert.Equal(t, EventMatch, got[0].Event)
+	assert.Equal(t, "keepass", got[0].Search)
+	assert.Equal(t, "/home/me/passwords.kdbx", got[0].File.FilePath)
+
+	deliveries, err := hsdb.Deliveries(db, "keepass", 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, hsdb.DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusOK, deliveries[0].ResponseCode)
+	assert.Equal(t, "laptop", deliveries[0].Host)
+
+	d.NotifyAnomaly(context.Background(), receiver.URL, "s3cret", &hsdb.AnomalyAlert{
+		Host: "nas", Kind: hsdb.AnomalyMassChange, Files: 800, Paths: []string{"/srv/a.doc"},
+	})
+	d.Wait()
+	require.Len(t, got, 2)
+	assert.Equal(t, EventAnomaly, got[1].Event)
+	assert.Equal(t, AnomalySearch, got[1].Search)
+	assert.Nil(t, got[1].File)
+	assert.Equal(t, int64(800), got[1].Alert.Files)
+
+	deliveries, err = hsdb.Deliveries(db, AnomalySearch, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "nas", deliveries[0].Host)
+
+	// Client errors are not retried
+	requests.Store(0)
+	bad := &hsdb.SavedSearch{Name: "bad", WebhookURL: receiver.URL, Secret: "wrong"}
+	delivery, err := d.Ping(context.Background(), bad)
+	require.NoError(t, err)
+	assert.Equal(t, hsdb.DeliveryFailed, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusUnauthorized, delivery.ResponseCode)
+	assert.Equal(t, int32(1), requests.Load())
+
+	// Unreachable webhooks are retried until giving up
+	receiver.Close()
+	delivery, err = d.Ping(context.Background(), s)
+	require.NoError(t, err)
+	assert.Equal(t, hsdb.DeliveryFailed, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Contains(t, delivery.Error, "failed to send request")
+
+	deliveries, err = hsdb.Deliveries(db, "", 0)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 4)
+}
+
+func TestDispatcherQueue(t *testing.T) {
+	db, err := hsdb.OpenDatabase(filepath.Join(t.TempDir(), "hashup.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	received := make(chan string, 10)
+	unblock := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := &Payload{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(p))
+		received <- p.File.FilePath
+		<-unblock
+	}))
+	defer receiver.Close()
+
+	d := NewDispatcher(db, WithWorkers(1), WithQueueSize(1))
+	s := &hsdb.SavedSearch{Name: "docs", WebhookURL: receiver.URL, Secret: "s3cret"}
+
+	// The worker is busy with the first file, the second one waits in the
+	// queue and the third one is dropped
+	ctx, cancel := context.WithCancel(context.Background())
+	d.Notify(ctx, s, &types.FileResult{FilePath: "/a.txt"})
+	assert.Equal(t, "/a.txt", <-received)
+	d.Notify(ctx, s, &types.FileResult{FilePath: "/b.txt"})
+	d.Notify(ctx, s, &types.FileResult{FilePath: "/c.txt"})
+	assert.Equal(t, int64(1), d.Dropped())
+
+	// Closing drains the queue, even once the notifying context is done
+	cancel()
+	close(unblock)
+	d.Close()
+	assert.Equal(t, "/b.txt", <-received)
+
+	d.Notify(context.Background(), s, &types.FileResult{FilePath: "/d.txt"})
+	assert.Equal(t, int64(2), d.Dropped())
+
+	deliveries, err := hsdb.Deliveries(db, "docs", 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	for _, delivery := range deliveries {
+		assert.Equal(t, hsdb.DeliveryDelivered, delivery.Status)
+	}
+}
@@ -4,13 +4,10 @@ import (
 	"embed"
 	"fmt"
 	"os"
-	"path/filepath"
 	"runtime/debug"
 	"time"
 
 	"filippo.io/age"
-	"github.com/rubiojr/hashup/internal/api"
-	"github.com/rubiojr/hashup/pkg/config"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/term"
 )
@@ -50,101 +47,38 @@ func main() {
 					return nil
 				},
 			},
+			commandAPI(),
+			commandAgent(),
 			{
-				Name:  "api",
-				Usage: "Serve index API",
-				Flags: []cli.Flag{
-					&cli.StringFlag{
-						Name:  "address",
-						Usage: "Address to listen on",
-						Value: "localhost:8448",
-					},
-					&cli.IntFlag{
-						Name:  "limit",
-						Usage: "Maximum number of results to return",
-						Value: 100,
-					},
-					&cli.StringFlag{
-						Name:  "config",
-						Usage: "Path to the configuration file",
-					},
-				},
+				Name:    "scan",
+				Aliases: []string{"i"},
+				Usage:   "Scan files recursively",
+				Flags:   scanFlags(),
 				Action: func(c *cli.Context) error {
-					cfgPath := c.String("config")
-					if cfgPath == "" {
-						cfgDir, err := config.DefaultConfigDir()
-						if err != nil {
-							return err
-						}
-						cfgPath = filepath.Join(cfgDir, "config.toml")
+					if c.Bool("debug") {
+						os.Setenv("HASHUP_DEBUG", "1")
 					}
-					return api.Serve(cfgPath, c.String("address"))
+					if c.String("every") != "" {
+						return runEvery(c, false)
+					}
+					return runScanner(c, false)
 				},
 			},
 			{
-				Name:    "scan",
-				Aliases: []string{"i"},
-				Usage:   "Scan files recursively",
-				Flags: []cli.Flag{
-					&cli.StringFlag{
-						Name:  "config",
-						Usage: "Path to the configuration file",
-						Value: "",
-					},
-					&cli.StringFlag{
-						Name:    "nats-url",
-						Usage:   "NATS URL",
-						EnvVars: []string{"HASHUP_NATS_URL"},
-					},
-					&cli.BoolFlag{
-						Name:  "debug",
-						Value: false,
-						Usage: "HASHUP_DEBUG",
-					},
-					&cli.StringFlag{
-						Name:  "ignore-file",
-						Value: "",
-						Usage: "List of files to ignore when scanning",
-					},
-					&cli.BoolFlag{
-						Name:  "ignore-hidden",
-						Value: true,
-						Usage: "Do not scann hidden files and directories",
-					},
-					&cli.IntFlag{
-						Name:  "concurrency",
-						Usage: "Number of concurrent workers",
-					},
-					&cli.StringFlag{
-						Name:    "encryption-key",
-						Usage:   "Key to use for encryption (if empty, a random key is generated)",
-						EnvVars: []string{"HASHUP_ENCRYPTION_KEY"},
-					},
-					&cli.StringFlag{
-						Name:  "client-cert",
-						Usage: "TLS client key",
-					},
-					&cli.StringFlag{
-						Name:  "client-key",
-						Usage: "TLS client cert",
-					},
-					&cli.StringFlag{
-						Name:  "ca-cert",
-						Usage: "TLS CA cert",
-					},
-					&cli.StringFlag{
-						Name:  "every",
-						Usage: "Run the scanner regularly. Interval specified in seconds(s), minutes(m) or hours(h)",
-					},
-				},
+				Name:  "verify",
+				Usage: "Re-hash files the scanner cache would skip, checking the index for bit-rot",
+				Description: "Scans like scan, publishing every file even if unchanged since the last scan.\n" +
+					"The store flags files whose content changed while their size and modification\n" +
+					"time did not, see `hs integrity`. Use --every to verify regularly.",
+				Flags: scanFlags(),
 				Action: func(c *cli.Context) error {
 					if c.Bool("debug") {
 						os.Setenv("HASHUP_DEBUG", "1")
 					}
 					if c.String("every") != "" {
-						return runEvery(c)
+						return runEvery(c, true)
 					}
-					return runScanner(c)
+					return runScanner(c, true)
 				},
 			},
 			{
@@ -14,6 +14,10 @@ type Config struct {
 	Main    MainConfig    `toml:"main"`
 	Store   StoreConfig   `toml:"store"`
 	Scanner ScannerConfig `toml:"scanner"`
+	API     APIConfig     `toml:"api"`
+	Client  ClientConfig  `toml:"client"`
+	Anomaly AnomalyConfig `toml:"anomaly"`
+	Agent   AgentConfig   `toml:"agent"`
 	Path    string
 }
 
@@ -39,6 +43,87 @@ type ScannerConfig struct {
 	ScanningInterval    int    `toml:"scanning_interval"`
 	ScanningConcurrency int    `toml:"scanning_concurrency"`
 	CachePath           string `toml:"cache_path"`
+	// Send the chunk manifests of files of at least ChunkMinSize bytes,
+	// used to find near-duplicates and to transfer only missing chunks
+	Chunking     bool  `toml:"chunking"`
+	ChunkMinSize int64 `toml:"chunk_min_size"`
+}
+
+// APIConfig represents the API server configuration section
+type APIConfig struct {
+	ListenAddr string `toml:"listen_addr"`
+	// TLS is enabled when both the certificate and key are set
+	TLSCert string `toml:"tls_cert"`
+	TLSKey  string `toml:"tls_key"`
+	// Origins allowed to make cross-origin requests, "*" allows any
+	AllowedOrigins []string `toml:"allowed_origins"`
+	// Number of results returned when requests don't set a limit
+	DefaultLimit int `toml:"default_limit"`
+	// Require a token created with `hashup api token create` to read the
+	// index, changing it always does. The web search page asks for it once
+	// per browser session.
+	RequireAuth bool `toml:"require_auth"`
+	// Serve the web search page at /
+	UI bool `toml:"ui"`
+	// Name identifies this index in federated search results
+	Name string `toml:"name"`
+	// Other API servers queried by /v1/search/federated
+	Peers []PeerConfig `toml:"peers"`
+	// Seconds to wait for peers before leaving their results out
+	PeerTimeout int `toml:"peer_timeout"`
+	// Seconds a request may run before it is cancelled
+	QueryTimeout int `toml:"query_timeout"`
+	// Requests per second allowed to each client, 0 disables rate limiting
+	RateLimit float64 `toml:"rate_limit"`
+	// Requests a client may make at once above the rate limit
+	RateBurst int `toml:"rate_burst"`
+}
+
+// PeerConfig is a peer API server in the [[api.peers]] sections
+type PeerConfig struct {
+	Name string `toml:"name"`
+	URL  string `toml:"url"`
+	// Token for peers that require authentication
+	Token string `toml:"token"`
+	// CA certificate verifying the peer TLS certificate
+	CACert string `toml:"ca_cert"`
+}
+
+// ClientConfig represents the hs client configuration section
+type ClientConfig struct {
+	// Query this API server instead of the local database
+	ServerURL string `toml:"server_url"`
+	// Token for servers that require authentication
+	Token string `toml:"token"`
+	// CA certificate verifying the server TLS certificate
+	CACert string `toml:"ca_cert"`
+}
+
+// AnomalyConfig represents the store mass-change detection section
+type AnomalyConfig struct {
+	// Detect hosts changing many more files than usual
+	Enabled bool `toml:"enabled"`
+	// Seconds of file modification times counted together
+	Window int `toml:"window"`
+	// Changed files in a window needed to raise an alert
+	MinChanges int `toml:"min_changes"`
+	// Times the usual number of changed files needed to raise an alert
+	Factor float64 `toml:"factor"`
+	// New files with extensions new to the host needed to raise an alert
+	MinNewExtensions int `toml:"min_new_extensions"`
+	// Hosts never alerted on
+	QuietHosts []string `toml:"quiet_hosts"`
+	// Webhook notified of alerts, requests are signed with the secret
+	WebhookURL    string `toml:"webhook_url"`
+	WebhookSecret string `toml:"webhook_secret"`
+}
+
+// AgentConfig represents the retrieval agent section
+type AgentConfig struct {
+	// Directories the agent serves files from
+	Roots []string `toml:"roots"`
+	// Prefix of the per host subjects the agent answers on
+	Subject string `toml:"subject"`
 }
 
 func (c Config) NormalizePath(file string) string {
@@ -84,6 +169,25 @@ func DefaultConfig() *Config {
 			ScanningInterval:    3600, // 1 hour in seconds
 			ScanningConcurrency: 5,
 			CachePath:           DefaultCachePath(),
+			ChunkMinSize:        1 << 20,
+		},
+		API: APIConfig{
+			ListenAddr:   "localhost:8448",
+			DefaultLimit: 100,
+			Name:         "local",
+			PeerTimeout:  5,
+			QueryTimeout: 60,
+			RateBurst:    20,
+		},
+		Anomaly: AnomalyConfig{
+			Enabled:          true,
+			Window:           300,
+			MinChanges:       500,
+			Factor:           10,
+			MinNewExtensions: 100,
+		},
+		Agent: AgentConfig{
+			Subject: "HASHUP.get",
 		},
 	}
 }
@@ -106,6 +210,15 @@ func LoadConfig(path string) (*Config, error) {
 	config.Main.ClientCert = config.NormalizePath(config.Main.ClientCert)
 	config.Main.CACert = config.NormalizePath(config.Main.CACert)
 	config.Store.DBPath = config.NormalizePath(config.Store.DBPath)
+	config.API.TLSCert = config.NormalizePath(config.API.TLSCert)
+	config.API.TLSKey = config.NormalizePath(config.API.TLSKey)
+	for i := range config.API.Peers {
+		config.API.Peers[i].CACert = config.NormalizePath(config.API.Peers[i].CACert)
+	}
+	config.Client.CACert = config.NormalizePath(config.Client.CACert)
+	for i := range config.Agent.Roots {
+		config.Agent.Roots[i] = config.NormalizePath(config.Agent.Roots[i])
+	}
 
 	return config, nil
 }
@@ -26,6 +26,17 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, 3600, cfg.Scanner.ScanningInterval)
 	assert.Equal(t, 5, cfg.Scanner.ScanningConcurrency)
 	assert.Equal(t, expectedCachePath, cfg.Scanner.CachePath)
+	assert.False(t, cfg.Scanner.Chunking)
+	assert.Equal(t, int64(1<<20), cfg.Scanner.ChunkMinSize)
+	assert.Equal(t, "localhost:8448", cfg.API.ListenAddr)
+	assert.Equal(t, 100, cfg.API.DefaultLimit)
+	assert.False(t, cfg.API.RequireAuth)
+	assert.True(t, cfg.Anomaly.Enabled)
+	assert.Equal(t, 300, cfg.Anomaly.Window)
+	assert.Equal(t, 500, cfg.Anomaly.MinChanges)
+	assert.Equal(t, 10.0, cfg.Anomaly.Factor)
+	assert.Equal(t, 100, cfg.Anomaly.MinNewExtensions)
+	assert.Equal(t, "HASHUP.get", cfg.Agent.Subject)
 }
 
 func TestNormalizePath(t *testing.T) {
@@ -80,6 +91,78 @@ func TestLoadConfig(t *testing.T) {
 	assert.Contains(t, err.Error(), "config file not found")
 }
 
+func TestLoadAPIConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(configPath, []byte(`
+[api]
+listen_addr = ":8443"
+tls_cert = "certs/api.pem"
+tls_key = "/etc/hashup/api-key.pem"
+allowed_origins = ["https://app.example.com"]
+require_auth = true
+rate_limit = 2.5
+
+[[api.peers]]
+name = "office"
+url = "https://office.example.com:8448"
+token = "hsk_office"
+ca_cert = "certs/office-ca.pem"
+
+[[api.peers]]
+name = "lab"
+url = "http://lab:8448"
+`), 0600)
+	assert.NoError(t, err)
+
+	cfg, err := config.LoadConfig(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, ":8443", cfg.API.ListenAddr)
+	assert.Equal(t, filepath.Join(filepath.Dir(configPath), "certs/api.pem"), cfg.API.TLSCert)
+	assert.Equal(t, "/etc/hashup/api-key.pem", cfg.API.TLSKey)
+	assert.Equal(t, []string{"https://app.example.com"}, cfg.API.AllowedOrigins)
+	assert.Equal(t, 100, cfg.API.DefaultLimit)
+	assert.True(t, cfg.API.RequireAuth)
+	assert.Equal(t, "local", cfg.API.Name)
+	assert.Equal(t, 5, cfg.API.PeerTimeout)
+	assert.Equal(t, 60, cfg.API.QueryTimeout)
+	assert.Equal(t, 2.5, cfg.API.RateLimit)
+	assert.Equal(t, 20, cfg.API.RateBurst)
+	assert.Equal(t, []config.PeerConfig{
+		{Name: "office", URL: "https://office.example.com:8448", Token: "hsk_office", CACert: filepath.Join(filepath.Dir(configPath), "certs/office-ca.pem")},
+		{Name: "lab", URL: "http://lab:8448"},
+	}, cfg.API.Peers)
+}
+
+func TestLoadClientConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(configPath, []byte(`
+[store]
+db_path = "index/hashup.db"
+
+[client]
+server_url = "https://hashup.example.com:8448"
+token = "hsk_client"
+ca_cert = "certs/api-ca.pem"
+
+[agent]
+roots = ["/srv/photos", "docs"]
+`), 0600)
+	assert.NoError(t, err)
+
+	cfg, err := config.LoadConfig(configPath)
+	assert.NoError(t, err)
+
+	dir := filepath.Dir(configPath)
+	assert.Equal(t, filepath.Join(dir, "index/hashup.db"), cfg.Store.DBPath)
+	assert.Equal(t, config.ClientConfig{
+		ServerURL: "https://hashup.example.com:8448",
+		Token:     "hsk_client",
+		CACert:    filepath.Join(dir, "certs/api-ca.pem"),
+	}, cfg.Client)
+	assert.Equal(t, []string{"/srv/photos", filepath.Join(dir, "docs")}, cfg.Agent.Roots)
+	assert.Equal(t, "HASHUP.get", cfg.Agent.Subject)
+}
+
 func TestSaveConfig(t *testing.T) {
 	tempDir := t.TempDir()
 	configPath := filepath.Join(tempDir, "config.toml")
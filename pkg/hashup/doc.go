@@ -0,0 +1,18 @@
+// Package hashup is the Go API of HashUp, for programs that scan, store
+// and search file indexes without shelling out to the hashup and hs
+// commands.
+//
+// A Scanner walks a directory and hands every file found to a Processor.
+// NATSProcessor publishes them to the stream read by the store, and
+// ProcessorFunc turns any function into a Processor. Storage saves files to
+// an index database. An Index searches a database directly, a Client
+// searches through an API server, both with the query language of
+// `hs search`. EncodeMessage and DecodeMessage convert files to and from
+// the messages published to NATS.
+//
+// # Stability
+//
+// The package follows semantic versioning: within a major version exported
+// identifiers are neither removed nor changed incompatibly, new ones may be
+// added. Packages under internal/ carry no such promise.
+package hashup
@@ -0,0 +1,87 @@
+package hashup_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rubiojr/hashup/pkg/hashup"
+)
+
+// Scan a directory into an index database and search it.
+func Example() {
+	ctx := context.Background()
+	dir, _ := os.MkdirTemp("", "hashup-example")
+	defer os.RemoveAll(dir)
+	os.WriteFile(filepath.Join(dir, "invoice.pdf"), []byte("invoice"), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0o644)
+
+	dbPath := filepath.Join(dir, "hashup.db")
+	storage, err := hashup.OpenStorage(dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	scanner := hashup.NewScanner(dir, hashup.WithIgnore(`\.db`))
+	_, err = scanner.Scan(ctx, hashup.ProcessorFunc(func(path string, f hashup.File) error {
+		_, err := storage.Store(ctx, &f)
+		return err
+	}))
+	storage.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	index, err := hashup.OpenIndex(dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer index.Close()
+
+	page, err := index.Search("ext:pdf", hashup.SearchOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, r := range page.Results {
+		fmt.Println(filepath.Base(r.Path), r.Size)
+	}
+	// Output: invoice.pdf 7
+}
+
+// Encode a file as an encrypted message for the store.
+func ExampleEncodeMessage() {
+	key := "AGE-SECRET-KEY-1FDQ7Q24T2Q3CC6SFLS33PV5P3A59RH89PCQ0PAU6FQ8GNWD9HNASTSQP57"
+	f := &hashup.File{
+		Path:      "/srv/data/report.csv",
+		Size:      1024,
+		ModTime:   time.Now(),
+		Hash:      "9f2b5e1c0a7d3e48",
+		Extension: "csv",
+		Hostname:  "nas",
+	}
+
+	data, err := hashup.EncodeMessage(f, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	decoded, err := hashup.DecodeMessage(data, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(decoded.Path, decoded.Hash)
+	// Output: /srv/data/report.csv 9f2b5e1c0a7d3e48
+}
+
+// Search an index through an API server.
+func ExampleClient_Search() {
+	client := hashup.NewClient("https://hashup.example.com:8448", hashup.WithToken(os.Getenv("HASHUP_API_TOKEN")))
+	page, err := client.Search("ext:jpg size:>1MB", hashup.SearchOptions{Sort: hashup.SortSize, Limit: 10})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, r := range page.Results {
+		fmt.Println(r.Host, r.Path)
+	}
+}
@@ -0,0 +1,126 @@
+package hashup
+
+import (
+	"time"
+
+	hstypes "github.com/rubiojr/hashup/cmd/hs/types"
+	"github.com/rubiojr/hashup/internal/crypto"
+	"github.com/rubiojr/hashup/internal/types"
+	"github.com/rubiojr/hashup/internal/util"
+)
+
+// File is a scanned file, as published to the store.
+type File struct {
+	Path    string    `msgpack:"path"`
+	Size    int64     `msgpack:"size"`
+	ModTime time.Time `msgpack:"mod_time"`
+	// Hash is the hex encoded xxHash64 of the content
+	Hash      string `msgpack:"hash"`
+	Extension string `msgpack:"extension"`
+	Hostname  string `msgpack:"hostname"`
+	// Chunks lists the content-defined chunks of the file, set by scans
+	// WithChunking.
+	Chunks *ChunkManifest `msgpack:"chunks,omitempty"`
+	// Removed reports a file found by a previous scan and since removed
+	// from disk. Only Path and Hostname are set then.
+	Removed bool `msgpack:"removed,omitempty"`
+}
+
+// ChunkManifest lists the content-defined chunks of a file, in order, as
+// split by FastCDC with an average chunk size of AvgSize.
+type ChunkManifest struct {
+	// AvgSize is the average chunk size the file was split with, chunks
+	// are only comparable between manifests with the same average size.
+	AvgSize int      `msgpack:"avg_size" json:"avg_size"`
+	Hashes  []uint64 `msgpack:"hashes" json:"hashes"`
+	Sizes   []uint32 `msgpack:"sizes" json:"sizes"`
+}
+
+// newFile converts a file from the scanner and the store.
+func newFile(msg *types.ScannedFile) File {
+	return File{
+		Path:      msg.Path,
+		Size:      msg.Size,
+		ModTime:   msg.ModTime,
+		Hash:      msg.Hash,
+		Extension: msg.Extension,
+		Hostname:  msg.Hostname,
+		Chunks:    (*ChunkManifest)(msg.Chunks),
+		Removed:   msg.Removed,
+	}
+}
+
+// internal converts f for the scanner and the store.
+func (f *File) internal() *types.ScannedFile {
+	return &types.ScannedFile{
+		Path:      f.Path,
+		Size:      f.Size,
+		ModTime:   f.ModTime,
+		Hash:      f.Hash,
+		Extension: f.Extension,
+		Hostname:  f.Hostname,
+		Chunks:    (*types.ChunkManifest)(f.Chunks),
+		Removed:   f.Removed,
+	}
+}
+
+// Result is an indexed file found by a search.
+type Result struct {
+	Path      string    `json:"file_path"`
+	Size      int64     `json:"file_size"`
+	ModTime   time.Time `json:"modified_date"`
+	Host      string    `json:"host"`
+	Extension string    `json:"extension"`
+	Hash      string    `json:"file_hash"`
+}
+
+func newResult(r *hstypes.FileResult) *Result {
+	return &Result{
+		Path:      r.FilePath,
+		Size:      r.FileSize,
+		ModTime:   r.ModifiedDate,
+		Host:      r.Host,
+		Extension: r.Extension,
+		Hash:      r.FileHash,
+	}
+}
+
+// HashFile returns the content hash of the file at path, as found in
+// File.Hash and in the index.
+func HashFile(path string) (string, error) {
+	return util.ComputeFileHash(path)
+}
+
+// EncodeMessage encodes f as a message for the store, encrypted with the
+// age secret key encryptionKey (main.encryption_key in the configuration).
+// An empty key leaves the message in clear, publishers must then not set
+// the Encrypted header.
+func EncodeMessage(f *File, encryptionKey string) ([]byte, error) {
+	m, err := machine(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return types.EncodeMessage(f.internal(), m)
+}
+
+// DecodeMessage decodes a message encoded by EncodeMessage with the same
+// key.
+func DecodeMessage(data []byte, encryptionKey string) (*File, error) {
+	m, err := machine(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := types.DecodeMessage(data, m)
+	if err != nil {
+		return nil, err
+	}
+	f := newFile(msg)
+	return &f, nil
+}
+
+func machine(encryptionKey string) (crypto.Machine, error) {
+	if encryptionKey == "" {
+		return nil, nil
+	}
+	return crypto.NewAge(encryptionKey)
+}
@@ -0,0 +1,151 @@
+package hashup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "AGE-SECRET-KEY-1FDQ7Q24T2Q3CC6SFLS33PV5P3A59RH89PCQ0PAU6FQ8GNWD9HNASTSQP57"
+
+func TestScanStoreSearch(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.pdf"), []byte("report"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden.txt"), []byte("hidden"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "skip"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "skip", "skipped.txt"), []byte("skipped"), 0o644))
+
+	dbPath := filepath.Join(t.TempDir(), "hashup.db")
+	s, err := OpenStorage(dbPath)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var found []string
+	scanner := NewScanner(dir, WithIgnore("/skip/"), WithConcurrency(2))
+	_, err = scanner.Scan(ctx, ProcessorFunc(func(path string, f File) error {
+		mu.Lock()
+		found = append(found, filepath.Base(path))
+		mu.Unlock()
+		_, err := s.Store(ctx, &f)
+		return err
+	}))
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+	assert.ElementsMatch(t, []string{"report.pdf", "notes.txt"}, found)
+
+	idx, err := OpenIndex(dbPath)
+	require.NoError(t, err)
+	defer idx.Close()
+
+	page, err := idx.Search("ext:pdf", SearchOptions{Count: true})
+	require.NoError(t, err)
+	require.Len(t, page.Results, 1)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, "report.pdf", filepath.Base(page.Results[0].Path))
+	assert.Equal(t, int64(6), page.Results[0].Size)
+	hash, err := HashFile(filepath.Join(dir, "report.pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, hash, page.Results[0].Hash)
+
+	_, err = idx.Search("size:>", SearchOptions{})
+	var qerr *QueryError
+	assert.ErrorAs(t, err, &qerr)
+}
+
+func TestScanChunking(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "small.txt"), []byte("small"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "large.bin"), make([]byte, 1<<20), 0o644))
+
+	var mu sync.Mutex
+	chunks := map[string]*ChunkManifest{}
+	_, err := NewScanner(dir, WithChunking(1024)).Scan(context.Background(), ProcessorFunc(func(path string, f File) error {
+		mu.Lock()
+		chunks[filepath.Base(path)] = f.Chunks
+		mu.Unlock()
+		return nil
+	}))
+	require.NoError(t, err)
+
+	assert.Nil(t, chunks["small.txt"])
+	require.NotNil(t, chunks["large.bin"])
+	assert.NotEmpty(t, chunks["large.bin"].Hashes)
+	var size int64
+	for _, s := range chunks["large.bin"].Sizes {
+		size += int64(s)
+	}
+	assert.Equal(t, int64(1<<20), size)
+}
+
+func TestScanHidden(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden.txt"), []byte("hidden"), 0o644))
+
+	var found []string
+	_, err := NewScanner(dir, WithHidden()).Scan(context.Background(), ProcessorFunc(func(path string, f File) error {
+		found = append(found, filepath.Base(path))
+		return nil
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{".hidden.txt"}, found)
+}
+
+func TestStoreResult(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStorage(filepath.Join(t.TempDir(), "hashup.db"))
+	require.NoError(t, err)
+	defer s.Close()
+
+	f := &File{Path: "/a.txt", Size: 1, ModTime: time.Now(), Hash: "0123456789abcdef", Extension: "txt", Hostname: "host"}
+	r, err := s.Store(ctx, f)
+	require.NoError(t, err)
+	assert.True(t, r.NewHash)
+	assert.True(t, r.NewFile)
+	assert.NotZero(t, r.FileID)
+
+	r, err = s.Store(ctx, f)
+	require.NoError(t, err)
+	assert.False(t, r.NewHash)
+	assert.False(t, r.NewFile)
+
+	r, err = s.Store(ctx, &File{Path: "/a.txt", Hostname: "host", Removed: true})
+	require.NoError(t, err)
+	assert.True(t, r.Removed)
+}
+
+func TestMessageRoundtrip(t *testing.T) {
+	f := &File{
+		Path:      "/home/user/a.txt",
+		Size:      42,
+		ModTime:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Hash:      "0123456789abcdef",
+		Extension: "txt",
+		Hostname:  "host",
+		Chunks:    &ChunkManifest{AvgSize: 8192, Hashes: []uint64{1, 2}, Sizes: []uint32{30, 12}},
+	}
+
+	for _, key := range []string{"", testKey} {
+		data, err := EncodeMessage(f, key)
+		require.NoError(t, err)
+		got, err := DecodeMessage(data, key)
+		require.NoError(t, err)
+		assert.Equal(t, f.Path, got.Path)
+		assert.Equal(t, f.Hash, got.Hash)
+		assert.True(t, f.ModTime.Equal(got.ModTime))
+		assert.Equal(t, f.Chunks, got.Chunks)
+		assert.False(t, got.Removed)
+	}
+
+	data, err := EncodeMessage(f, testKey)
+	require.NoError(t, err)
+	_, err = DecodeMessage(data, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package hashup
+
+import (
+	"context"
+	"time"
+
+	"github.com/rubiojr/hashup/internal/cache"
+	"github.com/rubiojr/hashup/internal/processors/nats"
+	"github.com/rubiojr/hashup/internal/scanner"
+	"github.com/rubiojr/hashup/internal/types"
+	"github.com/rubiojr/hashup/pkg/config"
+)
+
+// Processor receives the files found by a Scanner. Process is called from
+// several goroutines at once, path is the absolute path of the file.
+type Processor interface {
+	Process(path string, f File) error
+}
+
+// ProcessorFunc adapts a function to the Processor interface.
+type ProcessorFunc func(path string, f File) error
+
+func (fn ProcessorFunc) Process(path string, f File) error {
+	return fn(path, f)
+}
+
+// processor adapts a Processor to the scanner.
+type processor struct {
+	p Processor
+}
+
+func (p processor) Process(path string, msg types.ScannedFile) error {
+	return p.p.Process(path, newFile(&msg))
+}
+
+type scanOptions struct {
+	ignore       []string
+	concurrency  int
+	hidden       bool
+	cachePath    string
+	chunkMinSize int64
+}
+
+type ScanOption func(*scanOptions)
+
+// WithIgnore skips the files and directories whose absolute path matches
+// any of the regular expressions.
+func WithIgnore(patterns ...string) ScanOption {
+	return func(o *scanOptions) {
+		o.ignore = append(o.ignore, patterns...)
+	}
+}
+
+// WithConcurrency sets how many files are hashed at once, 5 by default.
+func WithConcurrency(n int) ScanOption {
+	return func(o *scanOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithHidden includes hidden files and directories, skipped by default.
+func WithHidden() ScanOption {
+	return func(o *scanOptions) {
+		o.hidden = true
+	}
+}
+
+// WithCache skips files already processed by a scan using the cache at
+// path, unless they changed. Scans don't use a cache by default.
+func WithCache(path string) ScanOption {
+	return func(o *scanOptions) {
+		o.cachePath = path
+	}
+}
+
+// WithChunking sets File.Chunks for the files of at least minSize bytes,
+// at the cost of reading them twice.
+func WithChunking(minSize int64) ScanOption {
+	return func(o *scanOptions) {
+		o.chunkMinSize = max(minSize, 1)
+	}
+}
+
+// Scanner finds and hashes the files below a directory.
+type Scanner struct {
+	root string
+	opts scanOptions
+}
+
+func NewScanner(root string, opts ...ScanOption) *Scanner {
+	s := &Scanner{root: root, opts: scanOptions{concurrency: 5}}
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+	return s
+}
+
+// Scan walks the directory, calling p for every regular file that is not
+// ignored, and returns the number of entries walked. Files are hashed
+// concurrently, Scan returns once all of them are processed.
+func (s *Scanner) Scan(ctx context.Context, p Processor) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var c cache.Cache = cache.NewNoopCache()
+	if s.opts.cachePath != "" {
+		c = cache.NewFileCache(ctx, 100, s.opts.cachePath)
+	}
+
+	opts := []scanner.Option{
+		scanner.WithIgnoreList(s.opts.ignore),
+		scanner.WithIgnoreHidden(!s.opts.hidden),
+		scanner.WithScanningConcurrency(max(s.opts.concurrency, 1)),
+		scanner.WithCache(c),
+	}
+	if s.opts.chunkMinSize > 0 {
+		opts = append(opts, scanner.WithChunking(s.opts.chunkMinSize))
+	}
+	ds := scanner.NewDirectoryScanner(s.root, opts...)
+	return ds.ScanDirectory(ctx, processor{p})
+}
+
+// NATSProcessor publishes files to the NATS stream read by the store.
+type NATSProcessor struct {
+	p interface {
+		Process(path string, msg types.ScannedFile) error
+		Close()
+	}
+}
+
+// NewNATSProcessor connects to the NATS server of the [main] configuration
+// section. Messages are encrypted with its encryption key.
+func NewNATSProcessor(ctx context.Context, cfg *config.Config) (*NATSProcessor, error) {
+	opts := []nats.Option{nats.WithEncryptionKey(cfg.Main.EncryptionKey)}
+	if cfg.Main.ClientKey != "" {
+		opts = append(opts,
+			nats.WithClientKey(cfg.Main.ClientKey),
+			nats.WithClientCert(cfg.Main.ClientCert),
+			nats.WithCACert(cfg.Main.CACert),
+		)
+	}
+
+	p, err := nats.NewNATSProcessor(ctx, cfg.Main.NatsServerURL, cfg.Main.NatsStream, cfg.Main.NatsSubject, time.Second, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &NATSProcessor{p: p}, nil
+}
+
+func (p *NATSProcessor) Process(path string, f File) error {
+	return p.p.Process(path, *f.internal())
+}
+
+// Close closes the connection to the NATS server.
+func (p *NATSProcessor) Close() {
+	p.p.Close()
+}
@@ -0,0 +1,183 @@
+package hashup
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/hashup/internal/api"
+	hsdb "github.com/rubiojr/hashup/internal/db"
+)
+
+// Search result orders.
+const (
+	SortRelevance = "relevance"
+	SortModified  = "modified"
+	SortSize      = "size"
+	SortPath      = "path"
+	SortHost      = "host"
+)
+
+// SearchOptions refine a search.
+type SearchOptions struct {
+	// Extensions and Hosts restrict results to any of the given values,
+	// in addition to the filters in the query.
+	Extensions []string
+	Hosts      []string
+	// Sort is one of the Sort constants, SortRelevance by default.
+	Sort string
+	// Order is asc or desc. Defaults to descending for modified and size
+	// and ascending otherwise.
+	Order string
+	// Cursor continues a previous search from SearchPage.NextCursor.
+	Cursor string
+	// Count requests the total number of matching files.
+	Count bool
+	Limit int
+}
+
+func (o SearchOptions) internal() hsdb.SearchOptions {
+	return hsdb.SearchOptions{
+		Extensions: o.Extensions,
+		Hosts:      o.Hosts,
+		Sort:       o.Sort,
+		Order:      o.Order,
+		Cursor:     o.Cursor,
+		Count:      o.Count,
+		Limit:      o.Limit,
+	}
+}
+
+// SearchPage is a page of search results.
+type SearchPage struct {
+	Results []*Result
+	// NextCursor fetches the following page. Empty on the last page.
+	NextCursor string
+	// Total is the number of matching files, or -1 when not requested.
+	Total int64
+}
+
+func newSearchPage(p *hsdb.SearchPage) *SearchPage {
+	page := &SearchPage{NextCursor: p.NextCursor, Total: p.Total}
+	for _, r := range p.Results {
+		page.Results = append(page.Results, newResult(r))
+	}
+	return page
+}
+
+// Searcher runs queries in the `hs search` query language, such as
+// `ext:pdf size:>1MB invoice`.
+type Searcher interface {
+	Search(query string, opts SearchOptions) (*SearchPage, error)
+}
+
+// Index searches an index database directly.
+type Index struct {
+	pool *hsdb.Pool
+}
+
+// OpenIndex opens the index database at dbPath for reading. It may be in
+// use by the store at the same time.
+func OpenIndex(dbPath string) (*Index, error) {
+	pool, err := hsdb.OpenReadOnly(dbPath, 4)
+	if err != nil {
+		return nil, err
+	}
+	return &Index{pool: pool}, nil
+}
+
+// Search returns the files matching query. Invalid queries return a
+// *QueryError.
+func (i *Index) Search(query string, opts SearchOptions) (*SearchPage, error) {
+	p, err := hsdb.SearchFiles(i.pool.WithContext(context.Background()), query, opts.internal())
+	var qerr *hsdb.QueryError
+	if errors.As(err, &qerr) {
+		return nil, &QueryError{Query: qerr.Query, Pos: qerr.Pos, Msg: qerr.Msg}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newSearchPage(p), nil
+}
+
+func (i *Index) Close() error {
+	return i.pool.Close()
+}
+
+// QueryError reports an invalid query and the offset where it was found.
+type QueryError struct {
+	Query string
+	// Pos is the byte offset of the offending token in Query.
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query: %s (at column %d)", e.Msg, e.Pos+1)
+}
+
+// Caret returns the query with a second line pointing at the error.
+func (e *QueryError) Caret() string {
+	return e.Query + "\n" + strings.Repeat(" ", len([]rune(e.Query[:e.Pos]))) + "^"
+}
+
+// Client searches through a HashUp API server.
+type Client struct {
+	c *api.Client
+}
+
+type clientOptions struct {
+	api []api.ClientOption
+}
+
+type ClientOption func(*clientOptions)
+
+// WithToken authenticates requests with an API token created by
+// `hashup api token create`.
+func WithToken(token string) ClientOption {
+	return func(o *clientOptions) {
+		o.api = append(o.api, api.WithToken(token))
+	}
+}
+
+// WithRootCAs verifies the server certificate with the given CAs instead
+// of the system ones.
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(o *clientOptions) {
+		o.api = append(o.api, api.WithRootCAs(pool))
+	}
+}
+
+// WithTimeout sets the time limit of requests, 10 seconds by default.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.api = append(o.api, api.WithTimeout(timeout))
+	}
+}
+
+// LoadCACert reads a PEM encoded CA certificate for WithRootCAs.
+func LoadCACert(path string) (*x509.CertPool, error) {
+	return api.LoadCACert(path)
+}
+
+// NewClient returns a client for the API server at serverURL, such as
+// https://hashup.example.com:8448.
+func NewClient(serverURL string, opts ...ClientOption) *Client {
+	o := &clientOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &Client{c: api.NewClient(serverURL, o.api...)}
+}
+
+// Search returns the files matching query.
+func (c *Client) Search(query string, opts SearchOptions) (*SearchPage, error) {
+	p, err := c.c.SearchFiles(query, opts.internal())
+	if err != nil {
+		return nil, err
+	}
+	return newSearchPage(p), nil
+}
@@ -0,0 +1,57 @@
+package hashup
+
+import (
+	"context"
+
+	"github.com/rubiojr/hashup/internal/store"
+)
+
+// Storage saves files to an index.
+type Storage interface {
+	Store(ctx context.Context, f *File) (Stored, error)
+	Close() error
+}
+
+// Stored reports what Storage.Store wrote.
+type Stored struct {
+	// NewHash is set when the content was not indexed yet
+	NewHash bool
+	// NewFile is set when the file, or this version of it, was not indexed
+	// yet
+	NewFile bool
+	// FileID identifies the indexed file when NewFile is set
+	FileID int64
+	// Corrupted is set when the content changed while the size and
+	// modification time did not, suggesting silent corruption. The file is
+	// recorded as an integrity event instead of a new version.
+	Corrupted bool
+	// Removed is set when the file was recorded as removed, see
+	// File.Removed.
+	Removed bool
+}
+
+// OpenStorage opens the index database at dbPath, creating it if needed.
+// It is the storage used by `hashup store`.
+func OpenStorage(dbPath string) (Storage, error) {
+	s, err := store.NewSqliteStorage(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteStorage{s: s}, nil
+}
+
+type sqliteStorage struct {
+	s interface {
+		store.Storage
+		Close() error
+	}
+}
+
+func (s *sqliteStorage) Store(ctx context.Context, f *File) (Stored, error) {
+	r, err := s.s.Store(ctx, f.internal())
+	return Stored{NewHash: r.FileHash, NewFile: r.FileInfo, FileID: r.FileID, Corrupted: r.Corrupted, Removed: r.Removed}, err
+}
+
+func (s *sqliteStorage) Close() error {
+	return s.s.Close()
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/rubiojr/hashup/internal/cache"
 	"github.com/rubiojr/hashup/internal/log"
 	"github.com/rubiojr/hashup/internal/processors/nats"
@@ -18,7 +19,68 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
-func runEvery(c *cli.Context) error {
+// scanFlags are the flags of the scan and verify commands.
+func scanFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "config",
+			Usage: "Path to the configuration file",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:    "nats-url",
+			Usage:   "NATS URL",
+			EnvVars: []string{"HASHUP_NATS_URL"},
+		},
+		&cli.BoolFlag{
+			Name:  "debug",
+			Value: false,
+			Usage: "HASHUP_DEBUG",
+		},
+		&cli.StringFlag{
+			Name:  "ignore-file",
+			Value: "",
+			Usage: "List of files to ignore when scanning",
+		},
+		&cli.BoolFlag{
+			Name:  "ignore-hidden",
+			Value: true,
+			Usage: "Do not scann hidden files and directories",
+		},
+		&cli.IntFlag{
+			Name:  "concurrency",
+			Usage: "Number of concurrent workers",
+		},
+		&cli.StringFlag{
+			Name:    "encryption-key",
+			Usage:   "Key to use for encryption (if empty, a random key is generated)",
+			EnvVars: []string{"HASHUP_ENCRYPTION_KEY"},
+		},
+		&cli.StringFlag{
+			Name:  "client-cert",
+			Usage: "TLS client key",
+		},
+		&cli.StringFlag{
+			Name:  "client-key",
+			Usage: "TLS client cert",
+		},
+		&cli.StringFlag{
+			Name:  "ca-cert",
+			Usage: "TLS CA cert",
+		},
+		&cli.BoolFlag{
+			Name:  "chunks",
+			Usage: "Send content-defined chunk manifests of large files (scanner.chunking)",
+		},
+		&cli.StringFlag{
+			Name:  "every",
+			Usage: "Run the scanner regularly. Interval specified in seconds(s), minutes(m) or hours(h)",
+		},
+	}
+}
+
+// runEvery runs the scanner at the --every interval, see runScanner.
+func runEvery(c *cli.Context, verify bool) error {
 	d, err := time.ParseDuration(c.String("every"))
 	if err != nil {
 		return fmt.Errorf("failed to parse duration: %v", err)
@@ -30,7 +92,7 @@ func runEvery(c *cli.Context) error {
 	for {
 		select {
 		case <-ticker.C:
-			err := runScanner(c)
+			err := runScanner(c, verify)
 			if err != nil {
 				log.Errorf("failed to run scanner: %v", err)
 			}
@@ -40,7 +102,22 @@ func runEvery(c *cli.Context) error {
 	}
 }
 
-func runScanner(clictx *cli.Context) error {
+// scanListPath returns where the files found by the scans of rootDir are
+// listed, next to the scanner cache. The paths sent to the store depend on
+// how the directory is given, lists are kept per spelling.
+func scanListPath(cachePath, rootDir string) string {
+	abs, err := filepath.Abs(rootDir)
+	if err != nil {
+		abs = rootDir
+	}
+	key := xxhash.Sum64String(abs + "\x00" + rootDir)
+	return filepath.Join(filepath.Dir(cachePath), "scans", fmt.Sprintf("%016x", key))
+}
+
+// runScanner scans the directory argument. When verifying, files are
+// published even if the scanner cache has them, so that the store compares
+// their content with the index.
+func runScanner(clictx *cli.Context, verify bool) error {
 	cfg, err := util.LoadConfigFromCLI(clictx)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %v", err)
@@ -83,10 +160,18 @@ func runScanner(clictx *cli.Context) error {
 		log.Debugf("Counted %d files in %s\n", fileCount, elapsed)
 	}()
 
+	var fileCache cache.Cache = cache.NewFileCache(context.Background(), 100, cfg.Scanner.CachePath)
+	if verify {
+		fileCache = refreshCache{fileCache}
+	}
 	scannerOpts := []scanner.Option{
 		scanner.WithIgnoreList(ignoreList),
 		scanner.WithIgnoreHidden(clictx.Bool("ignore-hidden")),
-		scanner.WithCache(cache.NewFileCache(context.Background(), 100, cfg.Scanner.CachePath)),
+		scanner.WithCache(fileCache),
+		scanner.WithRemovals(scanListPath(cfg.Scanner.CachePath, rootDir)),
+	}
+	if clictx.Bool("chunks") || cfg.Scanner.Chunking {
+		scannerOpts = append(scannerOpts, scanner.WithChunking(cfg.Scanner.ChunkMinSize))
 	}
 	scanner := scanner.NewDirectoryScanner(rootDir, scannerOpts...)
 
@@ -191,6 +276,15 @@ Loop:
 	return nil
 }
 
+// refreshCache records the processed files without skipping any.
+type refreshCache struct {
+	cache.Cache
+}
+
+func (refreshCache) IsFileProcessed(string, string) bool {
+	return false
+}
+
 func readIgnoreList(filename string) ([]string, error) {
 	file, err := os.Open(filename)
 	if err != nil {
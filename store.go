@@ -48,6 +48,22 @@ func runStore(clictx *cli.Context) error {
 		return err
 	}
 
+	alerts, err := store.NewAlerts(cfg.Store.DBPath)
+	if err != nil {
+		return err
+	}
+	defer alerts.Close()
+	opts = append(opts, store.WithAlerts(alerts))
+
+	if cfg.Anomaly.Enabled {
+		anomalies, err := store.NewAnomalyDetector(cfg.Store.DBPath, cfg.Anomaly)
+		if err != nil {
+			return err
+		}
+		defer anomalies.Close()
+		opts = append(opts, store.WithAnomalies(anomalies))
+	}
+
 	listener, err := store.NewNatsListener(cfg.Main.EncryptionKey, storage, opts...)
 	if err != nil {
 		return err